@@ -17,14 +17,15 @@ const docTemplate = `{
     "paths": {
         "/api/buckets": {
             "get": {
-                "description": "Lists all S3 buckets accessible to the current session",
+                "description": "Lists all S3 buckets accessible to the current session, or the buckets declared when connecting.\nUse GET /api/v1/buckets instead.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Buckets"
                 ],
-                "summary": "List buckets",
+                "summary": "List buckets (deprecated)",
+                "deprecated": true,
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -35,156 +36,34 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/buckets/{name}": {
-            "put": {
-                "description": "Creates a new S3 bucket with the given name",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Buckets"
-                ],
-                "summary": "Create bucket",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Bucket Name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created"
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Deletes an S3 bucket with the given name",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Buckets"
-                ],
-                "summary": "Delete bucket",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Bucket Name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "409": {
-                        "description": "Conflict - Bucket not empty",
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/connect": {
-            "post": {
-                "description": "Establish connection to S3 storage and create session",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Session"
-                ],
-                "summary": "Connect to S3",
-                "parameters": [
-                    {
-                        "description": "Connection parameters",
-                        "name": "connection",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.ConnectionRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "429": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "$ref": "#/definitions/models.ConnectionResponse"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.ConnectionResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/logout": {
-            "post": {
-                "description": "Destroys the current session and clears cookies",
-                "tags": [
-                    "Session"
-                ],
-                "summary": "Logout",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "503": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
@@ -192,14 +71,15 @@ const docTemplate = `{
         },
         "/api/objects": {
             "get": {
-                "description": "Lists all objects in a specified S3 bucket",
+                "description": "Lists up to 1000 objects in a specified S3 bucket. Use GET /api/v1/buckets/{bucket}/objects instead.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Objects"
                 ],
-                "summary": "List objects",
+                "summary": "List objects (deprecated)",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
@@ -207,6 +87,12 @@ const docTemplate = `{
                         "name": "bucket",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list keys under this prefix (defaults to the session prefix)",
+                        "name": "prefix",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -225,64 +111,42 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/objects/{key}": {
-            "get": {
-                "description": "Retrieves an object from S3 for viewing or download",
-                "tags": [
-                    "Objects"
-                ],
-                "summary": "View/Download object",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Object key",
-                        "name": "key",
-                        "in": "path",
-                        "required": true
                     },
-                    {
-                        "type": "string",
-                        "description": "Bucket name",
-                        "name": "bucket",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Object content"
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "409": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Object not found",
+                    "429": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/objects/{key}": {
             "post": {
-                "description": "Uploads a file to the specified S3 bucket",
+                "description": "Uploads a file to the specified S3 bucket. Use PUT /api/v1/buckets/{bucket}/objects/{key} instead.",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -292,7 +156,8 @@ const docTemplate = `{
                 "tags": [
                     "Objects"
                 ],
-                "summary": "Upload object",
+                "summary": "Upload object (deprecated)",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
@@ -332,165 +197,2899 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Deletes an object from the specified S3 bucket",
-                "tags": [
-                    "Objects"
-                ],
-                "summary": "Delete object",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Object key",
-                        "name": "key",
-                        "in": "path",
-                        "required": true
                     },
-                    {
-                        "type": "string",
-                        "description": "Bucket name",
-                        "name": "bucket",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "409": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Object not found",
+                    "429": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/presigned-url": {
+        "/api/v1/buckets": {
             "get": {
-                "description": "Generate a temporary URL for direct browser access to an S3 object",
+                "description": "Lists a page of the S3 buckets accessible to the current session, or of the buckets\ndeclared when connecting, whose creation date is then unknown",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Objects"
+                    "Buckets"
                 ],
-                "summary": "Get presigned URL",
+                "summary": "List buckets",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bucket name",
-                        "name": "bucket",
-                        "in": "query",
-                        "required": true
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Object key",
-                        "name": "key",
-                        "in": "query",
-                        "required": true
+                        "type": "integer",
+                        "description": "Maximum number of buckets (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Page-models_S3Bucket"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Failed S3 request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/session/status": {
-            "get": {
-                "description": "Check if the current request has a valid session",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Session"
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}": {
+            "put": {
+                "description": "Creates a new S3 bucket with the given name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Buckets"
+                ],
+                "summary": "Create bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3Bucket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an S3 bucket with the given name. With force=true the bucket is emptied first\n(objects, versions, delete markers and in-progress multipart uploads) by a background\noperation; the caller must repeat the bucket name in confirm.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Buckets"
+                ],
+                "summary": "Delete bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Empty the bucket before deleting it",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket name, required when force is set",
+                        "name": "confirm",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}/du": {
+            "get": {
+                "description": "Walks a bucket prefix once and returns a tree of sub-prefixes down to the requested depth.\nEach node carries its total size, object count and its largest objects.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Buckets"
+                ],
+                "summary": "Disk usage tree",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Root prefix of the tree",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of prefix levels to expand (default 2, max 10)",
+                        "name": "depth",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of largest objects listed per node (default 10, max 100)",
+                        "name": "top",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "auto (default), index or live",
+                        "name": "source",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Check session status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SessionStatusResponse"
+                            "$ref": "#/definitions/models.DiskUsageNode"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}/index": {
+            "put": {
+                "description": "Creates the local key index of a bucket if needed and refreshes it in the background.\nThe index is then kept fresh on a schedule while the session is alive.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Index"
+                ],
+                "summary": "Index bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Bucket outside the scope of the session",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Indexing is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the local key index of a bucket",
+                "tags": [
+                    "Index"
+                ],
+                "summary": "Drop index",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Bucket outside the scope of the session",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.ConnectionRequest": {
+        },
+        "/api/v1/buckets/{bucket}/objects": {
+            "get": {
+                "description": "Lists a page of the objects in a bucket, in key order. With a delimiter, keys that contain\nit after the prefix are rolled up into prefixes, like folders.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Objects"
+                ],
+                "summary": "List objects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list keys under this prefix (defaults to the session prefix)",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Roll up keys into common prefixes at this character, usually /",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of objects and prefixes (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}/objects/{key}": {
+            "get": {
+                "description": "Retrieves an object from S3 for viewing or download",
+                "tags": [
+                    "Objects"
+                ],
+                "summary": "View/Download object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Object content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Stores the request body as an object, with the request's Content-Type. Bodies larger\nthan a part are uploaded in parts. Responds with the stored object.",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Objects"
+                ],
+                "summary": "Upload object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.S3Object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "411": {
+                        "description": "Content-Length is required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an object from the specified S3 bucket",
+                "tags": [
+                    "Objects"
+                ],
+                "summary": "Delete object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}/presigned-urls/{key}": {
+            "get": {
+                "description": "Generate a temporary URL for direct browser access to an S3 object",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Objects"
+                ],
+                "summary": "Get presigned URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PresignedURL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}/search": {
+            "get": {
+                "description": "Walks a bucket or prefix and streams matching objects as NDJSON. Each line is a\nmodels.SearchResult of type \"match\"; the last line is a \"summary\" (or an \"error\").\nClosing the connection cancels the walk. Indexed buckets are searched in the local\nindex unless source=live.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Objects"
+                ],
+                "summary": "Search objects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only search under this prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Glob on the full key (* and ? stay within a path segment, ** spans segments)",
+                        "name": "glob",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Regular expression on the full key",
+                        "name": "regex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Match glob or regex case-insensitively",
+                        "name": "ignore_case",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum size in bytes",
+                        "name": "min_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum size in bytes",
+                        "name": "max_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only objects modified after this RFC 3339 time",
+                        "name": "modified_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only objects modified before this RFC 3339 time",
+                        "name": "modified_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated storage classes",
+                        "name": "storage_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact ETag",
+                        "name": "etag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of matches (default 1000, max 100000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "auto (default), index or live",
+                        "name": "source",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SearchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buckets/{bucket}/stats": {
+            "get": {
+                "description": "Walks a bucket (or a prefix) and returns object count, total size and breakdowns by\nstorage class, top-level prefix, file extension and age. Results are cached per bucket\nand prefix; pass refresh=true to recompute. Closing the connection cancels the walk.\nIndexed buckets are answered from the local index unless source=live.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Buckets"
+                ],
+                "summary": "Bucket statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only count objects under this prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Ignore cached results",
+                        "name": "refresh",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "auto (default), index or live",
+                        "name": "source",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BucketStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/compare": {
+            "get": {
+                "description": "Walks a left and a right location, on the same or different connections of the browser\nsession, and classifies every key as only-left, only-right, size-differs, etag-differs,\netag-incomparable or identical. As NDJSON, each line is a models.CompareResult of type \"entry\"\nand the last line is a \"summary\" (or an \"error\"). format=csv and format=json download the\nreport instead.",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv",
+                    "application/json"
+                ],
+                "tags": [
+                    "Compare"
+                ],
+                "summary": "Compare locations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection of the left location (default: the active one)",
+                        "name": "left_connection",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket of the left location",
+                        "name": "left_bucket",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefix of the left location",
+                        "name": "left_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Connection of the right location (default: the active one)",
+                        "name": "right_connection",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket of the right location",
+                        "name": "right_bucket",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefix of the right location",
+                        "name": "right_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Leave identical keys out of the report",
+                        "name": "differences_only",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ndjson (default), csv or json",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CompareResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/connections": {
+            "get": {
+                "description": "Lists the connections configured by the operator and whether users may also connect\nwith an endpoint and keys of their own",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "List connections",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConnectionListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/connections/{name}/connect": {
+            "post": {
+                "description": "Tests an operator-defined connection and creates a session for it. With add=true\nthe connection is added to the current session and becomes active.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Connect by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add the connection to the current session",
+                        "name": "add",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConnectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed connection test",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed connection test",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed connection test",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/indexes": {
+            "get": {
+                "description": "Lists the local key indexes of the current connection with their staleness",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Index"
+                ],
+                "summary": "List indexes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of items (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_IndexStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mirror-roots": {
+            "get": {
+                "description": "Lists the names of the local directories the operator allows mirroring to and from.\nEmpty when mirroring is not enabled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mirror"
+                ],
+                "summary": "List mirror roots",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of items (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mirrors": {
+            "post": {
+                "description": "Exports a bucket prefix to a directory under one of the operator's mirror roots, or\nimports such a directory into a bucket prefix. Both sides are compared by size and\nmodification time and only what differs is copied; with delete, what is missing from\nthe source is removed from the destination. Runs as a background operation that can be\ncancelled and resumed. Paths cannot leave the root.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mirror"
+                ],
+                "summary": "Mirror to or from the local filesystem",
+                "parameters": [
+                    {
+                        "description": "Direction, bucket, prefix, root and path",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MirrorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Mirroring is not enabled or the connection is read-only",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown connection or root",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/operations": {
+            "get": {
+                "description": "Lists background operations started by the current session, newest first. Operations\nbelong to the browser session, whichever of its connections they work on.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operations"
+                ],
+                "summary": "List operations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of items (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_Operation"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/operations/{id}": {
+            "get": {
+                "description": "Returns the status and progress of a background operation",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operations"
+                ],
+                "summary": "Get operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "404": {
+                        "description": "Operation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Requests cancellation of a running background operation",
+                "tags": [
+                    "Operations"
+                ],
+                "summary": "Cancel operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "404": {
+                        "description": "Operation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/operations/{id}/resume": {
+            "post": {
+                "description": "Starts a failed or cancelled operation again as a new operation that skips the work\nalready done, e.g. the objects a transfer has copied",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operations"
+                ],
+                "summary": "Resume operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "404": {
+                        "description": "Operation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Operation is running or completed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/profiles": {
+            "get": {
+                "description": "Lists the server-side AWS profiles the administrator allows users to connect with.\n\"default\" stands for the SDK default credential chain.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "List server profiles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of items (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_Profile"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/session": {
+            "get": {
+                "description": "Check if the current request has a valid session. credentials_expired tells the UI\nthat temporary credentials ran out and the user has to reconnect. read_only tells it\nto hide uploads, deletes and bucket changes, which the server rejects with 403.\nBoth describe the active connection; connections lists all connections of the session.\nuser is who signed in to S3 Browser, when sign-in is required.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Check session status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionStatusResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Establish connection to S3 storage and create session. Temporary credentials\nare supported through session_token and their expiration. With assume_role the\ncredentials are used to assume an IAM role via STS, optionally with MFA. With profile\nthe server's own credentials for one of the profiles from /api/v1/profiles are used.\npath_style, ca_cert_pem, insecure_skip_verify and proxy_url control how S3 is reached.\nWith anonymous, no credentials are sent and only the named buckets are browsed.\nbuckets and prefix restrict any session to those buckets and keys under the prefix.\nread_only rejects every change for the lifetime of the session.\nWith add=true the connection is added to the current session and becomes active.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Connect to S3",
+                "parameters": [
+                    {
+                        "description": "Connection parameters",
+                        "name": "connection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ConnectionRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add the connection to the current session",
+                        "name": "add",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConnectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed connection test",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed connection test",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed connection test",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Destroys the current session and clears cookies",
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/v1/session/connections/{id}": {
+            "delete": {
+                "description": "Removes a connection that was added to the current session. The first connection\nof a session is removed by logging out.",
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Remove connection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/session/connections/{id}/activate": {
+            "post": {
+                "description": "Makes one of the connections listed by GET /api/v1/session the active one",
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Switch connection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/syncs": {
+            "post": {
+                "description": "Compares a source and a destination location, on the same or different connections of the\nbrowser session, by key, size and ETag or last-modified time. Returns a dry-run plan of the\nobjects that would be created, updated and, with delete, removed from the destination.\nNothing is changed until the plan is executed; plans expire after an hour.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Plan sync",
+                "parameters": [
+                    {
+                        "description": "Source, destination, filters and comparison",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Failed S3 request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/syncs/{id}": {
+            "get": {
+                "description": "Returns a sync plan that has not been executed, discarded or expired yet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Get sync plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncPlan"
+                        }
+                    },
+                    "404": {
+                        "description": "Plan not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Drops a sync plan without executing it",
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Discard sync plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Plan not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/syncs/{id}/execute": {
+            "post": {
+                "description": "Carries out a sync plan as a background operation: creates and updates are copied like a\ntransfer, then extraneous objects are deleted. A plan can be executed once; resume the\noperation if it fails.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Execute sync plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "403": {
+                        "description": "Destination is read-only",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Plan not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tokens": {
+            "get": {
+                "description": "Lists the unexpired API tokens created in the browser session or, when users sign in,\nby the signed-in user. The tokens themselves are not shown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "List API tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of items (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_APIToken"
+                        }
+                    },
+                    "403": {
+                        "description": "Tokens cannot manage tokens",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Issues a personal API token for one of the connections of the browser session, for\nscripts and CLIs. Send it as \"Authorization: Bearer \u003ctoken\u003e\" instead of the session\ncookie. The token keeps working after the browser session ends, until it expires or is\nrevoked. read_only and buckets narrow what it may do; it never gets more than the\nconnection. The token is only returned here; the server keeps a hash of it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Create API token",
+                "parameters": [
+                    {
+                        "description": "Name, connection, scope and expiry",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.APITokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreatedAPIToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Tokens cannot create tokens",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown connection",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tokens/{id}": {
+            "delete": {
+                "description": "Revokes one of the tokens listed by GET /api/v1/tokens; it stops working immediately",
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Revoke API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Tokens cannot manage tokens",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfers": {
+            "post": {
+                "description": "Copies a list of keys, or everything under a prefix, from a bucket on one connection\nof the browser session to a bucket on the same or another connection. Content type,\nmetadata and tags are preserved, large objects are copied in parts and every copy is\nverified by size and, where possible, checksum. Runs as a background operation that\ncan be cancelled and resumed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transfers"
+                ],
+                "summary": "Transfer objects",
+                "parameters": [
+                    {
+                        "description": "Source, destination and keys",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Destination is read-only",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown connection",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/callback": {
+            "get": {
+                "description": "The redirect URL to register with the OIDC provider. Exchanges the code, verifies the ID\ntoken, maps the user's groups to connections, starts the app session and returns to\nwhere sign-in started.",
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Finish sign-in",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State of the sign-in",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect back into the app"
+                    },
+                    "400": {
+                        "description": "Sign-in failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Sign-in failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "The app session could not be stored",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "get": {
+                "description": "With -oidc-issuer, redirects to the OIDC provider to sign in with the authorization code\nflow and PKCE. With -users-file, shows the sign-in form of the local users.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Sign in",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Local path to return to after signing in (default: /)",
+                        "name": "redirect",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sign-in form",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirect to the provider"
+                    },
+                    "500": {
+                        "description": "Sign-in could not be started",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Checks a username and password against the users file given with -users-file and starts\nthe app session. Accepts JSON, which is answered with the user, or the sign-in form,\nwhich is redirected back into the app. After 5 failed attempts in a row the username is\nlocked for 15 minutes.",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Sign in as a local user",
+                "parameters": [
+                    {
+                        "description": "Username and password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirect back into the app, for the form"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid username or password",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed sign-ins",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "The app session could not be stored",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "Ends the app session. The S3 sessions the user opened stay bound to them and cannot be\nused by anyone else signing in on the same browser.",
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Sign out",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIToken": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "connection": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "read_only": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.APITokenRequest": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "description": "Buckets are the only buckets the token may use; when the connection\ndeclares buckets they must be among them",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "connection": {
+                    "description": "Connection is the ID of the connection to bind the token to; empty means the active one",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt defaults to 30 days from now and may be at most a year away",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "read_only": {
+                    "description": "ReadOnly tokens may not change anything in S3, even on a writable connection",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.AssumeRoleRequest": {
+            "type": "object",
+            "properties": {
+                "duration_seconds": {
+                    "description": "DurationSeconds is the lifetime of each set of role credentials (900-43200)",
+                    "type": "integer"
+                },
+                "external_id": {
+                    "type": "string"
+                },
+                "mfa_serial": {
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "type": "string"
+                },
+                "role_arn": {
+                    "type": "string"
+                },
+                "session_name": {
+                    "type": "string"
+                },
+                "sts_endpoint": {
+                    "description": "STSEndpoint overrides the STS endpoint, e.g. for a local STS stand-in",
+                    "type": "string"
+                }
+            }
+        },
+        "models.BucketStats": {
+            "type": "object",
+            "properties": {
+                "ages": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.StatsBreakdown"
+                    }
+                },
+                "bucket": {
+                    "type": "string"
+                },
+                "cached": {
+                    "type": "boolean"
+                },
+                "computed_at": {
+                    "type": "string"
+                },
+                "extensions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.StatsBreakdown"
+                    }
+                },
+                "indexed_at": {
+                    "type": "string"
+                },
+                "object_count": {
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "prefixes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.StatsBreakdown"
+                    }
+                },
+                "source": {
+                    "type": "string"
+                },
+                "storage_classes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.StatsBreakdown"
+                    }
+                },
+                "total_size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CompareEntry": {
+            "type": "object",
+            "properties": {
+                "class": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "left_etag": {
+                    "type": "string"
+                },
+                "left_size": {
+                    "type": "integer"
+                },
+                "right_etag": {
+                    "type": "string"
+                },
+                "right_size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CompareResult": {
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "$ref": "#/definitions/models.CompareEntry"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.CompareSummary"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CompareSummary": {
+            "type": "object",
+            "properties": {
+                "etag_differs": {
+                    "type": "integer"
+                },
+                "etag_incomparable": {
+                    "type": "integer"
+                },
+                "identical": {
+                    "type": "integer"
+                },
+                "match": {
+                    "type": "boolean"
+                },
+                "only_left": {
+                    "type": "integer"
+                },
+                "only_right": {
+                    "type": "integer"
+                },
+                "size_differs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ConnectionInfo": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "read_only": {
+                    "type": "boolean"
+                },
+                "region": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConnectionListResponse": {
+            "type": "object",
+            "properties": {
+                "ad_hoc_allowed": {
+                    "type": "boolean"
+                },
+                "connections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ConnectionInfo"
+                    }
+                }
+            }
+        },
+        "models.ConnectionRequest": {
+            "type": "object",
+            "properties": {
+                "access_key": {
+                    "type": "string"
+                },
+                "anonymous": {
+                    "description": "Anonymous connects without credentials; Buckets must then name the buckets to browse",
+                    "type": "boolean"
+                },
+                "assume_role": {
+                    "description": "AssumeRole makes the session access S3 through a role assumed with the credentials above",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AssumeRoleRequest"
+                        }
+                    ]
+                },
+                "buckets": {
+                    "description": "Buckets and Prefix scope the session for credentials that may not call\nListBuckets; they are tested with HeadBucket or ListObjectsV2 instead",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ca_cert_file": {
+                    "description": "CACertFile reads the CA bundle from the server; only allowed in the connections file",
+                    "type": "string"
+                },
+                "ca_cert_pem": {
+                    "description": "CACertPEM adds CA certificates to trust, e.g. an internal CA or a self-signed certificate",
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "expiration": {
+                    "type": "string"
+                },
+                "insecure_skip_verify": {
+                    "description": "InsecureSkipVerify disables TLS certificate verification. Use only for testing.",
+                    "type": "boolean"
+                },
+                "path_style": {
+                    "description": "PathStyle selects path-style addressing (the default) or virtual-hosted style when false",
+                    "type": "boolean"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "profile": {
+                    "description": "Profile connects with a server-side AWS profile (see /api/profiles)\ninstead of AccessKey and SecretKey; \"default\" uses the SDK default chain",
+                    "type": "string"
+                },
+                "proxy_url": {
+                    "description": "ProxyURL sends S3 and STS requests through an HTTP(S) proxy",
+                    "type": "string"
+                },
+                "read_only": {
+                    "description": "ReadOnly rejects every change for the lifetime of the session",
+                    "type": "boolean"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "secret_key": {
+                    "type": "string"
+                },
+                "session_token": {
+                    "description": "SessionToken and Expiration come with temporary (STS-issued) credentials",
+                    "type": "string"
+                },
+                "use_ssl": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ConnectionResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.CreatedAPIToken": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "connection": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "read_only": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DiskUsageNode": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DiskUsageNode"
+                    }
+                },
+                "largest": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.S3Object"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "object_count": {
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the S3 error code, e.g. NoSuchKey or SlowDown, or the HTTP\nstatus text without spaces, e.g. BadRequest, for other errors",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Message explains the error to the user",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "RequestID identifies the failed request to the storage provider",
+                    "type": "string"
+                },
+                "retryable": {
+                    "description": "Retryable is true when the same request may succeed later",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.IndexStatus": {
+            "type": "object",
+            "properties": {
+                "age_seconds": {
+                    "type": "integer"
+                },
+                "bucket": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "object_count": {
+                    "type": "integer"
+                },
+                "refreshed_at": {
+                    "type": "string"
+                },
+                "refreshing": {
+                    "type": "boolean"
+                },
+                "stale": {
+                    "type": "boolean"
+                },
+                "total_size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MirrorRequest": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "connection": {
+                    "type": "string"
+                },
+                "delete": {
+                    "type": "boolean"
+                },
+                "direction": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "root": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ObjectPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.S3Object"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Operation": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "progress": {
+                    "$ref": "#/definitions/models.OperationProgress"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.OperationStatus"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OperationProgress": {
             "type": "object",
             "properties": {
-                "access_key": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "done": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "phase": {
                     "type": "string"
                 },
-                "endpoint": {
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OperationStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "completed",
+                "failed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "OperationPending",
+                "OperationRunning",
+                "OperationCompleted",
+                "OperationFailed",
+                "OperationCancelled"
+            ]
+        },
+        "models.Page-models_APIToken": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIToken"
+                    }
+                },
+                "next_cursor": {
                     "type": "string"
+                }
+            }
+        },
+        "models.Page-models_IndexStatus": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.IndexStatus"
+                    }
                 },
-                "region": {
+                "next_cursor": {
                     "type": "string"
+                }
+            }
+        },
+        "models.Page-models_Operation": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Operation"
+                    }
                 },
-                "secret_key": {
+                "next_cursor": {
                     "type": "string"
+                }
+            }
+        },
+        "models.Page-models_Profile": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Profile"
+                    }
                 },
-                "use_ssl": {
-                    "type": "boolean"
+                "next_cursor": {
+                    "type": "string"
                 }
             }
         },
-        "models.ConnectionResponse": {
+        "models.Page-models_S3Bucket": {
             "type": "object",
             "properties": {
-                "message": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.S3Bucket"
+                    }
+                },
+                "next_cursor": {
                     "type": "string"
+                }
+            }
+        },
+        "models.Page-string": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "session_id": {
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PresignedURL": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
                     "type": "string"
                 },
-                "success": {
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Profile": {
+            "type": "object",
+            "properties": {
+                "default_chain": {
+                    "description": "DefaultChain is set for the SDK default credential chain",
                     "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
                 }
             }
         },
@@ -498,10 +3097,15 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "creation_date": {
-                    "type": "string"
+                    "type": "string",
+                    "format": "date-time"
                 },
                 "name": {
                     "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is set for sessions limited to part of the bucket",
+                    "type": "string"
                 }
             }
         },
@@ -515,7 +3119,8 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "last_modified": {
-                    "type": "string"
+                    "type": "string",
+                    "format": "date-time"
                 },
                 "size": {
                     "type": "integer"
@@ -525,11 +3130,289 @@ const docTemplate = `{
                 }
             }
         },
+        "models.SearchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "object": {
+                    "$ref": "#/definitions/models.S3Object"
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.SearchSummary"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SearchSummary": {
+            "type": "object",
+            "properties": {
+                "indexed_at": {
+                    "type": "string"
+                },
+                "matched": {
+                    "type": "integer"
+                },
+                "scanned": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.SessionConnection": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "connection": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "credentials_expired": {
+                    "type": "boolean"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "read_only": {
+                    "type": "boolean"
+                },
+                "region": {
+                    "type": "string"
+                }
+            }
+        },
         "models.SessionStatusResponse": {
             "type": "object",
             "properties": {
+                "active_connection": {
+                    "type": "string"
+                },
+                "connections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SessionConnection"
+                    }
+                },
+                "credentials_expired": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
                 "has_session": {
                     "type": "boolean"
+                },
+                "read_only": {
+                    "type": "boolean"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                }
+            }
+        },
+        "models.StatsBreakdown": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SyncAction": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "SyncCreate",
+                "SyncUpdate",
+                "SyncDelete"
+            ]
+        },
+        "models.SyncItem": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/models.SyncAction"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SyncPlan": {
+            "type": "object",
+            "properties": {
+                "compare": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delete": {
+                    "type": "boolean"
+                },
+                "destination": {
+                    "$ref": "#/definitions/models.TransferLocation"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SyncItem"
+                    }
+                },
+                "source": {
+                    "$ref": "#/definitions/models.TransferLocation"
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.SyncSummary"
+                }
+            }
+        },
+        "models.SyncRequest": {
+            "type": "object",
+            "properties": {
+                "compare": {
+                    "type": "string"
+                },
+                "delete": {
+                    "type": "boolean"
+                },
+                "destination": {
+                    "$ref": "#/definitions/models.TransferLocation"
+                },
+                "exclude": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "include": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "source": {
+                    "$ref": "#/definitions/models.TransferLocation"
+                }
+            }
+        },
+        "models.SyncSummary": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "creates": {
+                    "type": "integer"
+                },
+                "deletes": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "updates": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TransferLocation": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "connection": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TransferRequest": {
+            "type": "object",
+            "properties": {
+                "destination": {
+                    "$ref": "#/definitions/models.TransferLocation"
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "skip_existing": {
+                    "type": "boolean"
+                },
+                "source": {
+                    "$ref": "#/definitions/models.TransferLocation"
+                }
+            }
+        },
+        "models.User": {
+            "type": "object",
+            "properties": {
+                "connections": {
+                    "description": "Connections are the operator-defined connections the user may use",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "email": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "ID is unique per user, e.g. the issuer and subject of an OIDC identity",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         }
@@ -540,7 +3423,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8080",
-	BasePath:         "/api",
+	BasePath:         "/",
 	Schemes:          []string{},
 	Title:            "S3 Browser API",
 	Description:      "A modern web-based file manager for S3-compatible storage systems",
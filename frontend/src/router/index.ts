@@ -15,7 +15,7 @@ const router = createRouter({
       path: '/objects/:bucket',
       name: 'Objects',
       component: ObjectsView,
-      props: true,
+      props: (route) => ({ bucket: route.params.bucket, prefix: route.query.prefix || '' }),
     },
     {
       // Catch-all route - show 404 page for any invalid route
@@ -2,8 +2,48 @@ export interface ConnectionCredentials {
   endpoint: string;
   access_key: string;
   secret_key: string;
+  session_token?: string;
   region: string;
   use_ssl: boolean;
+  profile?: string;
+  assume_role?: AssumeRoleRequest;
+  path_style?: boolean;
+  ca_cert_pem?: string;
+  insecure_skip_verify?: boolean;
+  proxy_url?: string;
+  anonymous?: boolean;
+  buckets?: string[];
+  prefix?: string;
+  read_only?: boolean;
+}
+
+export interface ConfiguredConnection {
+  name: string;
+  description?: string;
+  endpoint: string;
+  region?: string;
+  read_only: boolean;
+}
+
+export interface ConnectionListResponse {
+  connections: ConfiguredConnection[];
+  ad_hoc_allowed: boolean;
+}
+
+export interface ServerProfile {
+  name: string;
+  region?: string;
+  default_chain: boolean;
+}
+
+export interface AssumeRoleRequest {
+  role_arn: string;
+  external_id?: string;
+  session_name?: string;
+  duration_seconds?: number;
+  mfa_serial?: string;
+  mfa_token?: string;
+  sts_endpoint?: string;
 }
 
 export interface ConnectionTestResponse {
@@ -14,6 +54,7 @@ export interface ConnectionTestResponse {
 export interface S3Bucket {
   name: string;
   creation_date: string;
+  prefix?: string;
 }
 
 export interface S3Object {
@@ -22,3 +63,14 @@ export interface S3Object {
   etag: string;
   storage_class: string;
 }
+
+export interface SessionConnection {
+  id: string;
+  connection?: string;
+  endpoint: string;
+  region: string;
+  read_only: boolean;
+  credentials_expired: boolean;
+  active: boolean;
+  created_at: string;
+}
@@ -0,0 +1,14 @@
+// Reads the message of a failed API response. Failed S3 requests come back as
+// a JSON error envelope; other errors are plain text.
+export async function responseError(response: Response, fallback: string): Promise<string> {
+  const text = await response.text().catch(() => '')
+  try {
+    const data = JSON.parse(text)
+    if (data && typeof data.message === 'string' && data.message) {
+      return data.message
+    }
+  } catch {
+    // Not JSON, use the raw text
+  }
+  return text || fallback
+}
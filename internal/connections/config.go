@@ -0,0 +1,221 @@
+package connections
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// Config is the operator's connection file
+type Config struct {
+	// AllowAdHoc lets users connect with endpoints and keys of their own;
+	// it defaults to true
+	AllowAdHoc  *bool        `json:"allow_ad_hoc"`
+	Connections []Connection `json:"connections"`
+}
+
+// Connection is a named S3 connection declared by the operator
+type Connection struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Endpoint    string `json:"endpoint"`
+	Region      string `json:"region"`
+	UseSSL      bool   `json:"use_ssl"`
+	// PathStyle selects path-style addressing (the default) or virtual-hosted style when false
+	PathStyle *bool `json:"path_style,omitempty"`
+	// CACertFile or CACertPEM add CA certificates trusted for the endpoint
+	CACertFile         string `json:"ca_cert_file,omitempty"`
+	CACertPEM          string `json:"ca_cert_pem,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	ProxyURL           string `json:"proxy_url,omitempty"`
+	ReadOnly           bool   `json:"read_only,omitempty"`
+	// Buckets and Prefix limit the connection to these buckets and the keys
+	// under the prefix; anonymous connections require buckets
+	Buckets     []string    `json:"buckets,omitempty"`
+	Prefix      string      `json:"prefix,omitempty"`
+	Credentials Credentials `json:"credentials"`
+	// Groups limit the connection to signed-in users in one of these groups;
+	// without groups every signed-in user may use it
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Credentials is where a connection gets its credentials from: a server-side
+// profile ("default" for the SDK default chain), static keys, which may be
+// read from environment variables to keep them out of the file, or none for
+// anonymous access to public buckets
+type Credentials struct {
+	Anonymous    bool                      `json:"anonymous,omitempty"`
+	Profile      string                    `json:"profile,omitempty"`
+	AccessKey    string                    `json:"access_key,omitempty"`
+	SecretKey    string                    `json:"secret_key,omitempty"`
+	AccessKeyEnv string                    `json:"access_key_env,omitempty"`
+	SecretKeyEnv string                    `json:"secret_key_env,omitempty"`
+	AssumeRole   *models.AssumeRoleRequest `json:"assume_role,omitempty"`
+}
+
+// Load reads and validates the connection file at path
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse connection file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range cfg.Connections {
+		conn := &cfg.Connections[i]
+		if err := conn.validate(); err != nil {
+			return nil, err
+		}
+		if seen[conn.Name] {
+			return nil, fmt.Errorf("connection %q is declared twice", conn.Name)
+		}
+		seen[conn.Name] = true
+	}
+	return &cfg, nil
+}
+
+func (c *Connection) validate() error {
+	if c.Name == "" {
+		return errors.New("every connection needs a name")
+	}
+	if c.Endpoint == "" {
+		return fmt.Errorf("connection %q has no endpoint", c.Name)
+	}
+
+	creds := c.Credentials
+	hasKeys := creds.AccessKey != "" || creds.AccessKeyEnv != ""
+	sources := 0
+	for _, set := range []bool{creds.Anonymous, creds.Profile != "", hasKeys} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("connection %q must use exactly one of anonymous, a profile or access keys", c.Name)
+	}
+	if sources == 0 {
+		return fmt.Errorf("connection %q has no credentials", c.Name)
+	}
+	if creds.Anonymous && len(c.Buckets) == 0 {
+		return fmt.Errorf("anonymous connection %q must list its buckets", c.Name)
+	}
+	if creds.Profile == "" && c.Region == "" {
+		return fmt.Errorf("connection %q has no region", c.Name)
+	}
+	return nil
+}
+
+// AdHocAllowed reports whether users may connect to endpoints of their own.
+// A nil config, i.e. no connection file, allows it.
+func (c *Config) AdHocAllowed() bool {
+	return c == nil || c.AllowAdHoc == nil || *c.AllowAdHoc
+}
+
+// Get returns the connection called name
+func (c *Config) Get(name string) (*Connection, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for i := range c.Connections {
+		if c.Connections[i].Name == name {
+			return &c.Connections[i], true
+		}
+	}
+	return nil, false
+}
+
+// List returns the public description of every connection
+func (c *Config) List() []models.ConnectionInfo {
+	infos := make([]models.ConnectionInfo, 0)
+	if c == nil {
+		return infos
+	}
+	for _, conn := range c.Connections {
+		infos = append(infos, conn.Info())
+	}
+	return infos
+}
+
+// Names returns the names of every connection
+func (c *Config) Names() []string {
+	names := make([]string, 0)
+	if c == nil {
+		return names
+	}
+	for _, conn := range c.Connections {
+		names = append(names, conn.Name)
+	}
+	return names
+}
+
+// ForGroups returns the names of the connections that members of groups may use
+func (c *Config) ForGroups(groups []string) []string {
+	names := make([]string, 0)
+	if c == nil {
+		return names
+	}
+	for _, conn := range c.Connections {
+		if len(conn.Groups) == 0 || slices.ContainsFunc(conn.Groups, func(group string) bool {
+			return slices.Contains(groups, group)
+		}) {
+			names = append(names, conn.Name)
+		}
+	}
+	return names
+}
+
+// Info returns what users may see about the connection
+func (c *Connection) Info() models.ConnectionInfo {
+	return models.ConnectionInfo{
+		Name:        c.Name,
+		Description: c.Description,
+		Endpoint:    c.Endpoint,
+		Region:      c.Region,
+		ReadOnly:    c.ReadOnly,
+	}
+}
+
+// Request turns the connection into the parameters of a session, resolving
+// keys from the environment
+func (c *Connection) Request() (models.ConnectionRequest, error) {
+	creds := c.Credentials
+	req := models.ConnectionRequest{
+		Endpoint:   c.Endpoint,
+		Region:     c.Region,
+		UseSSL:     c.UseSSL,
+		Profile:    creds.Profile,
+		Anonymous:  creds.Anonymous,
+		Buckets:    c.Buckets,
+		Prefix:     c.Prefix,
+		ReadOnly:   c.ReadOnly,
+		AccessKey:  creds.AccessKey,
+		SecretKey:  creds.SecretKey,
+		AssumeRole: creds.AssumeRole,
+
+		PathStyle:          c.PathStyle,
+		CACertPEM:          c.CACertPEM,
+		CACertFile:         c.CACertFile,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ProxyURL:           c.ProxyURL,
+	}
+
+	if creds.AccessKeyEnv != "" {
+		req.AccessKey = os.Getenv(creds.AccessKeyEnv)
+	}
+	if creds.SecretKeyEnv != "" {
+		req.SecretKey = os.Getenv(creds.SecretKeyEnv)
+	}
+	if !req.Anonymous && req.Profile == "" && (req.AccessKey == "" || req.SecretKey == "") {
+		return models.ConnectionRequest{}, fmt.Errorf("connection %q has incomplete credentials", c.Name)
+	}
+	return req, nil
+}
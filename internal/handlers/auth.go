@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/sso"
+)
+
+// stateCookie carries a pending OIDC sign-in, sealed by the provider, in the
+// browser that started it
+const stateCookie = "oidc_state"
+
+// loginPage is the sign-in form for local users
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in - S3 Browser</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f3f4f6; display: flex; justify-content: center; padding-top: 10vh; }
+form { background: #fff; padding: 2rem; border-radius: 0.5rem; box-shadow: 0 1px 3px rgba(0, 0, 0, 0.15); width: 20rem; }
+h1 { font-size: 1.25rem; margin-top: 0; }
+label { display: block; margin-top: 1rem; font-size: 0.875rem; }
+input { box-sizing: border-box; width: 100%; padding: 0.5rem; margin-top: 0.25rem; }
+button { margin-top: 1.5rem; width: 100%; padding: 0.5rem; background: #2563eb; color: #fff; border: 0; border-radius: 0.25rem; }
+.error { color: #b91c1c; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+<form method="post" action="/auth/login">
+<h1>Sign in to S3 Browser</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="hidden" name="redirect" value="{{.Redirect}}">
+<label>Username <input name="username" value="{{.Username}}" autocomplete="username" required autofocus></label>
+<label>Password <input name="password" type="password" autocomplete="current-password" required></label>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+// loginForm is what loginPage shows
+type loginForm struct {
+	Redirect string
+	Username string
+	Error    string
+}
+
+// AuthHandler handles signing in to S3 Browser itself, with an OIDC
+// provider or as one of the local users
+type AuthHandler struct {
+	provider   *sso.Provider
+	localUsers *identity.LocalUsers
+	users      *identity.Sessions
+	logger     *slog.Logger
+}
+
+// NewAuthHandler creates a new auth handler that signs users in with
+// provider or, when provider is nil, against localUsers
+func NewAuthHandler(provider *sso.Provider, localUsers *identity.LocalUsers, users *identity.Sessions, logger *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		provider:   provider,
+		localUsers: localUsers,
+		users:      users,
+		logger:     logger,
+	}
+}
+
+// Login starts signing in
+// @Summary Sign in
+// @Description With -oidc-issuer, redirects to the OIDC provider to sign in with the authorization code
+// @Description flow and PKCE. With -users-file, shows the sign-in form of the local users.
+// @Tags Auth
+// @Produce html
+// @Param redirect query string false "Local path to return to after signing in (default: /)"
+// @Success 200 {string} string "Sign-in form"
+// @Success 302 "Redirect to the provider"
+// @Failure 500 {string} string "Sign-in could not be started"
+// @Router /auth/login [get]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		h.renderLogin(w, http.StatusOK, loginForm{Redirect: identity.LocalRedirect(r.URL.Query().Get("redirect"))})
+		return
+	}
+
+	login, url, err := h.provider.Begin(r.URL.Query().Get("redirect"))
+	if err != nil {
+		h.logger.Error("Failed to start sign-in", slog.String("error", err.Error()))
+		http.Error(w, "Sign-in failed", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    login,
+		Path:     "/auth/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(sso.LoginTimeout.Seconds()),
+	})
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// LoginLocal signs in a local user
+// @Summary Sign in as a local user
+// @Description Checks a username and password against the users file given with -users-file and starts
+// @Description the app session. Accepts JSON, which is answered with the user, or the sign-in form,
+// @Description which is redirected back into the app. After 5 failed attempts in a row the username is
+// @Description locked for 15 minutes.
+// @Tags Auth
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.LoginRequest true "Username and password"
+// @Success 200 {object} models.User
+// @Success 302 "Redirect back into the app, for the form"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Invalid username or password"
+// @Failure 429 {string} string "Too many failed sign-ins"
+// @Failure 500 {string} string "The app session could not be stored"
+// @Router /auth/login [post]
+func (h *AuthHandler) LoginLocal(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isJSON := mediaType == "application/json"
+	redirect := "/"
+	if isJSON {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		req.Username = r.PostForm.Get("username")
+		req.Password = r.PostForm.Get("password")
+		redirect = identity.LocalRedirect(r.PostForm.Get("redirect"))
+	}
+
+	appSession, err := h.localUsers.Authenticate(req.Username, req.Password)
+	if err != nil {
+		status := http.StatusUnauthorized
+		var locked *identity.LockoutError
+		if errors.As(err, &locked) {
+			status = http.StatusTooManyRequests
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(locked.Until).Seconds())+1))
+		}
+		h.logger.Warn("Sign-in failed",
+			slog.String("user", req.Username),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("error", err.Error()))
+		if isJSON {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		h.renderLogin(w, status, loginForm{Redirect: redirect, Username: req.Username, Error: err.Error()})
+		return
+	}
+
+	user, err := h.users.Start(w, r, *appSession)
+	if err != nil {
+		h.logger.Error("Failed to start app session", slog.String("error", err.Error()))
+		http.Error(w, "Sign-in failed", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("User signed in",
+		slog.String("user", user.ID),
+		slog.Any("connections", user.Connections))
+	if !isJSON {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(user)
+}
+
+// renderLogin shows the sign-in form of the local users
+func (h *AuthHandler) renderLogin(w http.ResponseWriter, status int, form loginForm) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := loginPage.Execute(w, form); err != nil {
+		h.logger.Error("Failed to render sign-in form", slog.String("error", err.Error()))
+	}
+}
+
+// Callback finishes signing in when the identity provider redirects back
+// @Summary Finish sign-in
+// @Description The redirect URL to register with the OIDC provider. Exchanges the code, verifies the ID
+// @Description token, maps the user's groups to connections, starts the app session and returns to
+// @Description where sign-in started.
+// @Tags Auth
+// @Param code query string true "Authorization code"
+// @Param state query string true "State of the sign-in"
+// @Success 302 "Redirect back into the app"
+// @Failure 400,401 {string} string "Sign-in failed"
+// @Failure 500 {string} string "The app session could not be stored"
+// @Router /auth/callback [get]
+func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || state == "" {
+		http.Error(w, "Sign-in was not started in this browser; please try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/auth/", MaxAge: -1})
+
+	if reason := query.Get("error"); reason != "" {
+		h.logger.Warn("Identity provider refused sign-in",
+			slog.String("error", reason),
+			slog.String("description", query.Get("error_description")))
+		http.Error(w, "Sign-in failed: "+reason, http.StatusUnauthorized)
+		return
+	}
+
+	user, redirect, err := h.provider.Finish(r.Context(), cookie.Value, state, query.Get("code"))
+	if err != nil {
+		if errors.Is(err, sso.ErrLoginExpired) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Warn("Sign-in failed", slog.String("error", err.Error()))
+		http.Error(w, "Sign-in failed", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.users.Start(w, r, models.AppSession{User: *user}); err != nil {
+		h.logger.Error("Failed to start app session", slog.String("error", err.Error()))
+		http.Error(w, "Sign-in failed", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info("User signed in",
+		slog.String("user", user.ID),
+		slog.Any("groups", user.Groups),
+		slog.Any("connections", user.Connections))
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// Logout signs the user out of S3 Browser
+// @Summary Sign out
+// @Description Ends the app session. The S3 sessions the user opened stay bound to them and cannot be
+// @Description used by anyone else signing in on the same browser.
+// @Tags Auth
+// @Success 204 "No Content"
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if user := h.users.Get(r); user != nil {
+		h.logger.Info("User signed out", slog.String("user", user.ID))
+	}
+	h.users.End(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
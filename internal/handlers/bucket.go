@@ -1,36 +1,54 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/cksidharthan/s3-browser/internal/index"
 	"github.com/cksidharthan/s3-browser/internal/middleware"
 	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
 )
 
+// deleteBatchSize is the maximum number of keys accepted by a single DeleteObjects call
+const deleteBatchSize = 1000
+
 // BucketHandler handles bucket-related operations
 type BucketHandler struct {
-	logger *slog.Logger
+	operationManager *operations.Manager
+	indexer          *index.Indexer
+	logger           *slog.Logger
 }
 
 // NewBucketHandler creates a new bucket handler
-func NewBucketHandler(logger *slog.Logger) *BucketHandler {
+func NewBucketHandler(operationManager *operations.Manager, indexer *index.Indexer, logger *slog.Logger) *BucketHandler {
 	return &BucketHandler{
-		logger: logger,
+		operationManager: operationManager,
+		indexer:          indexer,
+		logger:           logger,
 	}
 }
 
 // ListBuckets lists all buckets in the S3 account
-// @Summary List buckets
-// @Description Lists all S3 buckets accessible to the current session
+// @Summary List buckets (deprecated)
+// @Description Lists all S3 buckets accessible to the current session, or the buckets declared when connecting.
+// @Description Use GET /api/v1/buckets instead.
 // @Tags Buckets
 // @Produce json
 // @Success 200 {array} models.S3Bucket
-// @Failure 500 {string} string "Internal Server Error"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Deprecated
 // @Router /api/buckets [get]
 func (h *BucketHandler) ListBuckets(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -40,10 +58,21 @@ func (h *BucketHandler) ListBuckets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Buckets declared when connecting are shown as they are; anonymous users cannot call ListBuckets
+	if len(session.Buckets) > 0 {
+		buckets := make([]models.S3Bucket, 0, len(session.Buckets))
+		for _, name := range session.Buckets {
+			buckets = append(buckets, models.S3Bucket{Name: name, Prefix: session.Prefix})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buckets)
+		return
+	}
+
 	result, err := session.S3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
 	if err != nil {
 		h.logger.Error("Failed to list buckets", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		sendS3Error(w, err)
 		return
 	}
 
@@ -52,7 +81,7 @@ func (h *BucketHandler) ListBuckets(w http.ResponseWriter, r *http.Request) {
 		if bucket.Name != nil && bucket.CreationDate != nil {
 			buckets = append(buckets, models.S3Bucket{
 				Name:         aws.ToString(bucket.Name),
-				CreationDate: bucket.CreationDate.Format("2006-01-02 15:04:05"),
+				CreationDate: bucket.CreationDate.Format(models.LegacyTimeLayout),
 			})
 		}
 	}
@@ -61,17 +90,80 @@ func (h *BucketHandler) ListBuckets(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(buckets)
 }
 
+// ListBucketsV1 lists a page of the buckets in the S3 account
+// @Summary List buckets
+// @Description Lists a page of the S3 buckets accessible to the current session, or of the buckets
+// @Description declared when connecting, whose creation date is then unknown
+// @Tags Buckets
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of buckets (default and max 1000)"
+// @Success 200 {object} models.Page[models.S3Bucket]
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets [get]
+func (h *BucketHandler) ListBucketsV1(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := middleware.GetSessionFromContext(ctx)
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(session.Buckets) > 0 {
+		buckets := make([]models.S3Bucket, 0, len(session.Buckets))
+		for _, name := range session.Buckets {
+			buckets = append(buckets, models.S3Bucket{Name: name, Prefix: session.Prefix})
+		}
+		sendList(w, r, buckets)
+		return
+	}
+
+	input := &s3.ListBucketsInput{MaxBuckets: aws.Int32(int32(limit))}
+	if cursor != "" {
+		input.ContinuationToken = aws.String(cursor)
+	}
+	result, err := session.S3Client.ListBuckets(ctx, input)
+	if err != nil {
+		h.logger.Error("Failed to list buckets", slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+
+	page := models.Page[models.S3Bucket]{
+		Items:      make([]models.S3Bucket, 0, len(result.Buckets)),
+		NextCursor: aws.ToString(result.ContinuationToken),
+	}
+	for _, bucket := range result.Buckets {
+		if bucket.Name != nil && bucket.CreationDate != nil {
+			page.Items = append(page.Items, models.S3Bucket{
+				Name:         aws.ToString(bucket.Name),
+				CreationDate: bucket.CreationDate.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
 // CreateBucket creates a new S3 bucket
 // @Summary Create bucket
 // @Description Creates a new S3 bucket with the given name
 // @Tags Buckets
 // @Accept json
 // @Produce json
-// @Param name path string true "Bucket Name"
-// @Success 201 "Created"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /api/buckets/{name} [put]
+// @Param bucket path string true "Bucket name"
+// @Success 201 {object} models.S3Bucket
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket} [put]
 func (h *BucketHandler) CreateBucket(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := middleware.GetSessionFromContext(ctx)
@@ -94,42 +186,37 @@ func (h *BucketHandler) CreateBucket(w http.ResponseWriter, r *http.Request) {
 			slog.String("bucket", bucketName), 
 			slog.String("error", err.Error()))
 		
-		// Determine appropriate status code based on error
-		errorMessage := err.Error()
-		statusCode := http.StatusInternalServerError
-		userMessage := errorMessage
-		
-		if strings.Contains(errorMessage, "BucketAlreadyExists") {
-			statusCode = http.StatusConflict
-			userMessage = "Bucket already exists. Bucket names must be globally unique."
-		} else if strings.Contains(errorMessage, "InvalidBucketName") {
-			statusCode = http.StatusBadRequest
-			userMessage = "Invalid bucket name. Bucket names must follow S3 naming conventions."
-		} else if strings.Contains(errorMessage, "AccessDenied") {
-			statusCode = http.StatusForbidden
-			userMessage = "Access denied: You don't have permission to create buckets."
-		}
-		
-		http.Error(w, userMessage, statusCode)
+		sendS3Error(w, err)
 		return
 	}
 
 	h.logger.Info("Bucket created", slog.String("bucket", bucketName))
+	if !middleware.IsAPIv1(r) {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", r.URL.Path)
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.S3Bucket{Name: bucketName})
 }
 
 // DeleteBucket deletes an S3 bucket
 // @Summary Delete bucket
-// @Description Deletes an S3 bucket with the given name
+// @Description Deletes an S3 bucket with the given name. With force=true the bucket is emptied first
+// @Description (objects, versions, delete markers and in-progress multipart uploads) by a background
+// @Description operation; the caller must repeat the bucket name in confirm.
 // @Tags Buckets
 // @Accept json
 // @Produce json
-// @Param name path string true "Bucket Name"
+// @Param bucket path string true "Bucket name"
+// @Param force query bool false "Empty the bucket before deleting it"
+// @Param confirm query string false "Bucket name, required when force is set"
+// @Success 202 {object} models.Operation
 // @Success 204 "No Content"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 409 {string} string "Conflict - Bucket not empty"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /api/buckets/{name} [delete]
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket} [delete]
 func (h *BucketHandler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := middleware.GetSessionFromContext(ctx)
@@ -144,6 +231,25 @@ func (h *BucketHandler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("force") == "true" {
+		if r.URL.Query().Get("confirm") != bucketName {
+			http.Error(w, "Force delete requires confirm to match the bucket name", http.StatusBadRequest)
+			return
+		}
+
+		client := session.S3Client
+		scopeID := indexScope(session, bucketName)
+		op := h.operationManager.Start(middleware.GetOwnerFromContext(ctx).ID, "force-delete-bucket", bucketName,
+			func(ctx context.Context, op *operations.Operation) error {
+				if err := h.forceDeleteBucket(ctx, client, bucketName, op); err != nil {
+					return err
+				}
+				return h.indexer.Drop(scopeID)
+			})
+		sendOperationAccepted(w, r, op)
+		return
+	}
+
 	_, err := session.S3Client.DeleteBucket(ctx, &s3.DeleteBucketInput{
 		Bucket: aws.String(bucketName),
 	})
@@ -152,31 +258,166 @@ func (h *BucketHandler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 			slog.String("bucket", bucketName), 
 			slog.String("error", err.Error()))
 		
-		// Determine appropriate status code and user-friendly message based on error
-		errorMessage := err.Error()
-		statusCode := http.StatusInternalServerError
-		userMessage := errorMessage
-		
-		// Check for common S3 errors and provide user-friendly messages
-		if strings.Contains(errorMessage, "BucketNotEmpty") {
-			statusCode = http.StatusConflict
-			userMessage = "Cannot delete bucket: The bucket is not empty. Please delete all objects first."
-		} else if strings.Contains(errorMessage, "NoSuchBucket") {
-			statusCode = http.StatusNotFound
-			userMessage = "Bucket not found."
-		} else if strings.Contains(errorMessage, "AccessDenied") {
-			statusCode = http.StatusForbidden
-			userMessage = "Access denied: You don't have permission to delete this bucket."
-		}
-		
-		http.Error(w, userMessage, statusCode)
+		sendS3Error(w, err)
 		return
 	}
 
+	if err := h.indexer.Drop(indexScope(session, bucketName)); err != nil {
+		h.logger.Warn("Failed to drop index of deleted bucket",
+			slog.String("bucket", bucketName),
+			slog.String("error", err.Error()))
+	}
+
 	h.logger.Info("Bucket deleted", slog.String("bucket", bucketName))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// forceDeleteBucket aborts multipart uploads, deletes every object version and
+// delete marker, and finally deletes the bucket itself
+func (h *BucketHandler) forceDeleteBucket(ctx context.Context, client *s3.Client, bucket string, op *operations.Operation) error {
+	op.SetPhase("aborting multipart uploads")
+	uploads := s3.NewListMultipartUploadsPaginator(client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+	})
+	for uploads.HasMorePages() {
+		page, err := uploads.NextPage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !notImplemented(err) {
+				return fmt.Errorf("failed to list multipart uploads: %w", err)
+			}
+			// Not every S3-compatible provider implements ListMultipartUploads
+			h.logger.Warn("Multipart uploads are not supported, skipping abort",
+				slog.String("bucket", bucket),
+				slog.String("error", err.Error()))
+			break
+		}
+		op.AddTotal(int64(len(page.Uploads)))
+		for _, upload := range page.Uploads {
+			_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucket),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to abort multipart upload of %s: %w", aws.ToString(upload.Key), err)
+			}
+			op.AddDone(1, 0)
+		}
+	}
+
+	op.SetPhase("deleting object versions")
+	versions := s3.NewListObjectVersionsPaginator(client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	})
+	for versions.HasMorePages() {
+		page, err := versions.NextPage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !notImplemented(err) {
+				return fmt.Errorf("failed to list object versions: %w", err)
+			}
+			// Some S3-compatible providers do not implement ListObjectVersions;
+			// the plain listing below still empties unversioned buckets
+			h.logger.Warn("Object versions are not supported, deleting current objects only",
+				slog.String("bucket", bucket),
+				slog.String("error", err.Error()))
+			break
+		}
+
+		ids := make([]types.ObjectIdentifier, 0, len(page.Versions)+len(page.DeleteMarkers))
+		var size int64
+		for _, version := range page.Versions {
+			ids = append(ids, types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+			size += aws.ToInt64(version.Size)
+		}
+		for _, marker := range page.DeleteMarkers {
+			ids = append(ids, types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		op.AddTotal(int64(len(ids)))
+
+		if err := h.deleteObjectBatches(ctx, client, bucket, ids, op); err != nil {
+			return err
+		}
+		op.AddDone(0, size)
+	}
+
+	op.SetPhase("deleting remaining objects")
+	objects := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	})
+	for objects.HasMorePages() {
+		page, err := objects.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		ids := make([]types.ObjectIdentifier, 0, len(page.Contents))
+		var size int64
+		for _, obj := range page.Contents {
+			ids = append(ids, types.ObjectIdentifier{Key: obj.Key})
+			size += aws.ToInt64(obj.Size)
+		}
+		op.AddTotal(int64(len(ids)))
+
+		if err := h.deleteObjectBatches(ctx, client, bucket, ids, op); err != nil {
+			return err
+		}
+		op.AddDone(0, size)
+	}
+
+	op.SetPhase("deleting bucket")
+	_, err := client.DeleteBucket(ctx, &s3.DeleteBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete bucket: %w", err)
+	}
+
+	h.logger.Info("Bucket force deleted", slog.String("bucket", bucket))
+	return nil
+}
+
+// deleteObjectBatches deletes ids in chunks of at most deleteBatchSize keys
+func (h *BucketHandler) deleteObjectBatches(ctx context.Context, client *s3.Client, bucket string, ids []types.ObjectIdentifier, op *operations.Operation) error {
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(ids))
+		result, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{
+				Objects: ids[start:end],
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects: %w", err)
+		}
+
+		if len(result.Errors) > 0 {
+			op.AddFailed(int64(len(result.Errors)))
+			first := result.Errors[0]
+			return fmt.Errorf("failed to delete %s: %s", aws.ToString(first.Key), aws.ToString(first.Message))
+		}
+		op.AddDone(int64(end-start), 0)
+	}
+	return nil
+}
+
+// notImplemented reports whether err means the provider does not implement
+// the operation at all, as opposed to refusing or failing it
+func notImplemented(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotImplemented" {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotImplemented
+}
+
 // extractBucketNameFromPath extracts bucket name from URL path like "/api/buckets/{name}"
 func (h *BucketHandler) extractBucketNameFromPath(path string) string {
 	parts := strings.Split(strings.Trim(path, "/"), "/")
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/session"
+	"github.com/cksidharthan/s3-browser/internal/syncer"
+)
+
+// Formats of a comparison report
+const (
+	compareFormatNDJSON = "ndjson"
+	compareFormatCSV    = "csv"
+	compareFormatJSON   = "json"
+)
+
+// CompareHandler handles comparing two locations
+type CompareHandler struct {
+	sessionManager *session.Manager
+	logger         *slog.Logger
+}
+
+// NewCompareHandler creates a new compare handler
+func NewCompareHandler(sessionManager *session.Manager, logger *slog.Logger) *CompareHandler {
+	return &CompareHandler{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// Compare walks two locations in key order and reports how they differ
+// @Summary Compare locations
+// @Description Walks a left and a right location, on the same or different connections of the browser
+// @Description session, and classifies every key as only-left, only-right, size-differs, etag-differs,
+// @Description etag-incomparable or identical. As NDJSON, each line is a models.CompareResult of type "entry"
+// @Description and the last line is a "summary" (or an "error"). format=csv and format=json download the
+// @Description report instead.
+// @Tags Compare
+// @Produce application/x-ndjson,text/csv,json
+// @Param left_connection query string false "Connection of the left location (default: the active one)"
+// @Param left_bucket query string true "Bucket of the left location"
+// @Param left_prefix query string false "Prefix of the left location"
+// @Param right_connection query string false "Connection of the right location (default: the active one)"
+// @Param right_bucket query string true "Bucket of the right location"
+// @Param right_prefix query string false "Prefix of the right location"
+// @Param differences_only query bool false "Leave identical keys out of the report"
+// @Param format query string false "ndjson (default), csv or json"
+// @Success 200 {object} models.CompareResult
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 404 {object} models.ErrorResponse "Unknown connection"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/compare [get]
+func (h *CompareHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	owner := middleware.GetOwnerFromContext(ctx)
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	left := models.TransferLocation{Connection: query.Get("left_connection"), Bucket: query.Get("left_bucket"), Prefix: query.Get("left_prefix")}
+	right := models.TransferLocation{Connection: query.Get("right_connection"), Bucket: query.Get("right_bucket"), Prefix: query.Get("right_prefix")}
+	if left.Bucket == "" || right.Bucket == "" {
+		http.Error(w, "left_bucket and right_bucket are required", http.StatusBadRequest)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = compareFormatNDJSON
+	}
+	if format != compareFormatNDJSON && format != compareFormatCSV && format != compareFormatJSON {
+		http.Error(w, "format must be ndjson, csv or json", http.StatusBadRequest)
+		return
+	}
+	differencesOnly := query.Get("differences_only") == "true"
+
+	leftSession, err := h.sessionManager.Resolve(owner, left.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	rightSession, err := h.sessionManager.Resolve(owner, right.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	left.Connection = leftSession.ID
+	right.Connection = rightSession.ID
+	leftList := syncer.S3(leftSession.S3Client, left.Bucket, left.Prefix)
+	rightList := syncer.S3(rightSession.S3Client, right.Bucket, right.Prefix)
+
+	controller := http.NewResponseController(w)
+	// Comparing large buckets can take longer than the server write timeout
+	controller.SetWriteDeadline(time.Time{})
+
+	var summary models.CompareSummary
+	switch format {
+	case compareFormatJSON:
+		summary, err = h.compareJSON(ctx, w, left, right, leftList, rightList, differencesOnly)
+	case compareFormatCSV:
+		summary, err = h.compareCSV(ctx, w, controller, leftList, rightList, differencesOnly)
+	default:
+		summary, err = h.compareNDJSON(ctx, w, controller, leftList, rightList, differencesOnly)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			h.logger.Info("Comparison cancelled by client")
+			return
+		}
+		h.logger.Error("Comparison failed", slog.String("error", err.Error()))
+		return
+	}
+
+	h.logger.Info("Comparison completed",
+		slog.String("left", left.Bucket+"/"+left.Prefix),
+		slog.String("right", right.Bucket+"/"+right.Prefix),
+		slog.Bool("match", summary.Match))
+}
+
+// compareNDJSON streams entries as they are classified, followed by the summary
+func (h *CompareHandler) compareNDJSON(ctx context.Context, w http.ResponseWriter, controller *http.ResponseController, left, right syncer.Lister, differencesOnly bool) (models.CompareSummary, error) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	encoder := json.NewEncoder(w)
+
+	streamed := false
+	summary, err := syncer.Compare(ctx, left, right, func(entry models.CompareEntry) error {
+		if differencesOnly && entry.Class == models.CompareIdentical {
+			return nil
+		}
+		streamed = true
+		if err := encoder.Encode(models.CompareResult{Type: "entry", Entry: &entry}); err != nil {
+			return err
+		}
+		return controller.Flush()
+	})
+
+	switch {
+	case err == nil:
+		encoder.Encode(models.CompareResult{Type: "summary", Summary: &summary})
+	case errors.Is(err, context.Canceled):
+		return summary, err
+	case !streamed:
+		// Nothing has been streamed yet, so a plain error status can still be sent
+		sendS3Error(w, err)
+		return summary, err
+	default:
+		encoder.Encode(models.CompareResult{Type: "error", Error: err.Error()})
+		controller.Flush()
+		return summary, err
+	}
+	controller.Flush()
+	return summary, nil
+}
+
+// compareCSV streams entries as CSV rows. CSV has no room for a trailing
+// error, so a comparison that fails midway aborts the download.
+func (h *CompareHandler) compareCSV(ctx context.Context, w http.ResponseWriter, controller *http.ResponseController, left, right syncer.Lister, differencesOnly bool) (models.CompareSummary, error) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="compare.csv"`)
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"key", "class", "left_size", "right_size", "left_etag", "right_etag"})
+
+	streamed := false
+	summary, err := syncer.Compare(ctx, left, right, func(entry models.CompareEntry) error {
+		if differencesOnly && entry.Class == models.CompareIdentical {
+			return nil
+		}
+		streamed = true
+		writer.Write([]string{entry.Key, entry.Class, formatSize(entry.LeftSize), formatSize(entry.RightSize), entry.LeftETag, entry.RightETag})
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		return controller.Flush()
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, context.Canceled):
+			return summary, err
+		case !streamed:
+			sendS3Error(w, err)
+			return summary, err
+		}
+		h.logger.Error("Comparison failed after streaming started", slog.String("error", err.Error()))
+		panic(http.ErrAbortHandler)
+	}
+	writer.Flush()
+	return summary, nil
+}
+
+// compareJSON builds the whole report before sending it
+func (h *CompareHandler) compareJSON(ctx context.Context, w http.ResponseWriter, left, right models.TransferLocation, leftList, rightList syncer.Lister, differencesOnly bool) (models.CompareSummary, error) {
+	report := models.CompareReport{Left: left, Right: right, Entries: make([]models.CompareEntry, 0)}
+	summary, err := syncer.Compare(ctx, leftList, rightList, func(entry models.CompareEntry) error {
+		if !differencesOnly || entry.Class != models.CompareIdentical {
+			report.Entries = append(report.Entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			sendS3Error(w, err)
+		}
+		return summary, err
+	}
+	report.Summary = summary
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="compare.json"`)
+	json.NewEncoder(w).Encode(report)
+	return summary, nil
+}
+
+// formatSize renders an optional size for CSV, leaving missing sides empty
+func formatSize(size *int64) string {
+	if size == nil {
+		return ""
+	}
+	return strconv.FormatInt(*size, 10)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	"github.com/cksidharthan/s3-browser/internal/s3errors"
+)
+
+// errOutOfScope rejects uses of buckets and prefixes outside a scoped session
+// that do not go through its S3 client, such as those of the key index, the
+// way the scope guard of the client does
+var errOutOfScope = &smithy.GenericAPIError{
+	Code:    "AccessDenied",
+	Message: "the bucket or prefix is outside the buckets and prefix this session was connected with",
+	Fault:   smithy.FaultClient,
+}
+
+// sendS3Error reports a failed S3 call with the status and JSON error body
+// chosen by s3errors.Translate
+func sendS3Error(w http.ResponseWriter, err error) {
+	status, body := s3errors.Translate(err)
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
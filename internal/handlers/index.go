@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/index"
+	"github.com/cksidharthan/s3-browser/internal/listing"
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+)
+
+// Object sources accepted by endpoints that can be served from the index
+const (
+	sourceAuto  = "auto"
+	sourceIndex = "index"
+	sourceLive  = "live"
+)
+
+// IndexHandler handles the local key index
+type IndexHandler struct {
+	indexer          *index.Indexer
+	operationManager *operations.Manager
+	logger           *slog.Logger
+}
+
+// NewIndexHandler creates a new index handler
+func NewIndexHandler(indexer *index.Indexer, operationManager *operations.Manager, logger *slog.Logger) *IndexHandler {
+	return &IndexHandler{
+		indexer:          indexer,
+		operationManager: operationManager,
+		logger:           logger,
+	}
+}
+
+// ListIndexes lists the indexed buckets of the current connection
+// @Summary List indexes
+// @Description Lists the local key indexes of the current connection with their staleness
+// @Tags Index
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of items (default and max 1000)"
+// @Success 200 {object} models.Page[models.IndexStatus]
+// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Router /api/v1/indexes [get]
+func (h *IndexHandler) ListIndexes(w http.ResponseWriter, r *http.Request) {
+	session := middleware.GetSessionFromContext(r.Context())
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	statuses, err := h.indexer.List(func(status *models.IndexStatus) bool {
+		// Indexes cover whole buckets, so prefix-scoped sessions see none
+		return status.ID == indexScope(session, status.Bucket) && bucketInScope(session, status.Bucket) && session.Prefix == ""
+	})
+	if err != nil {
+		h.logger.Error("Failed to list indexes", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendList(w, r, statuses)
+}
+
+// RefreshIndex creates the index of a bucket if needed and refreshes it
+// @Summary Index bucket
+// @Description Creates the local key index of a bucket if needed and refreshes it in the background.
+// @Description The index is then kept fresh on a schedule while the session is alive.
+// @Tags Index
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Success 202 {object} models.Operation
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403 {object} models.ErrorResponse "Bucket outside the scope of the session"
+// @Failure 404 {object} models.ErrorResponse "Indexing is disabled"
+// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Router /api/v1/buckets/{bucket}/index [put]
+func (h *IndexHandler) RefreshIndex(w http.ResponseWriter, r *http.Request) {
+	session := middleware.GetSessionFromContext(r.Context())
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+	if !h.indexer.Enabled() {
+		http.Error(w, "Indexing is disabled; start the server with -data-dir", http.StatusNotFound)
+		return
+	}
+	if session.Prefix != "" {
+		// The index lists whole buckets, which a prefix-scoped session may not do
+		http.Error(w, "Indexing is not available for prefix-scoped connections", http.StatusBadRequest)
+		return
+	}
+
+	bucket := pathParam(r, "bucket", h.extractBucketNameFromPath)
+	if bucket == "" {
+		http.Error(w, "Bucket name is required", http.StatusBadRequest)
+		return
+	}
+	if !bucketInScope(session, bucket) {
+		sendS3Error(w, errOutOfScope)
+		return
+	}
+
+	scopeID := indexScope(session, bucket)
+	client := session.S3Client
+	if err := h.indexer.Enable(session.ID, client, scopeID, session.Endpoint, bucket); err != nil {
+		h.logger.Error("Failed to enable index",
+			slog.String("bucket", bucket),
+			slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	op := h.operationManager.Start(middleware.GetOwnerFromContext(r.Context()).ID, "index-refresh", bucket,
+		func(ctx context.Context, op *operations.Operation) error {
+			op.SetPhase("listing objects")
+			return h.indexer.Refresh(ctx, client, scopeID, op.SetDone)
+		})
+	sendOperationAccepted(w, r, op)
+}
+
+// DeleteIndex removes the local key index of a bucket
+// @Summary Drop index
+// @Description Removes the local key index of a bucket
+// @Tags Index
+// @Param bucket path string true "Bucket name"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse "Bucket outside the scope of the session"
+// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Router /api/v1/buckets/{bucket}/index [delete]
+func (h *IndexHandler) DeleteIndex(w http.ResponseWriter, r *http.Request) {
+	session := middleware.GetSessionFromContext(r.Context())
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	bucket := pathParam(r, "bucket", h.extractBucketNameFromPath)
+	if bucket == "" {
+		http.Error(w, "Bucket name is required", http.StatusBadRequest)
+		return
+	}
+	if !bucketInScope(session, bucket) || session.Prefix != "" {
+		// Other sessions of the same principal share the index
+		sendS3Error(w, errOutOfScope)
+		return
+	}
+
+	if err := h.indexer.Drop(indexScope(session, bucket)); err != nil {
+		h.logger.Error("Failed to drop index",
+			slog.String("bucket", bucket),
+			slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Index dropped", slog.String("bucket", bucket))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendWalkerError reports why objectWalker could not enumerate a bucket
+func sendWalkerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errOutOfScope) {
+		sendS3Error(w, err)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// extractBucketNameFromPath extracts bucket name from URL path like "/api/index/{bucket}"
+func (h *IndexHandler) extractBucketNameFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[len(parts)-2] == "index" {
+		return parts[len(parts)-1]
+	}
+	return ""
+}
+
+// indexScope identifies the index of bucket for the session's endpoint and principal
+func indexScope(session *models.Session, bucket string) string {
+	return index.ScopeID(session.Endpoint, session.Principal(), bucket)
+}
+
+// bucketInScope reports whether session may use bucket: it declared no
+// buckets, or bucket is one of them
+func bucketInScope(session *models.Session, bucket string) bool {
+	return len(session.Buckets) == 0 || slices.Contains(session.Buckets, bucket)
+}
+
+// objectWalker selects how to enumerate the objects of bucket. With source
+// "live" the bucket is listed; with "index" the local index must exist; with
+// "auto" (the default) the index is used once it has been refreshed at least
+// once. The returned status is nil for live listings. Buckets outside a
+// scoped session are rejected with errOutOfScope, as the index is read
+// without the scope guard of the session's S3 client.
+func objectWalker(ix *index.Indexer, session *models.Session, bucket, source string) (listing.WalkFunc, *models.IndexStatus, error) {
+	if !bucketInScope(session, bucket) {
+		return nil, nil, errOutOfScope
+	}
+	live := listing.Bucket(session.S3Client, bucket)
+
+	switch source {
+	case "", sourceAuto, sourceIndex:
+	case sourceLive:
+		return live, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("source must be one of %s, %s or %s", sourceAuto, sourceIndex, sourceLive)
+	}
+
+	scopeID := indexScope(session, bucket)
+	status, err := ix.Status(scopeID)
+	if err == nil && status.RefreshedAt == nil {
+		err = index.ErrNotIndexed
+	}
+	if err != nil {
+		if source == sourceIndex || !errors.Is(err, index.ErrNotIndexed) {
+			return nil, nil, err
+		}
+		return live, nil, nil
+	}
+
+	// Keep the index fresh with this session's credentials, e.g. after a restart
+	ix.Attach(session.ID, session.S3Client, scopeID)
+
+	indexed := func(ctx context.Context, prefix string, fn func(obj types.Object) error) error {
+		return ix.Walk(ctx, scopeID, prefix, fn)
+	}
+	return indexed, status, nil
+}
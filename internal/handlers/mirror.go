@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/mirror"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+	"github.com/cksidharthan/s3-browser/internal/session"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// MirrorHandler handles mirroring between S3 and the server's local filesystem
+type MirrorHandler struct {
+	sessionManager   *session.Manager
+	operationManager *operations.Manager
+	roots            *mirror.Roots
+	logger           *slog.Logger
+}
+
+// NewMirrorHandler creates a new mirror handler; mirroring is disabled when roots is nil
+func NewMirrorHandler(sessionManager *session.Manager, operationManager *operations.Manager, roots *mirror.Roots, logger *slog.Logger) *MirrorHandler {
+	return &MirrorHandler{
+		sessionManager:   sessionManager,
+		operationManager: operationManager,
+		roots:            roots,
+		logger:           logger,
+	}
+}
+
+// ListMirrorRoots lists the local directories available for mirroring
+// @Summary List mirror roots
+// @Description Lists the names of the local directories the operator allows mirroring to and from.
+// @Description Empty when mirroring is not enabled.
+// @Tags Mirror
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of items (default and max 1000)"
+// @Success 200 {object} models.Page[string]
+// @Router /api/v1/mirror-roots [get]
+func (h *MirrorHandler) ListMirrorRoots(w http.ResponseWriter, r *http.Request) {
+	sendList(w, r, h.roots.Names())
+}
+
+// StartMirror starts copying between a bucket prefix and a local directory
+// @Summary Mirror to or from the local filesystem
+// @Description Exports a bucket prefix to a directory under one of the operator's mirror roots, or
+// @Description imports such a directory into a bucket prefix. Both sides are compared by size and
+// @Description modification time and only what differs is copied; with delete, what is missing from
+// @Description the source is removed from the destination. Runs as a background operation that can be
+// @Description cancelled and resumed. Paths cannot leave the root.
+// @Tags Mirror
+// @Accept json
+// @Produce json
+// @Param request body models.MirrorRequest true "Direction, bucket, prefix, root and path"
+// @Success 202 {object} models.Operation
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403 {object} models.ErrorResponse "Mirroring is not enabled or the connection is read-only"
+// @Failure 404 {object} models.ErrorResponse "Unknown connection or root"
+// @Router /api/v1/mirrors [post]
+func (h *MirrorHandler) StartMirror(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+	if h.roots == nil {
+		http.Error(w, "Mirroring to the local filesystem is not enabled", http.StatusForbidden)
+		return
+	}
+
+	var req models.MirrorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.Direction != mirror.Export && req.Direction != mirror.Import {
+		http.Error(w, "direction must be export or import", http.StatusBadRequest)
+		return
+	}
+	if req.Bucket == "" || req.Root == "" {
+		http.Error(w, "Bucket and root are required", http.StatusBadRequest)
+		return
+	}
+
+	root, err := h.roots.Get(req.Root)
+	if err != nil {
+		http.Error(w, "Unknown mirror root", http.StatusNotFound)
+		return
+	}
+	remote, err := h.sessionManager.Resolve(owner, req.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	if req.Direction == mirror.Import && h.sessionManager.IsReadOnly(remote) {
+		http.Error(w, "This connection is read-only", http.StatusForbidden)
+		return
+	}
+
+	m := mirror.New(req.Direction,
+		transfer.Location{Client: remote.S3Client, Bucket: req.Bucket, Prefix: req.Prefix},
+		root, req.Path, req.Delete, h.logger)
+	target := req.Bucket + "/" + req.Prefix + " -> " + req.Root + ":/" + req.Path
+	if req.Direction == mirror.Import {
+		target = req.Root + ":/" + req.Path + " -> " + req.Bucket + "/" + req.Prefix
+	}
+	op := h.operationManager.Start(owner.ID, req.Direction, target, m.Run)
+
+	h.logger.Info("Mirror started",
+		slog.String("operation_id", op.ID()),
+		slog.String("direction", req.Direction),
+		slog.String("connection_id", remote.ID),
+		slog.String("target", target),
+		slog.Bool("delete", req.Delete))
+	sendOperationAccepted(w, r, op)
+}
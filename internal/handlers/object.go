@@ -13,31 +13,39 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/index"
+	"github.com/cksidharthan/s3-browser/internal/listing"
 	"github.com/cksidharthan/s3-browser/internal/middleware"
 	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
 )
 
 // ObjectHandler handles object-related operations
 type ObjectHandler struct {
-	logger *slog.Logger
+	indexer *index.Indexer
+	logger  *slog.Logger
 }
 
 // NewObjectHandler creates a new object handler
-func NewObjectHandler(logger *slog.Logger) *ObjectHandler {
+func NewObjectHandler(indexer *index.Indexer, logger *slog.Logger) *ObjectHandler {
 	return &ObjectHandler{
-		logger: logger,
+		indexer: indexer,
+		logger:  logger,
 	}
 }
 
 // ListObjects lists objects in a bucket
-// @Summary List objects
-// @Description Lists all objects in a specified S3 bucket
+// @Summary List objects (deprecated)
+// @Description Lists up to 1000 objects in a specified S3 bucket. Use GET /api/v1/buckets/{bucket}/objects instead.
 // @Tags Objects
 // @Produce json
 // @Param bucket query string true "Bucket name"
+// @Param prefix query string false "Only list keys under this prefix (defaults to the session prefix)"
 // @Success 200 {array} models.S3Object
 // @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Deprecated
 // @Router /api/objects [get]
 func (h *ObjectHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -53,36 +61,31 @@ func (h *ObjectHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := session.S3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucket),
-	})
+	}
+	prefix, err := queryPrefix(r, session)
+	if err != nil {
+		sendS3Error(w, err)
+		return
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	result, err := session.S3Client.ListObjectsV2(ctx, input)
 	if err != nil {
 		h.logger.Error("Failed to list objects",
 			slog.String("bucket", bucket),
 			slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		sendS3Error(w, err)
 		return
 	}
 
 	objects := make([]models.S3Object, 0, len(result.Contents))
 	for _, obj := range result.Contents {
 		if obj.Key != nil {
-			s3Object := models.S3Object{
-				Key:  aws.ToString(obj.Key),
-				Size: aws.ToInt64(obj.Size),
-			}
-
-			if obj.ETag != nil {
-				s3Object.ETag = aws.ToString(obj.ETag)
-			}
-			if obj.StorageClass != "" {
-				s3Object.StorageClass = string(obj.StorageClass)
-			}
-			if obj.LastModified != nil {
-				s3Object.LastModified = obj.LastModified.Format("2006-01-02 15:04:05")
-			}
-
-			objects = append(objects, s3Object)
+			objects = append(objects, listing.ToS3Object(obj, models.LegacyTimeLayout))
 		}
 	}
 
@@ -90,9 +93,84 @@ func (h *ObjectHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(objects)
 }
 
+// ListObjectsV1 lists a page of the objects in a bucket
+// @Summary List objects
+// @Description Lists a page of the objects in a bucket, in key order. With a delimiter, keys that contain
+// @Description it after the prefix are rolled up into prefixes, like folders.
+// @Tags Objects
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string false "Only list keys under this prefix (defaults to the session prefix)"
+// @Param delimiter query string false "Roll up keys into common prefixes at this character, usually /"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of objects and prefixes (default and max 1000)"
+// @Success 200 {object} models.ObjectPage
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/objects [get]
+func (h *ObjectHandler) ListObjectsV1(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := middleware.GetSessionFromContext(ctx)
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	bucket := r.PathValue("bucket")
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		MaxKeys: aws.Int32(int32(limit)),
+	}
+	prefix, err := queryPrefix(r, session)
+	if err != nil {
+		sendS3Error(w, err)
+		return
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if delimiter := r.URL.Query().Get("delimiter"); delimiter != "" {
+		input.Delimiter = aws.String(delimiter)
+	}
+	if cursor != "" {
+		input.ContinuationToken = aws.String(cursor)
+	}
+
+	result, err := session.S3Client.ListObjectsV2(ctx, input)
+	if err != nil {
+		h.logger.Error("Failed to list objects",
+			slog.String("bucket", bucket),
+			slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+
+	page := models.ObjectPage{Items: make([]models.S3Object, 0, len(result.Contents))}
+	for _, obj := range result.Contents {
+		if obj.Key != nil {
+			page.Items = append(page.Items, listing.ToS3Object(obj, time.RFC3339))
+		}
+	}
+	for _, prefix := range result.CommonPrefixes {
+		page.Prefixes = append(page.Prefixes, aws.ToString(prefix.Prefix))
+	}
+	if aws.ToBool(result.IsTruncated) {
+		page.NextCursor = aws.ToString(result.NextContinuationToken)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
 // UploadObject uploads an object to S3
-// @Summary Upload object
-// @Description Uploads a file to the specified S3 bucket
+// @Summary Upload object (deprecated)
+// @Description Uploads a file to the specified S3 bucket. Use PUT /api/v1/buckets/{bucket}/objects/{key} instead.
 // @Tags Objects
 // @Accept multipart/form-data
 // @Produce json
@@ -101,7 +179,8 @@ func (h *ObjectHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
 // @Param file formData file true "File to upload"
 // @Success 201 {object} map[string]string
 // @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Deprecated
 // @Router /api/objects/{key} [post]
 func (h *ObjectHandler) UploadObject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -111,13 +190,11 @@ func (h *ObjectHandler) UploadObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bucket := r.URL.Query().Get("bucket")
+	bucket, key := h.objectParams(r)
 	if bucket == "" {
 		http.Error(w, "Bucket name is required", http.StatusBadRequest)
 		return
 	}
-
-	key := h.extractObjectKeyFromPath(r.URL.Path)
 	if key == "" {
 		http.Error(w, "Object key is required", http.StatusBadRequest)
 		return
@@ -169,7 +246,7 @@ func (h *ObjectHandler) UploadObject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	_, err = session.S3Client.PutObject(ctx, &s3.PutObjectInput{
+	output, err := session.S3Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:         aws.String(bucket),
 		Key:            aws.String(key),
 		Body:           file,
@@ -182,10 +259,17 @@ func (h *ObjectHandler) UploadObject(w http.ResponseWriter, r *http.Request) {
 			slog.String("bucket", bucket),
 			slog.String("key", key),
 			slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		sendS3Error(w, err)
 		return
 	}
 
+	h.indexer.Put(indexScope(session, bucket), types.Object{
+		Key:          aws.String(key),
+		Size:         aws.Int64(header.Size),
+		ETag:         output.ETag,
+		LastModified: aws.Time(time.Now()),
+	})
+
 	h.logger.Info("Object uploaded",
 		slog.String("bucket", bucket),
 		slog.String("key", key),
@@ -200,17 +284,96 @@ func (h *ObjectHandler) UploadObject(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PutObject stores the request body as an object
+// @Summary Upload object
+// @Description Stores the request body as an object, with the request's Content-Type. Bodies larger
+// @Description than a part are uploaded in parts. Responds with the stored object.
+// @Tags Objects
+// @Accept application/octet-stream
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param key path string true "Object key"
+// @Success 201 {object} models.S3Object
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 411 {object} models.ErrorResponse "Content-Length is required"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/objects/{key} [put]
+func (h *ObjectHandler) PutObject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := middleware.GetSessionFromContext(ctx)
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	bucket, key := h.objectParams(r)
+	if key == "" {
+		http.Error(w, "Object key is required", http.StatusBadRequest)
+		return
+	}
+	if r.ContentLength < 0 {
+		http.Error(w, "Content-Length is required", http.StatusLengthRequired)
+		return
+	}
+
+	// Large uploads can take longer than the server timeouts
+	controller := http.NewResponseController(w)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	destination := transfer.Location{Client: session.S3Client, Bucket: bucket}
+	err := transfer.Upload(ctx, destination, key, r.Body, r.ContentLength, r.Header.Get("Content-Type"), h.logger)
+	if err != nil {
+		h.logger.Error("Failed to upload object",
+			slog.String("bucket", bucket),
+			slog.String("key", key),
+			slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+
+	head, err := session.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		h.logger.Error("Failed to read uploaded object",
+			slog.String("bucket", bucket),
+			slog.String("key", key),
+			slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+	obj := types.Object{
+		Key:          aws.String(key),
+		Size:         head.ContentLength,
+		ETag:         head.ETag,
+		LastModified: head.LastModified,
+		StorageClass: types.ObjectStorageClass(head.StorageClass),
+	}
+	h.indexer.Put(indexScope(session, bucket), obj)
+
+	h.logger.Info("Object uploaded",
+		slog.String("bucket", bucket),
+		slog.String("key", key),
+		slog.Int64("size", r.ContentLength))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", r.URL.Path)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(listing.ToS3Object(obj, time.RFC3339))
+}
+
 // ViewObject returns an object from S3
 // @Summary View/Download object
 // @Description Retrieves an object from S3 for viewing or download
 // @Tags Objects
+// @Param bucket path string true "Bucket name"
 // @Param key path string true "Object key"
-// @Param bucket query string true "Bucket name"
 // @Success 200 "Object content"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 404 {string} string "Object not found"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /api/objects/{key} [get]
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/objects/{key} [get]
 func (h *ObjectHandler) ViewObject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := middleware.GetSessionFromContext(ctx)
@@ -219,13 +382,11 @@ func (h *ObjectHandler) ViewObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bucket := r.URL.Query().Get("bucket")
+	bucket, key := h.objectParams(r)
 	if bucket == "" {
 		http.Error(w, "Bucket name is required", http.StatusBadRequest)
 		return
 	}
-
-	key := h.extractObjectKeyFromPath(r.URL.Path)
 	if key == "" {
 		http.Error(w, "Object key is required", http.StatusBadRequest)
 		return
@@ -240,13 +401,7 @@ func (h *ObjectHandler) ViewObject(w http.ResponseWriter, r *http.Request) {
 			slog.String("bucket", bucket),
 			slog.String("key", key),
 			slog.String("error", err.Error()))
-
-		// Determine status code based on error
-		if strings.Contains(err.Error(), "NoSuchKey") {
-			http.Error(w, "Object not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		sendS3Error(w, err)
 		return
 	}
 	defer result.Body.Close()
@@ -282,13 +437,12 @@ func (h *ObjectHandler) ViewObject(w http.ResponseWriter, r *http.Request) {
 // @Summary Delete object
 // @Description Deletes an object from the specified S3 bucket
 // @Tags Objects
+// @Param bucket path string true "Bucket name"
 // @Param key path string true "Object key"
-// @Param bucket query string true "Bucket name"
 // @Success 204 "No Content"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 404 {string} string "Object not found"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /api/objects/{key} [delete]
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/objects/{key} [delete]
 func (h *ObjectHandler) DeleteObject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := middleware.GetSessionFromContext(ctx)
@@ -297,13 +451,11 @@ func (h *ObjectHandler) DeleteObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bucket := r.URL.Query().Get("bucket")
+	bucket, key := h.objectParams(r)
 	if bucket == "" {
 		http.Error(w, "Bucket name is required", http.StatusBadRequest)
 		return
 	}
-
-	key := h.extractObjectKeyFromPath(r.URL.Path)
 	if key == "" {
 		http.Error(w, "Object key is required", http.StatusBadRequest)
 		return
@@ -320,10 +472,12 @@ func (h *ObjectHandler) DeleteObject(w http.ResponseWriter, r *http.Request) {
 			slog.String("error", err.Error()))
 
 		// S3 delete doesn't fail if object doesn't exist, but handle other errors
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		sendS3Error(w, err)
 		return
 	}
 
+	h.indexer.Delete(indexScope(session, bucket), key)
+
 	h.logger.Info("Object deleted",
 		slog.String("bucket", bucket),
 		slog.String("key", key))
@@ -335,12 +489,12 @@ func (h *ObjectHandler) DeleteObject(w http.ResponseWriter, r *http.Request) {
 // @Description Generate a temporary URL for direct browser access to an S3 object
 // @Tags Objects
 // @Produce json
-// @Param bucket query string true "Bucket name"
-// @Param key query string true "Object key"
-// @Success 200 {object} map[string]string
-// @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
-// @Router /api/presigned-url [get]
+// @Param bucket path string true "Bucket name"
+// @Param key path string true "Object key"
+// @Success 200 {object} models.PresignedURL
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/presigned-urls/{key} [get]
 func (h *ObjectHandler) GetPresignedURL(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	session := middleware.GetSessionFromContext(ctx)
@@ -349,13 +503,11 @@ func (h *ObjectHandler) GetPresignedURL(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	bucket := r.URL.Query().Get("bucket")
+	bucket, key := h.objectParams(r)
 	if bucket == "" {
 		http.Error(w, "Bucket name is required", http.StatusBadRequest)
 		return
 	}
-
-	key := r.URL.Query().Get("key")
 	if key == "" {
 		http.Error(w, "Object key is required", http.StatusBadRequest)
 		return
@@ -381,6 +533,8 @@ func (h *ObjectHandler) GetPresignedURL(w http.ResponseWriter, r *http.Request)
 
 	// Create the presigner
 	presignClient := s3.NewPresignClient(session.S3Client)
+	expires := 15 * time.Minute
+	expiresAt := time.Now().Add(expires).UTC().Truncate(time.Second)
 
 	// Generate a presigned URL with the content type explicitly set
 	presignResult, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
@@ -388,7 +542,7 @@ func (h *ObjectHandler) GetPresignedURL(w http.ResponseWriter, r *http.Request)
 		Key:                 aws.String(key),
 		ResponseContentType: aws.String(contentType), // Force the correct content type
 	}, func(opts *s3.PresignOptions) {
-		opts.Expires = 15 * time.Minute
+		opts.Expires = expires
 	})
 
 	if err != nil {
@@ -396,22 +550,40 @@ func (h *ObjectHandler) GetPresignedURL(w http.ResponseWriter, r *http.Request)
 			slog.String("bucket", bucket),
 			slog.String("key", key),
 			slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		sendS3Error(w, err)
 		return
 	}
 
 	// Return the presigned URL as JSON
 	w.Header().Set("Content-Type", "application/json")
+	if middleware.IsAPIv1(r) {
+		json.NewEncoder(w).Encode(models.PresignedURL{URL: presignResult.URL, ExpiresAt: expiresAt})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{
 		"url": presignResult.URL,
 	})
 }
 
-// extractObjectKeyFromPath extracts object key from URL path like "/api/objects/{key}"
+// objectParams returns the bucket and key of an object request: the path
+// wildcards of a v1 route, or the bucket query parameter and the key in the
+// path or the key query parameter of a deprecated route
+func (h *ObjectHandler) objectParams(r *http.Request) (string, string) {
+	if middleware.IsAPIv1(r) {
+		return r.PathValue("bucket"), r.PathValue("key")
+	}
+	query := r.URL.Query()
+	if key := h.extractObjectKeyFromPath(r.URL.Path); key != "" {
+		return query.Get("bucket"), key
+	}
+	return query.Get("bucket"), query.Get("key")
+}
+
+// extractObjectKeyFromPath extracts object key from URL path like "/api/objects/{key}".
+// The key is everything after the route so that keys under a prefix keep their slashes.
 func (h *ObjectHandler) extractObjectKeyFromPath(path string) string {
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) >= 3 && parts[len(parts)-2] == "objects" {
-		return parts[len(parts)-1]
+	if key, ok := strings.CutPrefix(path, "/api/objects/"); ok {
+		return key
 	}
 	return ""
 }
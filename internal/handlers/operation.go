@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+)
+
+// OperationHandler handles background operation queries and cancellation
+type OperationHandler struct {
+	operationManager *operations.Manager
+	logger           *slog.Logger
+}
+
+// NewOperationHandler creates a new operation handler
+func NewOperationHandler(operationManager *operations.Manager, logger *slog.Logger) *OperationHandler {
+	return &OperationHandler{
+		operationManager: operationManager,
+		logger:           logger,
+	}
+}
+
+// ListOperations lists the background operations of the current session
+// @Summary List operations
+// @Description Lists background operations started by the current session, newest first. Operations
+// @Description belong to the browser session, whichever of its connections they work on.
+// @Tags Operations
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of items (default and max 1000)"
+// @Success 200 {object} models.Page[models.Operation]
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Router /api/v1/operations [get]
+func (h *OperationHandler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	ops := h.operationManager.List(owner.ID)
+	snapshots := make([]models.Operation, 0, len(ops))
+	for _, op := range ops {
+		snapshots = append(snapshots, op.Snapshot())
+	}
+
+	sendList(w, r, snapshots)
+}
+
+// GetOperation returns the status and progress of a background operation
+// @Summary Get operation
+// @Description Returns the status and progress of a background operation
+// @Tags Operations
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 200 {object} models.Operation
+// @Failure 404 {object} models.ErrorResponse "Operation not found"
+// @Router /api/v1/operations/{id} [get]
+func (h *OperationHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	op := h.operationManager.Get(owner.ID, h.extractOperationIDFromPath(r.URL.Path))
+	if op == nil {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(op.Snapshot())
+}
+
+// CancelOperation requests cancellation of a running background operation
+// @Summary Cancel operation
+// @Description Requests cancellation of a running background operation
+// @Tags Operations
+// @Param id path string true "Operation ID"
+// @Success 202 "Accepted"
+// @Failure 404 {object} models.ErrorResponse "Operation not found"
+// @Router /api/v1/operations/{id} [delete]
+func (h *OperationHandler) CancelOperation(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	id := h.extractOperationIDFromPath(r.URL.Path)
+	if !h.operationManager.Cancel(owner.ID, id) {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+
+	h.logger.Info("Operation cancellation requested", slog.String("operation_id", id))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResumeOperation starts a failed or cancelled operation again
+// @Summary Resume operation
+// @Description Starts a failed or cancelled operation again as a new operation that skips the work
+// @Description already done, e.g. the objects a transfer has copied
+// @Tags Operations
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 202 {object} models.Operation
+// @Failure 404 {object} models.ErrorResponse "Operation not found"
+// @Failure 409 {object} models.ErrorResponse "Operation is running or completed"
+// @Router /api/v1/operations/{id}/resume [post]
+func (h *OperationHandler) ResumeOperation(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	id := h.extractOperationIDFromPath(strings.TrimSuffix(r.URL.Path, "/resume"))
+	op, err := h.operationManager.Resume(owner.ID, id)
+	switch {
+	case errors.Is(err, operations.ErrNotFound):
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	case errors.Is(err, operations.ErrNotResumable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	h.logger.Info("Operation resumed",
+		slog.String("operation_id", id),
+		slog.String("resumed_as", op.ID()))
+	sendOperationAccepted(w, r, op)
+}
+
+// extractOperationIDFromPath extracts operation ID from URL path like "/api/operations/{id}"
+func (h *OperationHandler) extractOperationIDFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[len(parts)-2] == "operations" {
+		return parts[len(parts)-1]
+	}
+	return ""
+}
+
+// sendOperationAccepted responds with 202 and a pointer to the started operation
+func sendOperationAccepted(w http.ResponseWriter, r *http.Request, op *operations.Operation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", apiBase(r)+"/operations/"+op.ID())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(op.Snapshot())
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// maxPageLimit is the default and the largest number of items in a page of a v1 list
+const maxPageLimit = 1000
+
+// errInvalidCursor reports a cursor that no page of the list returned
+var errInvalidCursor = errors.New("cursor is not valid for this list")
+
+// pageParams reads the cursor and limit query parameters of a v1 list
+func pageParams(r *http.Request) (string, int, error) {
+	limit, err := queryInt(r, "limit", maxPageLimit, 1, maxPageLimit)
+	return r.URL.Query().Get("cursor"), limit, err
+}
+
+// paginate cuts a page out of a list that is held in memory; its cursors
+// are offsets into the list
+func paginate[T any](items []T, cursor string, limit int) (models.Page[T], error) {
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 || offset > len(items) {
+			return models.Page[T]{}, errInvalidCursor
+		}
+	}
+
+	end := min(offset+limit, len(items))
+	page := models.Page[T]{Items: make([]T, 0, end-offset)}
+	page.Items = append(page.Items, items[offset:end]...)
+	if end < len(items) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+// sendList responds with items: a page of them on /api/v1, all of them as a
+// plain array on the deprecated routes
+func sendList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	w.Header().Set("Content-Type", "application/json")
+	if !middleware.IsAPIv1(r) {
+		if items == nil {
+			items = []T{}
+		}
+		json.NewEncoder(w).Encode(items)
+		return
+	}
+
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := paginate(items, cursor, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// timeLayout is how timestamps of r's API version are formatted
+func timeLayout(r *http.Request) string {
+	if middleware.IsAPIv1(r) {
+		return time.RFC3339
+	}
+	return models.LegacyTimeLayout
+}
+
+// apiBase is the root of r's API version, for Location headers
+func apiBase(r *http.Request) string {
+	if middleware.IsAPIv1(r) {
+		return "/api/v1"
+	}
+	return "/api"
+}
+
+// pathParam returns the named wildcard of a v1 route, or what extract finds
+// in the path of the deprecated route
+func pathParam(r *http.Request, name string, extract func(string) string) string {
+	if value := r.PathValue(name); value != "" {
+		return value
+	}
+	return extract(r.URL.Path)
+}
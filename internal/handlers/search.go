@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/index"
+	"github.com/cksidharthan/s3-browser/internal/listing"
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/search"
+)
+
+// errSearchLimitReached stops a search walk once enough matches were streamed
+var errSearchLimitReached = errors.New("search result limit reached")
+
+// SearchHandler handles server-side object search
+type SearchHandler struct {
+	indexer *index.Indexer
+	logger  *slog.Logger
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(indexer *index.Indexer, logger *slog.Logger) *SearchHandler {
+	return &SearchHandler{
+		indexer: indexer,
+		logger:  logger,
+	}
+}
+
+// Search streams the objects of a bucket that match the given filters
+// @Summary Search objects
+// @Description Walks a bucket or prefix and streams matching objects as NDJSON. Each line is a
+// @Description models.SearchResult of type "match"; the last line is a "summary" (or an "error").
+// @Description Closing the connection cancels the walk. Indexed buckets are searched in the local
+// @Description index unless source=live.
+// @Tags Objects
+// @Produce application/x-ndjson
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string false "Only search under this prefix"
+// @Param glob query string false "Glob on the full key (* and ? stay within a path segment, ** spans segments)"
+// @Param regex query string false "Regular expression on the full key"
+// @Param ignore_case query bool false "Match glob or regex case-insensitively"
+// @Param min_size query int false "Minimum size in bytes"
+// @Param max_size query int false "Maximum size in bytes"
+// @Param modified_after query string false "Only objects modified after this RFC 3339 time"
+// @Param modified_before query string false "Only objects modified before this RFC 3339 time"
+// @Param storage_class query string false "Comma-separated storage classes"
+// @Param etag query string false "Exact ETag"
+// @Param limit query int false "Maximum number of matches (default 1000, max 100000)"
+// @Param source query string false "auto (default), index or live"
+// @Success 200 {object} models.SearchResult
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/search [get]
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := middleware.GetSessionFromContext(ctx)
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	bucket := extractBucketNameFromSubresourcePath(r.URL.Path)
+	if bucket == "" {
+		http.Error(w, "Bucket name is required", http.StatusBadRequest)
+		return
+	}
+	prefix, err := queryPrefix(r, session)
+	if err != nil {
+		sendS3Error(w, err)
+		return
+	}
+
+	filter, err := search.ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", 1000, 1, 100000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	walk, indexStatus, err := objectWalker(h.indexer, session, bucket, r.URL.Query().Get("source"))
+	if err != nil {
+		sendWalkerError(w, err)
+		return
+	}
+
+	layout := timeLayout(r)
+	controller := http.NewResponseController(w)
+	// Searching a large bucket can take longer than the server write timeout
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	encoder := json.NewEncoder(w)
+
+	summary := models.SearchSummary{Source: sourceLive}
+	if indexStatus != nil {
+		summary.Source = sourceIndex
+		summary.IndexedAt = indexStatus.RefreshedAt
+	}
+	err = walk(ctx, prefix, func(obj types.Object) error {
+		summary.Scanned++
+		if !filter.Match(obj) {
+			return nil
+		}
+		if summary.Matched == int64(limit) {
+			summary.Truncated = true
+			return errSearchLimitReached
+		}
+		summary.Matched++
+
+		object := listing.ToS3Object(obj, layout)
+		if err := encoder.Encode(models.SearchResult{Type: "match", Object: &object}); err != nil {
+			return err
+		}
+		return controller.Flush()
+	})
+
+	switch {
+	case err == nil || errors.Is(err, errSearchLimitReached):
+		encoder.Encode(models.SearchResult{Type: "summary", Summary: &summary})
+	case errors.Is(err, context.Canceled):
+		h.logger.Info("Search cancelled by client", slog.String("bucket", bucket))
+		return
+	default:
+		h.logger.Error("Search failed",
+			slog.String("bucket", bucket),
+			slog.String("error", err.Error()))
+		// Nothing has been streamed yet, so a plain error status can still be sent
+		if summary.Matched == 0 {
+			sendS3Error(w, err)
+			return
+		}
+		encoder.Encode(models.SearchResult{Type: "error", Error: err.Error()})
+	}
+	controller.Flush()
+
+	h.logger.Info("Search completed",
+		slog.String("bucket", bucket),
+		slog.Int64("scanned", summary.Scanned),
+		slog.Int64("matched", summary.Matched))
+}
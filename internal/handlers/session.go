@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/aws/smithy-go"
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/middleware"
 	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/s3errors"
 	"github.com/cksidharthan/s3-browser/internal/session"
 )
 
@@ -26,16 +32,43 @@ func NewSessionHandler(sessionManager *session.Manager, logger *slog.Logger) *Se
 
 // CheckSession checks if a valid session exists
 // @Summary Check session status
-// @Description Check if the current request has a valid session
+// @Description Check if the current request has a valid session. credentials_expired tells the UI
+// @Description that temporary credentials ran out and the user has to reconnect. read_only tells it
+// @Description to hide uploads, deletes and bucket changes, which the server rejects with 403.
+// @Description Both describe the active connection; connections lists all connections of the session.
+// @Description user is who signed in to S3 Browser, when sign-in is required.
 // @Tags Session
 // @Produce json
 // @Success 200 {object} models.SessionStatusResponse
-// @Router /api/session/status [get]
+// @Router /api/v1/session [get]
 func (h *SessionHandler) CheckSession(w http.ResponseWriter, r *http.Request) {
-	session := h.sessionManager.GetSessionFromCookie(r)
+	owner := h.sessionManager.GetSessionFromCookie(r)
 	
 	response := models.SessionStatusResponse{
-		HasSession: session != nil,
+		HasSession: owner != nil,
+		User:       identity.UserFromContext(r.Context()),
+	}
+	if owner != nil {
+		session, _ := h.sessionManager.Resolve(owner, "")
+		response.ExpiresAt = session.ExpiresAt
+		response.ReadOnly = h.sessionManager.IsReadOnly(session)
+		response.ActiveConnection = session.ID
+		if session.CredentialsHaveExpired() {
+			response.HasSession = false
+			response.CredentialsExpired = true
+		}
+		for _, conn := range h.sessionManager.SessionConnections(owner) {
+			response.Connections = append(response.Connections, models.SessionConnection{
+				ID:                 conn.ID,
+				Connection:         conn.Connection,
+				Endpoint:           conn.Endpoint,
+				Region:             conn.Region,
+				ReadOnly:           h.sessionManager.IsReadOnly(conn),
+				CredentialsExpired: conn.CredentialsHaveExpired(),
+				Active:             conn.ID == session.ID,
+				CreatedAt:          conn.CreatedAt,
+			})
+		}
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -44,63 +77,165 @@ func (h *SessionHandler) CheckSession(w http.ResponseWriter, r *http.Request) {
 
 // Connect establishes a new S3 connection and creates a session
 // @Summary Connect to S3
-// @Description Establish connection to S3 storage and create session
+// @Description Establish connection to S3 storage and create session. Temporary credentials
+// @Description are supported through session_token and their expiration. With assume_role the
+// @Description credentials are used to assume an IAM role via STS, optionally with MFA. With profile
+// @Description the server's own credentials for one of the profiles from /api/v1/profiles are used.
+// @Description path_style, ca_cert_pem, insecure_skip_verify and proxy_url control how S3 is reached.
+// @Description With anonymous, no credentials are sent and only the named buckets are browsed.
+// @Description buckets and prefix restrict any session to those buckets and keys under the prefix.
+// @Description read_only rejects every change for the lifetime of the session.
+// @Description With add=true the connection is added to the current session and becomes active.
 // @Tags Session
 // @Accept json
 // @Produce json
 // @Param connection body models.ConnectionRequest true "Connection parameters"
+// @Param add query bool false "Add the connection to the current session"
 // @Success 200 {object} models.ConnectionResponse
-// @Failure 400 {object} models.ConnectionResponse
-// @Router /api/connect [post]
+// @Failure 400,403 {object} models.ErrorResponse
+// @Failure 404,429,503 {object} models.ErrorResponse "Failed connection test"
+// @Router /api/v1/session [post]
 func (h *SessionHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+
+	if !h.sessionManager.AdHocAllowed() {
+		h.sendConnectionError(w, r, http.StatusForbidden, session.ErrAdHocDisabled.Error())
+		return
+	}
 	
 	var connReq models.ConnectionRequest
 	if err := json.NewDecoder(r.Body).Decode(&connReq); err != nil {
-		h.sendConnectionResponse(w, false, "Invalid request format", "")
+		h.sendConnectionResponse(w, r, false, "Invalid request format", "")
 		return
 	}
 
-	// Validate required fields
-	if connReq.Endpoint == "" || connReq.AccessKey == "" || connReq.SecretKey == "" || connReq.Region == "" {
-		h.sendConnectionResponse(w, false, "Missing required connection parameters", "")
-		return
-	}
+	// Validate required fields; profiles bring their own credentials and usually a region
+	switch {
+	case connReq.Anonymous:
+		if connReq.Endpoint == "" || connReq.Region == "" || len(connReq.Buckets) == 0 {
+			h.sendConnectionResponse(w, r, false, "Missing required connection parameters; anonymous connections need the buckets to browse", "")
+			return
+		}
 
-	h.logger.Info("Creating S3 connection",
-		slog.String("endpoint", connReq.Endpoint),
-		slog.String("region", connReq.Region),
-		slog.String("access_key", connReq.AccessKey[:4]+"..."))
+		h.logger.Info("Creating anonymous S3 connection",
+			slog.String("endpoint", connReq.Endpoint),
+			slog.String("region", connReq.Region),
+			slog.Int("buckets", len(connReq.Buckets)))
+	case connReq.Profile != "":
+		if connReq.Endpoint == "" {
+			h.sendConnectionResponse(w, r, false, "Missing required connection parameters", "")
+			return
+		}
+
+		h.logger.Info("Creating S3 connection from server profile",
+			slog.String("endpoint", connReq.Endpoint),
+			slog.String("profile", connReq.Profile))
+	default:
+		if connReq.Endpoint == "" || len(connReq.AccessKey) < 4 || connReq.SecretKey == "" || connReq.Region == "" {
+			h.sendConnectionResponse(w, r, false, "Missing required connection parameters", "")
+			return
+		}
+
+		h.logger.Info("Creating S3 connection",
+			slog.String("endpoint", connReq.Endpoint),
+			slog.String("region", connReq.Region),
+			slog.String("access_key", connReq.AccessKey[:4]+"..."))
+	}
 
 	// Create session with context
-	session, err := h.sessionManager.CreateSession(ctx, connReq)
+	owner := h.addingTo(r)
+	session, err := h.sessionManager.CreateSession(ctx, connReq, ownerID(owner))
 	if err != nil {
 		h.logger.Error("Failed to create session", slog.String("error", err.Error()))
-		h.sendConnectionResponse(w, false, "Failed to connect to S3: "+err.Error(), "")
+		h.sendConnectFailure(w, r, err)
 		return
 	}
 
-	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    session.ID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false, // Set to true for HTTPS
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(24 * time.Hour),
-	})
+	h.startSession(w, owner, session)
 
 	h.logger.Info("Connection successful", slog.String("session_id", session.ID))
-	h.sendConnectionResponse(w, true, "Connection successful", session.ID)
+	h.sendConnectionResponse(w, r, true, "Connection successful", session.ID)
+}
+
+// ListConnections lists the operator-defined connections
+// @Summary List connections
+// @Description Lists the connections configured by the operator and whether users may also connect
+// @Description with an endpoint and keys of their own
+// @Tags Session
+// @Produce json
+// @Success 200 {object} models.ConnectionListResponse
+// @Router /api/v1/connections [get]
+func (h *SessionHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ConnectionListResponse{
+		Connections:  h.sessionManager.Connections(identity.UserFromContext(r.Context())),
+		AdHocAllowed: h.sessionManager.AdHocAllowed(),
+	})
+}
+
+// ConnectNamed creates a session for an operator-defined connection
+// @Summary Connect by name
+// @Description Tests an operator-defined connection and creates a session for it. With add=true
+// @Description the connection is added to the current session and becomes active.
+// @Tags Session
+// @Produce json
+// @Param name path string true "Connection name"
+// @Param add query bool false "Add the connection to the current session"
+// @Success 200 {object} models.ConnectionResponse
+// @Failure 400,404 {object} models.ErrorResponse
+// @Failure 403,429,503 {object} models.ErrorResponse "Failed connection test"
+// @Router /api/v1/connections/{name}/connect [post]
+func (h *SessionHandler) ConnectNamed(w http.ResponseWriter, r *http.Request) {
+	name := pathParam(r, "name", extractConnectionNameFromPath)
+	if name == "" {
+		h.sendConnectionError(w, r, http.StatusNotFound, "Connection name is required")
+		return
+	}
+
+	h.logger.Info("Creating S3 connection from configured connection", slog.String("connection", name))
+
+	owner := h.addingTo(r)
+	sess, err := h.sessionManager.ConnectNamed(r.Context(), name, ownerID(owner))
+	if err != nil {
+		if errors.Is(err, session.ErrUnknownConnection) {
+			h.sendConnectionError(w, r, http.StatusNotFound, "Unknown connection: "+name)
+			return
+		}
+		h.logger.Error("Failed to create session",
+			slog.String("connection", name),
+			slog.String("error", err.Error()))
+		h.sendConnectFailure(w, r, err)
+		return
+	}
+
+	h.startSession(w, owner, sess)
+
+	h.logger.Info("Connection successful",
+		slog.String("session_id", sess.ID),
+		slog.String("connection", name))
+	h.sendConnectionResponse(w, r, true, "Connection successful", sess.ID)
+}
+
+// ListProfiles lists the server-side AWS profiles users may connect with
+// @Summary List server profiles
+// @Description Lists the server-side AWS profiles the administrator allows users to connect with.
+// @Description "default" stands for the SDK default credential chain.
+// @Tags Session
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of items (default and max 1000)"
+// @Success 200 {object} models.Page[models.Profile]
+// @Router /api/v1/profiles [get]
+func (h *SessionHandler) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	sendList(w, r, h.sessionManager.Profiles(r.Context()))
 }
 
 // Logout destroys the current session
 // @Summary Logout
 // @Description Destroys the current session and clears cookies
 // @Tags Session
-// @Success 200 {object} map[string]string
-// @Router /api/logout [post]
+// @Success 204 "No Content"
+// @Router /api/v1/session [delete]
 func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	session := h.sessionManager.GetSessionFromCookie(r)
 	if session != nil {
@@ -119,14 +254,172 @@ func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		Expires:  time.Unix(0, 0),
 	})
 
+	if middleware.IsAPIv1(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Logged out successfully",
 	})
 }
 
+// ActivateConnection switches the connection requests use by default
+// @Summary Switch connection
+// @Description Makes one of the connections listed by GET /api/v1/session the active one
+// @Tags Session
+// @Param id path string true "Connection ID"
+// @Success 204 "No Content"
+// @Failure 401,404 {object} models.ErrorResponse
+// @Router /api/v1/session/connections/{id}/activate [post]
+func (h *SessionHandler) ActivateConnection(w http.ResponseWriter, r *http.Request) {
+	owner := h.sessionManager.GetSessionFromCookie(r)
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.sessionManager.Activate(owner, pathParam(r, "id", extractSessionConnectionFromPath)); err != nil {
+		if errors.Is(err, session.ErrUnknownSessionConnection) {
+			http.Error(w, "Unknown connection", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to switch connection", slog.String("error", err.Error()))
+		http.Error(w, "Failed to switch connection", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisconnectConnection removes a connection from the current session
+// @Summary Remove connection
+// @Description Removes a connection that was added to the current session. The first connection
+// @Description of a session is removed by logging out.
+// @Tags Session
+// @Param id path string true "Connection ID"
+// @Success 204 "No Content"
+// @Failure 401,404 {object} models.ErrorResponse
+// @Router /api/v1/session/connections/{id} [delete]
+func (h *SessionHandler) DisconnectConnection(w http.ResponseWriter, r *http.Request) {
+	owner := h.sessionManager.GetSessionFromCookie(r)
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.sessionManager.Disconnect(owner, pathParam(r, "id", extractSessionConnectionFromPath)); err != nil {
+		if errors.Is(err, session.ErrUnknownSessionConnection) {
+			http.Error(w, "Unknown connection", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to remove connection", slog.String("error", err.Error()))
+		http.Error(w, "Failed to remove connection", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addingTo returns the browser session a new connection is added to when
+// the request asks for it with add=true
+func (h *SessionHandler) addingTo(r *http.Request) *models.Session {
+	if r.URL.Query().Get("add") != "true" {
+		return nil
+	}
+	return h.sessionManager.GetSessionFromCookie(r)
+}
+
+// startSession makes a new connection usable: an added connection becomes
+// the active one, anything else starts a new browser session
+func (h *SessionHandler) startSession(w http.ResponseWriter, owner, sess *models.Session) {
+	if owner == nil {
+		h.setSessionCookie(w, sess.ID)
+		return
+	}
+	if err := h.sessionManager.Activate(owner, sess.ID); err != nil {
+		h.logger.Warn("Failed to switch to the new connection",
+			slog.String("session_id", owner.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// ownerID returns the ID of the browser session a connection is added to, if any
+func ownerID(owner *models.Session) string {
+	if owner == nil {
+		return ""
+	}
+	return owner.ID
+}
+
+// setSessionCookie hands the session ID to the browser
+func (h *SessionHandler) setSessionCookie(w http.ResponseWriter, sessionID string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false, // Set to true for HTTPS
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now().Add(24 * time.Hour),
+	})
+}
+
+// extractConnectionNameFromPath extracts the connection name from URL path like "/api/connections/{name}/connect"
+func extractConnectionNameFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 4 && parts[1] == "connections" && parts[3] == "connect" {
+		return parts[2]
+	}
+	return ""
+}
+
+// extractSessionConnectionFromPath extracts the connection ID from URL paths like
+// "/api/session/connections/{id}" and "/api/session/connections/{id}/activate"
+func extractSessionConnectionFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 4 && parts[1] == "session" && parts[2] == "connections" {
+		return parts[3]
+	}
+	return ""
+}
+
+// sendConnectionError sends a failed connection response with a specific
+// status code; on /api/v1 it is an error like any other
+func (h *SessionHandler) sendConnectionError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if middleware.IsAPIv1(r) {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ConnectionResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// sendConnectFailure reports a connection that could not be made. Failed S3
+// and STS calls get the status and message chosen by s3errors.Translate;
+// anything else, such as parameters that do not go together, is a bad request.
+func (h *SessionHandler) sendConnectFailure(w http.ResponseWriter, r *http.Request, err error) {
+	var opErr *smithy.OperationError
+	if !errors.As(err, &opErr) {
+		h.sendConnectionResponse(w, r, false, "Failed to connect to S3: "+err.Error(), "")
+		return
+	}
+	if middleware.IsAPIv1(r) {
+		sendS3Error(w, err)
+		return
+	}
+	status, body := s3errors.Translate(err)
+	h.sendConnectionError(w, r, status, "Failed to connect to S3: "+body.Message)
+}
+
 // Helper to send connection response
-func (h *SessionHandler) sendConnectionResponse(w http.ResponseWriter, success bool, message string, sessionID string) {
+func (h *SessionHandler) sendConnectionResponse(w http.ResponseWriter, r *http.Request, success bool, message string, sessionID string) {
+	if !success && middleware.IsAPIv1(r) {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 
 	if !success {
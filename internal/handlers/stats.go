@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/index"
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/stats"
+)
+
+// StatsHandler handles bucket statistics and storage usage queries
+type StatsHandler struct {
+	cache   *stats.Cache
+	indexer *index.Indexer
+	logger  *slog.Logger
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(cache *stats.Cache, indexer *index.Indexer, logger *slog.Logger) *StatsHandler {
+	return &StatsHandler{
+		cache:   cache,
+		indexer: indexer,
+		logger:  logger,
+	}
+}
+
+// GetBucketStats returns object count, total size and breakdowns for a bucket
+// @Summary Bucket statistics
+// @Description Walks a bucket (or a prefix) and returns object count, total size and breakdowns by
+// @Description storage class, top-level prefix, file extension and age. Results are cached per bucket
+// @Description and prefix; pass refresh=true to recompute. Closing the connection cancels the walk.
+// @Description Indexed buckets are answered from the local index unless source=live.
+// @Tags Buckets
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string false "Only count objects under this prefix"
+// @Param refresh query bool false "Ignore cached results"
+// @Param source query string false "auto (default), index or live"
+// @Success 200 {object} models.BucketStats
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/stats [get]
+func (h *StatsHandler) GetBucketStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := middleware.GetSessionFromContext(ctx)
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	bucket := extractBucketNameFromSubresourcePath(r.URL.Path)
+	if bucket == "" {
+		http.Error(w, "Bucket name is required", http.StatusBadRequest)
+		return
+	}
+	prefix, err := queryPrefix(r, session)
+	if err != nil {
+		sendS3Error(w, err)
+		return
+	}
+
+	walk, indexStatus, err := objectWalker(h.indexer, session, bucket, r.URL.Query().Get("source"))
+	if err != nil {
+		sendWalkerError(w, err)
+		return
+	}
+
+	// Answers from the index are cheap, so only live walks are cached
+	if indexStatus == nil && r.URL.Query().Get("refresh") != "true" {
+		if cached, ok := h.cache.Get(session.ID, bucket, prefix); ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(cached)
+			return
+		}
+	}
+
+	// Walking a large bucket can take longer than the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	start := time.Now()
+	result, err := stats.Compute(ctx, walk, bucket, prefix)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			h.logger.Info("Bucket stats walk cancelled", slog.String("bucket", bucket))
+			return
+		}
+		h.logger.Error("Failed to compute bucket stats",
+			slog.String("bucket", bucket),
+			slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+
+	if indexStatus != nil {
+		result.Source = sourceIndex
+		result.IndexedAt = indexStatus.RefreshedAt
+	} else {
+		result.Source = sourceLive
+		h.cache.Put(session.ID, result)
+	}
+
+	h.logger.Info("Bucket stats computed",
+		slog.String("bucket", bucket),
+		slog.String("prefix", prefix),
+		slog.Int64("objects", result.ObjectCount),
+		slog.Duration("duration", time.Since(start)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetDiskUsage returns a size tree for a bucket prefix
+// @Summary Disk usage tree
+// @Description Walks a bucket prefix once and returns a tree of sub-prefixes down to the requested depth.
+// @Description Each node carries its total size, object count and its largest objects.
+// @Tags Buckets
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string false "Root prefix of the tree"
+// @Param depth query int false "Number of prefix levels to expand (default 2, max 10)"
+// @Param top query int false "Number of largest objects listed per node (default 10, max 100)"
+// @Param source query string false "auto (default), index or live"
+// @Success 200 {object} models.DiskUsageNode
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/buckets/{bucket}/du [get]
+func (h *StatsHandler) GetDiskUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	session := middleware.GetSessionFromContext(ctx)
+	if session == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	bucket := extractBucketNameFromSubresourcePath(r.URL.Path)
+	if bucket == "" {
+		http.Error(w, "Bucket name is required", http.StatusBadRequest)
+		return
+	}
+	prefix, err := queryPrefix(r, session)
+	if err != nil {
+		sendS3Error(w, err)
+		return
+	}
+
+	depth, err := queryInt(r, "depth", 2, 0, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	top, err := queryInt(r, "top", 10, 0, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	walk, _, err := objectWalker(h.indexer, session, bucket, r.URL.Query().Get("source"))
+	if err != nil {
+		sendWalkerError(w, err)
+		return
+	}
+
+	// Walking a large bucket can take longer than the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	tree, err := stats.DiskUsage(ctx, walk, prefix, depth, top, timeLayout(r))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			h.logger.Info("Disk usage walk cancelled", slog.String("bucket", bucket))
+			return
+		}
+		h.logger.Error("Failed to compute disk usage",
+			slog.String("bucket", bucket),
+			slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// queryInt parses an optional integer query parameter and checks it lies within [minValue, maxValue]
+func queryInt(r *http.Request, name string, defaultValue, minValue, maxValue int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < minValue || value > maxValue {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, minValue, maxValue)
+	}
+	return value, nil
+}
+
+// queryPrefix returns the prefix query parameter, defaulting to the prefix a
+// scoped session is limited to. Prefixes outside it are rejected.
+func queryPrefix(r *http.Request, session *models.Session) (string, error) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		return session.Prefix, nil
+	}
+	if !strings.HasPrefix(prefix, session.Prefix) {
+		return "", errOutOfScope
+	}
+	return prefix, nil
+}
+
+// extractBucketNameFromSubresourcePath extracts bucket name from URL path like "/api/buckets/{name}/{subresource}"
+func extractBucketNameFromSubresourcePath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 4 && parts[len(parts)-3] == "buckets" {
+		return parts[len(parts)-2]
+	}
+	return ""
+}
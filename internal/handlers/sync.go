@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+	"github.com/cksidharthan/s3-browser/internal/session"
+	"github.com/cksidharthan/s3-browser/internal/syncer"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// SyncHandler handles planning and executing syncs between two locations
+type SyncHandler struct {
+	sessionManager   *session.Manager
+	operationManager *operations.Manager
+	plans            *syncer.Plans
+	logger           *slog.Logger
+}
+
+// NewSyncHandler creates a new sync handler
+func NewSyncHandler(sessionManager *session.Manager, operationManager *operations.Manager, logger *slog.Logger) *SyncHandler {
+	return &SyncHandler{
+		sessionManager:   sessionManager,
+		operationManager: operationManager,
+		plans:            syncer.NewPlans(),
+		logger:           logger,
+	}
+}
+
+// PlanSync compares two locations and returns what a sync would do
+// @Summary Plan sync
+// @Description Compares a source and a destination location, on the same or different connections of the
+// @Description browser session, by key, size and ETag or last-modified time. Returns a dry-run plan of the
+// @Description objects that would be created, updated and, with delete, removed from the destination.
+// @Description Nothing is changed until the plan is executed; plans expire after an hour.
+// @Tags Sync
+// @Accept json
+// @Produce json
+// @Param request body models.SyncRequest true "Source, destination, filters and comparison"
+// @Success 201 {object} models.SyncPlan
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 404 {object} models.ErrorResponse "Unknown connection"
+// @Failure 403,404,409,429,503 {object} models.ErrorResponse "Failed S3 request"
+// @Router /api/v1/syncs [post]
+func (h *SyncHandler) PlanSync(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.Source.Bucket == "" || req.Destination.Bucket == "" {
+		http.Error(w, "Source and destination buckets are required", http.StatusBadRequest)
+		return
+	}
+	if req.Compare == "" {
+		req.Compare = syncer.CompareETag
+	}
+	if !syncer.ValidCompare(req.Compare) {
+		http.Error(w, "compare must be etag or modified", http.StatusBadRequest)
+		return
+	}
+	filter, err := syncer.NewFilter(req.Include, req.Exclude)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, err := h.sessionManager.Resolve(owner, req.Source.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	destination, err := h.sessionManager.Resolve(owner, req.Destination.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	if source.ID == destination.ID && req.Source.Bucket == req.Destination.Bucket &&
+		(strings.HasPrefix(req.Source.Prefix, req.Destination.Prefix) || strings.HasPrefix(req.Destination.Prefix, req.Source.Prefix)) {
+		http.Error(w, "The destination overlaps the source", http.StatusBadRequest)
+		return
+	}
+
+	items, summary, err := syncer.Plan(r.Context(),
+		syncer.S3(source.S3Client, req.Source.Bucket, req.Source.Prefix),
+		syncer.S3(destination.S3Client, req.Destination.Bucket, req.Destination.Prefix),
+		syncer.Options{Filter: filter, Compare: req.Compare, Delete: req.Delete})
+	if err != nil {
+		h.logger.Error("Failed to plan sync", slog.String("error", err.Error()))
+		sendS3Error(w, err)
+		return
+	}
+
+	// Pin the plan to the connections it was made for, whichever is active when it runs
+	req.Source.Connection = source.ID
+	req.Destination.Connection = destination.ID
+	plan := &models.SyncPlan{
+		Source:      req.Source,
+		Destination: req.Destination,
+		Compare:     req.Compare,
+		Delete:      req.Delete,
+		Items:       items,
+		Summary:     summary,
+	}
+	h.plans.Add(owner.ID, plan)
+
+	h.logger.Info("Sync planned",
+		slog.String("plan_id", plan.ID),
+		slog.Int64("creates", summary.Creates),
+		slog.Int64("updates", summary.Updates),
+		slog.Int64("deletes", summary.Deletes))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", apiBase(r)+"/syncs/"+plan.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GetSync returns a sync plan
+// @Summary Get sync plan
+// @Description Returns a sync plan that has not been executed, discarded or expired yet
+// @Tags Sync
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Success 200 {object} models.SyncPlan
+// @Failure 404 {object} models.ErrorResponse "Plan not found"
+// @Router /api/v1/syncs/{id} [get]
+func (h *SyncHandler) GetSync(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	plan := h.plans.Get(owner.ID, h.extractPlanIDFromPath(r.URL.Path))
+	if plan == nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// ExecuteSync carries out an approved sync plan
+// @Summary Execute sync plan
+// @Description Carries out a sync plan as a background operation: creates and updates are copied like a
+// @Description transfer, then extraneous objects are deleted. A plan can be executed once; resume the
+// @Description operation if it fails.
+// @Tags Sync
+// @Produce json
+// @Param id path string true "Plan ID"
+// @Success 202 {object} models.Operation
+// @Failure 403 {object} models.ErrorResponse "Destination is read-only"
+// @Failure 404 {object} models.ErrorResponse "Plan not found"
+// @Router /api/v1/syncs/{id}/execute [post]
+func (h *SyncHandler) ExecuteSync(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	id := h.extractPlanIDFromPath(strings.TrimSuffix(r.URL.Path, "/execute"))
+	plan := h.plans.Get(owner.ID, id)
+	if plan == nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	source, err := h.sessionManager.Resolve(owner, plan.Source.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	destination, err := h.sessionManager.Resolve(owner, plan.Destination.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	if h.sessionManager.IsReadOnly(destination) {
+		http.Error(w, "The destination connection is read-only", http.StatusForbidden)
+		return
+	}
+
+	// Removing the plan claims it, so concurrent requests cannot run it twice
+	if !h.plans.Remove(owner.ID, id) {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	fn := syncer.Execute(plan.Items,
+		transfer.Location{Client: source.S3Client, Bucket: plan.Source.Bucket, Prefix: plan.Source.Prefix},
+		transfer.Location{Client: destination.S3Client, Bucket: plan.Destination.Bucket, Prefix: plan.Destination.Prefix},
+		h.logger)
+	target := plan.Source.Bucket + "/" + plan.Source.Prefix + " -> " + plan.Destination.Bucket + "/" + plan.Destination.Prefix
+	op := h.operationManager.Start(owner.ID, "sync", target, fn)
+
+	h.logger.Info("Sync started",
+		slog.String("plan_id", id),
+		slog.String("operation_id", op.ID()))
+	sendOperationAccepted(w, r, op)
+}
+
+// DiscardSync drops a sync plan without executing it
+// @Summary Discard sync plan
+// @Description Drops a sync plan without executing it
+// @Tags Sync
+// @Param id path string true "Plan ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} models.ErrorResponse "Plan not found"
+// @Router /api/v1/syncs/{id} [delete]
+func (h *SyncHandler) DiscardSync(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	if !h.plans.Remove(owner.ID, h.extractPlanIDFromPath(r.URL.Path)) {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// extractPlanIDFromPath extracts plan ID from URL path like "/api/syncs/{id}"
+func (h *SyncHandler) extractPlanIDFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[len(parts)-2] == "syncs" {
+		return parts[len(parts)-1]
+	}
+	return ""
+}
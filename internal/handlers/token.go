@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/session"
+)
+
+// TokenHandler handles personal API tokens
+type TokenHandler struct {
+	sessionManager *session.Manager
+	logger         *slog.Logger
+}
+
+// NewTokenHandler creates a new token handler
+func NewTokenHandler(sessionManager *session.Manager, logger *slog.Logger) *TokenHandler {
+	return &TokenHandler{
+		sessionManager: sessionManager,
+		logger:         logger,
+	}
+}
+
+// CreateToken issues a personal API token
+// @Summary Create API token
+// @Description Issues a personal API token for one of the connections of the browser session, for
+// @Description scripts and CLIs. Send it as "Authorization: Bearer <token>" instead of the session
+// @Description cookie. The token keeps working after the browser session ends, until it expires or is
+// @Description revoked. read_only and buckets narrow what it may do; it never gets more than the
+// @Description connection. The token is only returned here; the server keeps a hash of it.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param request body models.APITokenRequest true "Name, connection, scope and expiry"
+// @Success 201 {object} models.CreatedAPIToken
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403 {object} models.ErrorResponse "Tokens cannot create tokens"
+// @Failure 404 {object} models.ErrorResponse "Unknown connection"
+// @Router /api/v1/tokens [post]
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+	if owner.Token != nil {
+		http.Error(w, "API tokens cannot create other tokens; sign in to the browser session", http.StatusForbidden)
+		return
+	}
+
+	var req models.APITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	token, secret, err := h.sessionManager.CreateToken(r.Context(), owner, req)
+	if err != nil {
+		if errors.Is(err, session.ErrUnknownSessionConnection) {
+			http.Error(w, "Unknown connection", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to create API token", slog.String("error", err.Error()))
+		http.Error(w, "Failed to create API token: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Location", apiBase(r)+"/tokens/"+token.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreatedAPIToken{APIToken: toAPIToken(token), Token: secret})
+}
+
+// ListTokens lists the personal API tokens of the browser session or its user
+// @Summary List API tokens
+// @Description Lists the unexpired API tokens created in the browser session or, when users sign in,
+// @Description by the signed-in user. The tokens themselves are not shown.
+// @Tags Tokens
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of items (default and max 1000)"
+// @Success 200 {object} models.Page[models.APIToken]
+// @Failure 403 {object} models.ErrorResponse "Tokens cannot manage tokens"
+// @Router /api/v1/tokens [get]
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+	if owner.Token != nil {
+		http.Error(w, "API tokens cannot list tokens; sign in to the browser session", http.StatusForbidden)
+		return
+	}
+
+	sessions, err := h.sessionManager.Tokens(owner)
+	if err != nil {
+		h.logger.Error("Failed to list API tokens", slog.String("error", err.Error()))
+		http.Error(w, "Failed to list API tokens", http.StatusInternalServerError)
+		return
+	}
+	tokens := make([]models.APIToken, 0, len(sessions))
+	for _, token := range sessions {
+		tokens = append(tokens, toAPIToken(token))
+	}
+	sendList(w, r, tokens)
+}
+
+// RevokeToken revokes a personal API token
+// @Summary Revoke API token
+// @Description Revokes one of the tokens listed by GET /api/v1/tokens; it stops working immediately
+// @Tags Tokens
+// @Param id path string true "Token ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse "Tokens cannot manage tokens"
+// @Failure 404 {object} models.ErrorResponse "Unknown token"
+// @Router /api/v1/tokens/{id} [delete]
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+	if owner.Token != nil {
+		http.Error(w, "API tokens cannot revoke tokens; sign in to the browser session", http.StatusForbidden)
+		return
+	}
+
+	if err := h.sessionManager.RevokeToken(owner, r.PathValue("id")); err != nil {
+		if errors.Is(err, session.ErrUnknownToken) {
+			http.Error(w, "Unknown API token", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to revoke API token", slog.String("error", err.Error()))
+		http.Error(w, "Failed to revoke API token", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// toAPIToken describes the session of a token without its secret
+func toAPIToken(token *models.Session) models.APIToken {
+	return models.APIToken{
+		ID:         token.ID,
+		Name:       token.Token.Name,
+		Endpoint:   token.Endpoint,
+		Connection: token.Connection,
+		ReadOnly:   token.ReadOnly,
+		Buckets:    token.Buckets,
+		CreatedAt:  token.CreatedAt.UTC(),
+		LastUsed:   token.LastUsed.UTC(),
+		ExpiresAt:  token.Token.ExpiresAt.UTC(),
+	}
+}
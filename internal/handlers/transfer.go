@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+	"github.com/cksidharthan/s3-browser/internal/session"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// TransferHandler handles copying objects between connections
+type TransferHandler struct {
+	sessionManager   *session.Manager
+	operationManager *operations.Manager
+	logger           *slog.Logger
+}
+
+// NewTransferHandler creates a new transfer handler
+func NewTransferHandler(sessionManager *session.Manager, operationManager *operations.Manager, logger *slog.Logger) *TransferHandler {
+	return &TransferHandler{
+		sessionManager:   sessionManager,
+		operationManager: operationManager,
+		logger:           logger,
+	}
+}
+
+// StartTransfer starts copying objects from one location to another
+// @Summary Transfer objects
+// @Description Copies a list of keys, or everything under a prefix, from a bucket on one connection
+// @Description of the browser session to a bucket on the same or another connection. Content type,
+// @Description metadata and tags are preserved, large objects are copied in parts and every copy is
+// @Description verified by size and, where possible, checksum. Runs as a background operation that
+// @Description can be cancelled and resumed.
+// @Tags Transfers
+// @Accept json
+// @Produce json
+// @Param request body models.TransferRequest true "Source, destination and keys"
+// @Success 202 {object} models.Operation
+// @Failure 400 {object} models.ErrorResponse "Bad Request"
+// @Failure 403 {object} models.ErrorResponse "Destination is read-only"
+// @Failure 404 {object} models.ErrorResponse "Unknown connection"
+// @Router /api/v1/transfers [post]
+func (h *TransferHandler) StartTransfer(w http.ResponseWriter, r *http.Request) {
+	owner := middleware.GetOwnerFromContext(r.Context())
+	if owner == nil {
+		http.Error(w, "No valid session", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.Source.Bucket == "" || req.Destination.Bucket == "" {
+		http.Error(w, "Source and destination buckets are required", http.StatusBadRequest)
+		return
+	}
+
+	source, err := h.sessionManager.Resolve(owner, req.Source.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	destination, err := h.sessionManager.Resolve(owner, req.Destination.Connection)
+	if err != nil {
+		sendResolveError(w, err)
+		return
+	}
+	if h.sessionManager.IsReadOnly(destination) {
+		http.Error(w, "The destination connection is read-only", http.StatusForbidden)
+		return
+	}
+
+	if source.ID == destination.ID && req.Source.Bucket == req.Destination.Bucket {
+		// Copying a prefix into itself would keep finding its own copies
+		overlaps := req.Destination.Prefix == req.Source.Prefix ||
+			(len(req.Keys) == 0 && strings.HasPrefix(req.Destination.Prefix, req.Source.Prefix))
+		if overlaps {
+			http.Error(w, "The destination overlaps the source", http.StatusBadRequest)
+			return
+		}
+	}
+
+	t := transfer.New(
+		transfer.Location{Client: source.S3Client, Bucket: req.Source.Bucket, Prefix: req.Source.Prefix},
+		transfer.Location{Client: destination.S3Client, Bucket: req.Destination.Bucket, Prefix: req.Destination.Prefix},
+		req.Keys, req.SkipExisting, h.logger)
+	target := req.Source.Bucket + "/" + req.Source.Prefix + " -> " + req.Destination.Bucket + "/" + req.Destination.Prefix
+	op := h.operationManager.Start(owner.ID, "transfer", target, t.Run)
+
+	h.logger.Info("Transfer started",
+		slog.String("operation_id", op.ID()),
+		slog.String("source_connection", source.ID),
+		slog.String("destination_connection", destination.ID),
+		slog.String("target", target),
+		slog.Int("keys", len(req.Keys)))
+	sendOperationAccepted(w, r, op)
+}
+
+// sendResolveError reports a connection that could not be resolved
+func sendResolveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, session.ErrUnknownSessionConnection) {
+		http.Error(w, "Unknown connection", http.StatusNotFound)
+		return
+	}
+	sendS3Error(w, err)
+}
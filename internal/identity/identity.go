@@ -0,0 +1,189 @@
+// Package identity keeps track of the users signed in to S3 Browser itself.
+// Sign-in methods such as OIDC authenticate a user and start an app session
+// for them here; the S3 sessions a user opens afterwards are bound to it.
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// cookieName is the cookie that carries the app session, apart from the
+// session_id cookie of the S3 session
+const cookieName = "app_session"
+
+// contextKey is used to store the user in request contexts
+type contextKey struct{}
+
+// WithUser returns a copy of ctx that carries user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the signed-in user of a request, or nil when
+// nobody has to sign in
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(contextKey{}).(*models.User)
+	return user
+}
+
+// MayUse reports whether user may use the operator-defined connection called
+// name; without a user, i.e. without sign-in, everyone may
+func MayUse(user *models.User, name string) bool {
+	return user == nil || slices.Contains(user.Connections, name)
+}
+
+// LocalRedirect keeps the redirect after sign-in on this server
+func LocalRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+// ErrAppSessionNotFound is returned by a Store when no app session has the requested ID
+var ErrAppSessionNotFound = errors.New("app session not found")
+
+// Store persists app sessions by the ID in their cookie. The session stores
+// implement it, so sign-ins are kept wherever S3 sessions are: they survive
+// restarts and are shared between instances unless sessions are in memory.
+type Store interface {
+	// GetAppSession returns the app session with the given ID or ErrAppSessionNotFound
+	GetAppSession(id string) (*models.AppSession, error)
+	// PutAppSession creates or replaces an app session
+	PutAppSession(id string, session *models.AppSession) error
+	// DeleteAppSession ends an app session; ending a missing one is not an error
+	DeleteAppSession(id string) error
+	// ListAppSessions returns every app session by ID
+	ListAppSessions() (map[string]*models.AppSession, error)
+}
+
+// Sessions holds the app sessions of signed-in users; they end after a
+// fixed lifetime or when the user signs out
+type Sessions struct {
+	ttl    time.Duration
+	store  Store
+	logger *slog.Logger
+}
+
+// NewSessions creates app sessions that each last ttl, kept in store
+func NewSessions(ttl time.Duration, store Store, logger *slog.Logger) *Sessions {
+	return &Sessions{
+		ttl:    ttl,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Start signs the user of session in on the browser of r and returns them
+// with the expiry of the app session
+func (s *Sessions) Start(w http.ResponseWriter, r *http.Request, session models.AppSession) (models.User, error) {
+	id := rand.Text()
+	session.User.ExpiresAt = time.Now().Add(s.ttl)
+
+	s.prune()
+	if err := s.store.PutAppSession(id, &session); err != nil {
+		return session.User, fmt.Errorf("failed to store app session: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax, so that the cookie comes along when the identity provider redirects back
+		SameSite: http.SameSiteLaxMode,
+		Expires:  session.User.ExpiresAt,
+	})
+	return session.User, nil
+}
+
+// prune ends the app sessions that have expired; stores that expire keys on
+// their own will not have any
+func (s *Sessions) prune() {
+	sessions, err := s.store.ListAppSessions()
+	if err != nil {
+		s.logger.Warn("Failed to list app sessions", slog.String("error", err.Error()))
+		return
+	}
+	now := time.Now()
+	for id, session := range sessions {
+		if now.After(session.User.ExpiresAt) {
+			s.delete(id)
+		}
+	}
+}
+
+// delete ends the app session with the given ID, logging failures
+func (s *Sessions) delete(id string) {
+	if err := s.store.DeleteAppSession(id); err != nil {
+		s.logger.Warn("Failed to delete app session", slog.String("error", err.Error()))
+	}
+}
+
+// Get returns the user signed in on the browser of r, if any
+func (s *Sessions) Get(r *http.Request) *models.User {
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		return nil
+	}
+
+	session, err := s.store.GetAppSession(cookie.Value)
+	if err != nil {
+		if !errors.Is(err, ErrAppSessionNotFound) {
+			s.logger.Warn("Failed to load app session", slog.String("error", err.Error()))
+		}
+		return nil
+	}
+	if time.Now().After(session.User.ExpiresAt) {
+		s.delete(cookie.Value)
+		return nil
+	}
+	return &session.User
+}
+
+// Revise applies revise to every app session; users it rejects are signed out
+func (s *Sessions) Revise(revise func(session models.AppSession) (models.AppSession, bool)) error {
+	sessions, err := s.store.ListAppSessions()
+	if err != nil {
+		return fmt.Errorf("failed to list app sessions: %w", err)
+	}
+	for id, session := range sessions {
+		revised, ok := revise(*session)
+		if !ok {
+			err = s.store.DeleteAppSession(id)
+		} else {
+			err = s.store.PutAppSession(id, &revised)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update app session: %w", err)
+		}
+	}
+	return nil
+}
+
+// End signs out the user of the browser of r
+func (s *Sessions) End(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		s.delete(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
@@ -0,0 +1,263 @@
+package identity
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// maxFailures is how many sign-ins in a row may fail before an account is locked
+	maxFailures = 5
+	// lockoutPeriod is how long a locked account refuses every sign-in
+	lockoutPeriod = 15 * time.Minute
+)
+
+// localPrefix starts the user IDs of local users
+const localPrefix = "local:"
+
+// ErrInvalidCredentials is returned for unknown users and wrong passwords alike
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// LockoutError is returned while an account is locked after repeated failed sign-ins
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("too many failed sign-ins; try again after %s", e.Until.Format(time.RFC3339))
+}
+
+// dummyHash is compared against for unknown users, so that they take as
+// long to reject as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("s3-browser"), bcrypt.DefaultCost)
+
+// LocalUsers are accounts kept in an htpasswd-style file of bcrypt hashes,
+// one "name:hash:connections" line per user. connections is the
+// comma-separated allow-list of operator-defined connections, or * for all
+// of them; users without it may not use any.
+type LocalUsers struct {
+	path           string
+	allConnections []string
+	sessions       *Sessions
+	users          map[string]localUser
+	failures       map[string]*failures
+	mu             sync.Mutex
+	logger         *slog.Logger
+}
+
+// localUser is one line of the users file
+type localUser struct {
+	hash        []byte
+	connections []string
+}
+
+// failures tracks the sign-ins of a name that failed or are still being checked
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// LoadLocalUsers reads the users file at path. allConnections are the names
+// of every operator-defined connection, for users allowed all of them.
+// sessions are the app sessions the users sign in to.
+func LoadLocalUsers(path string, allConnections []string, sessions *Sessions, logger *slog.Logger) (*LocalUsers, error) {
+	users := &LocalUsers{
+		path:           path,
+		allConnections: allConnections,
+		sessions:       sessions,
+		failures:       make(map[string]*failures),
+		logger:         logger,
+	}
+	if err := users.Reload(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Len returns how many users the file declares
+func (u *LocalUsers) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.users)
+}
+
+// Reload reads the users file again. A file that cannot be read or parsed
+// leaves the current users in place. Signed-in users get their new
+// connections; those who were removed or got a new password are signed out.
+func (u *LocalUsers) Reload() error {
+	raw, err := os.ReadFile(u.path)
+	if err != nil {
+		return fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	users := make(map[string]localUser)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, ":", 3)
+		if len(fields) < 2 || fields[0] == "" {
+			return fmt.Errorf("users file line %d: expected name:hash[:connections]", line)
+		}
+		name, hash := fields[0], fields[1]
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("users file line %d: user %q needs a bcrypt hash (htpasswd -B)", line, name)
+		}
+		if _, ok := users[name]; ok {
+			return fmt.Errorf("users file line %d: user %q is declared twice", line, name)
+		}
+
+		user := localUser{hash: []byte(hash), connections: make([]string, 0)}
+		if len(fields) == 3 {
+			for _, conn := range strings.Split(fields[2], ",") {
+				switch conn = strings.TrimSpace(conn); conn {
+				case "":
+				case "*":
+					user.connections = append(user.connections, u.allConnections...)
+				default:
+					if !slices.Contains(u.allConnections, conn) {
+						u.logger.Warn("Users file allows a connection that is not defined",
+							slog.String("user", name),
+							slog.String("connection", conn))
+					}
+					user.connections = append(user.connections, conn)
+				}
+			}
+		}
+		users[name] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	u.mu.Lock()
+	u.users = users
+	u.mu.Unlock()
+
+	err = u.sessions.Revise(func(session models.AppSession) (models.AppSession, bool) {
+		name := strings.TrimPrefix(session.User.ID, localPrefix)
+		current, ok := users[name]
+		if !ok || session.Credential != fingerprint(current.hash) {
+			u.logger.Info("Signed out user removed or changed in the users file", slog.String("user", session.User.ID))
+			return session, false
+		}
+		session.User.Connections = current.connections
+		return session, true
+	})
+	if err != nil {
+		// The new users are in place; only signed-in users keep their old access
+		u.logger.Error("Failed to revise signed-in users", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// fingerprint identifies a password hash without revealing it
+func fingerprint(hash []byte) string {
+	sum := sha256.Sum256(hash)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Authenticate checks a name and password and returns the app session to
+// start for the user. After maxFailures failed attempts in a row the name is
+// locked for lockoutPeriod, whether or not such a user exists; failures are
+// forgotten after lockoutPeriod without any.
+func (u *LocalUsers) Authenticate(name, password string) (*models.AppSession, error) {
+	now := time.Now()
+	u.mu.Lock()
+	for key, other := range u.failures {
+		if now.Sub(other.last) > lockoutPeriod && now.After(other.lockedUntil) {
+			delete(u.failures, key)
+		}
+	}
+	record, ok := u.failures[name]
+	if !ok {
+		record = &failures{}
+		u.failures[name] = record
+	}
+	if now.Before(record.lockedUntil) {
+		u.mu.Unlock()
+		return nil, &LockoutError{Until: record.lockedUntil}
+	}
+	if record.count >= maxFailures {
+		// Attempts that are still being checked count until they succeed
+		u.mu.Unlock()
+		return nil, &LockoutError{Until: now.Add(lockoutPeriod)}
+	}
+	record.count++
+	record.last = now
+	user, exists := u.users[name]
+	u.mu.Unlock()
+
+	hash := user.hash
+	if !exists {
+		hash = dummyHash
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err != nil || !exists {
+		if record.count >= maxFailures {
+			record.count = 0
+			record.lockedUntil = time.Now().Add(lockoutPeriod)
+			u.logger.Warn("Account locked after repeated failed sign-ins",
+				slog.String("user", name),
+				slog.Time("until", record.lockedUntil))
+		}
+		return nil, ErrInvalidCredentials
+	}
+	delete(u.failures, name)
+	return &models.AppSession{
+		User: models.User{
+			ID:          localPrefix + name,
+			Name:        name,
+			Connections: user.connections,
+		},
+		Credential: fingerprint(user.hash),
+	}, nil
+}
+
+// StartReloadRoutine reloads the users file whenever the process receives SIGHUP
+func (u *LocalUsers) StartReloadRoutine(ctx context.Context) {
+	if u == nil {
+		return
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangup)
+		for {
+			select {
+			case <-hangup:
+				if err := u.Reload(); err != nil {
+					u.logger.Error("Failed to reload users; keeping the previous ones", slog.String("error", err.Error()))
+					continue
+				}
+				u.logger.Info("Users reloaded", slog.Int("users", u.Len()))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
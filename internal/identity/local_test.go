@@ -0,0 +1,272 @@
+package identity
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mapStore keeps app sessions in a map
+type mapStore struct {
+	mu       sync.Mutex
+	sessions map[string]models.AppSession
+}
+
+func (m *mapStore) GetAppSession(id string) (*models.AppSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrAppSessionNotFound
+	}
+	return &session, nil
+}
+
+func (m *mapStore) PutAppSession(id string, session *models.AppSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = *session
+	return nil
+}
+
+func (m *mapStore) DeleteAppSession(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *mapStore) ListAppSessions() (map[string]*models.AppSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make(map[string]*models.AppSession, len(m.sessions))
+	for id, session := range m.sessions {
+		sessions[id] = &session
+	}
+	return sessions, nil
+}
+
+// hashPassword returns a bcrypt hash of password, cheap to check
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	return string(hash)
+}
+
+// writeUsers writes a users file and returns its path
+func writeUsers(t *testing.T, path, content string) string {
+	t.Helper()
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "users")
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestUsers loads the users in content, signing in to sessions kept in a map
+func newTestUsers(t *testing.T, content string) (*LocalUsers, *Sessions) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sessions := NewSessions(time.Hour, &mapStore{sessions: make(map[string]models.AppSession)}, logger)
+	users, err := LoadLocalUsers(writeUsers(t, "", content), []string{"dev", "prod"}, sessions, logger)
+	if err != nil {
+		t.Fatalf("LoadLocalUsers: %v", err)
+	}
+	return users, sessions
+}
+
+// signIn starts an app session for name and returns a request made with its cookie
+func signIn(t *testing.T, users *LocalUsers, sessions *Sessions, name, password string) *http.Request {
+	t.Helper()
+	session, err := users.Authenticate(name, password)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	if _, err := sessions.Start(recorder, httptest.NewRequest(http.MethodPost, "/auth/login", nil), *session); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	users, _ := newTestUsers(t, "# operators\nalice:"+hashPassword(t, "secret")+":dev\nbob:"+hashPassword(t, "hunter2")+":*\n")
+
+	session, err := users.Authenticate("alice", "secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if session.User.ID != "local:alice" || !slices.Equal(session.User.Connections, []string{"dev"}) || session.Credential == "" {
+		t.Errorf("Authenticate = %+v, want alice with the connection dev", session)
+	}
+	if session, err := users.Authenticate("bob", "hunter2"); err != nil || !slices.Equal(session.User.Connections, []string{"dev", "prod"}) {
+		t.Errorf("Authenticate of a user with * = %+v, %v, want every connection", session, err)
+	}
+
+	for name, password := range map[string]string{"alice": "wrong", "carol": "secret", "": ""} {
+		if _, err := users.Authenticate(name, password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%q, %q) = %v, want ErrInvalidCredentials", name, password, err)
+		}
+	}
+}
+
+func TestAuthenticateLockout(t *testing.T) {
+	users, _ := newTestUsers(t, "alice:"+hashPassword(t, "secret")+":dev\n")
+
+	for name, exists := range map[string]bool{"alice": true, "mallory": false} {
+		t.Run(name, func(t *testing.T) {
+			for range maxFailures {
+				if _, err := users.Authenticate(name, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Authenticate = %v, want ErrInvalidCredentials", err)
+				}
+			}
+
+			// Locked whether or not the user exists, even with the right password
+			var lockout *LockoutError
+			if _, err := users.Authenticate(name, "secret"); !errors.As(err, &lockout) {
+				t.Fatalf("Authenticate after %d failures = %v, want a LockoutError", maxFailures, err)
+			}
+			if until := time.Until(lockout.Until); until <= 0 || until > lockoutPeriod {
+				t.Errorf("locked for %v, want up to %v", until, lockoutPeriod)
+			}
+
+			// Once the lockout is over the name may try again
+			users.mu.Lock()
+			users.failures[name].lockedUntil = time.Now().Add(-time.Second)
+			users.mu.Unlock()
+			_, err := users.Authenticate(name, "secret")
+			if exists && err != nil {
+				t.Errorf("Authenticate after the lockout = %v", err)
+			}
+			if !exists && !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Authenticate of an unknown user after the lockout = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateCountsPendingAttempts(t *testing.T) {
+	users, _ := newTestUsers(t, "alice:"+hashPassword(t, "secret")+":dev\n")
+
+	// maxFailures attempts whose passwords are still being checked
+	users.mu.Lock()
+	users.failures["alice"] = &failures{count: maxFailures, last: time.Now()}
+	users.mu.Unlock()
+
+	var lockout *LockoutError
+	if _, err := users.Authenticate("alice", "secret"); !errors.As(err, &lockout) {
+		t.Errorf("Authenticate while %d attempts are pending = %v, want a LockoutError", maxFailures, err)
+	}
+}
+
+func TestAuthenticateForgetsFailures(t *testing.T) {
+	users, _ := newTestUsers(t, "alice:"+hashPassword(t, "secret")+":dev\n")
+
+	for range maxFailures - 1 {
+		users.Authenticate("alice", "wrong")
+	}
+	users.Authenticate("mallory", "wrong")
+
+	// A success forgets the failures of the name
+	if _, err := users.Authenticate("alice", "secret"); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	users.mu.Lock()
+	_, tracked := users.failures["alice"]
+	// Failures are forgotten after lockoutPeriod without any
+	users.failures["mallory"].last = time.Now().Add(-lockoutPeriod - time.Second)
+	users.mu.Unlock()
+	if tracked {
+		t.Error("failures were kept after a successful sign-in")
+	}
+
+	users.Authenticate("alice", "wrong")
+	users.mu.Lock()
+	defer users.mu.Unlock()
+	if _, tracked := users.failures["mallory"]; tracked {
+		t.Error("failures older than the lockout period were kept")
+	}
+	if users.failures["alice"] == nil || users.failures["alice"].count != 1 {
+		t.Errorf("failures of alice = %+v, want 1", users.failures["alice"])
+	}
+}
+
+func TestReload(t *testing.T) {
+	aliceHash, bobHash := hashPassword(t, "secret"), hashPassword(t, "hunter2")
+	users, sessions := newTestUsers(t, "alice:"+aliceHash+":dev\nbob:"+bobHash+":dev\ncarol:"+hashPassword(t, "pass")+":dev\n")
+	alice := signIn(t, users, sessions, "alice", "secret")
+	bob := signIn(t, users, sessions, "bob", "hunter2")
+	carol := signIn(t, users, sessions, "carol", "pass")
+
+	// alice gets another connection, bob a new password and carol is removed
+	writeUsers(t, users.path, "alice:"+aliceHash+":dev,prod\nbob:"+hashPassword(t, "changed")+":dev\n")
+	if err := users.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if users.Len() != 2 {
+		t.Errorf("Len after Reload = %d, want 2", users.Len())
+	}
+	if user := sessions.Get(alice); user == nil || !slices.Equal(user.Connections, []string{"dev", "prod"}) {
+		t.Errorf("alice after Reload = %+v, want her signed in with the new connections", user)
+	}
+	if user := sessions.Get(bob); user != nil {
+		t.Error("bob is still signed in after his password changed")
+	}
+	if user := sessions.Get(carol); user != nil {
+		t.Error("carol is still signed in after she was removed")
+	}
+	if _, err := users.Authenticate("bob", "changed"); err != nil {
+		t.Errorf("Authenticate with the new password = %v", err)
+	}
+
+	// A broken file leaves the users in place
+	for name, content := range map[string]string{
+		"no hash":    "alice\n",
+		"plain hash": "alice:secret:dev\n",
+		"duplicate":  "alice:" + aliceHash + "\nalice:" + aliceHash + "\n",
+	} {
+		writeUsers(t, users.path, content)
+		if err := users.Reload(); err == nil {
+			t.Errorf("Reload of a file with %s succeeded", name)
+		}
+	}
+	if users.Len() != 2 || sessions.Get(alice) == nil {
+		t.Error("a failed Reload changed the users")
+	}
+}
+
+func TestReloadOnHangup(t *testing.T) {
+	users, _ := newTestUsers(t, "alice:"+hashPassword(t, "secret")+":dev\n")
+	users.StartReloadRoutine(t.Context())
+
+	writeUsers(t, users.path, "alice:"+hashPassword(t, "secret")+":dev\nbob:"+hashPassword(t, "hunter2")+":dev\n")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); users.Len() != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the users file was not reloaded on SIGHUP")
+		}
+	}
+}
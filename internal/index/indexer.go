@@ -0,0 +1,563 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// metaBucket holds one models.IndexStatus record per index
+var metaBucket = []byte("indexes")
+
+// ErrNotIndexed is returned when a bucket has no completed index
+var ErrNotIndexed = errors.New("bucket is not indexed")
+
+// SessionChecker reports whether a session still exists, so scheduled
+// refreshes stop using the credentials of sessions that have ended
+type SessionChecker interface {
+	HasSession(sessionID string) bool
+}
+
+// entry is the stored form of a listed object
+type entry struct {
+	Size         int64     `json:"s"`
+	ETag         string    `json:"e,omitempty"`
+	StorageClass string    `json:"c,omitempty"`
+	LastModified time.Time `json:"m"`
+}
+
+// source is the session whose client keeps an index up to date
+type source struct {
+	sessionID string
+	client    *s3.Client
+}
+
+// Indexer snapshots bucket listings into a local bbolt database and keeps
+// them fresh. A nil *Indexer is valid and behaves as if indexing is disabled.
+type Indexer struct {
+	db         *bolt.DB
+	sessions   SessionChecker
+	interval   time.Duration
+	sources    map[string]source
+	refreshing map[string]bool
+	mu         sync.Mutex
+	logger     *slog.Logger
+}
+
+// Open opens (or creates) the index database in dataDir. Indexes are
+// refreshed every interval while a session that uses them is alive.
+func Open(dataDir string, interval time.Duration, sessions SessionChecker, logger *slog.Logger) (*Indexer, error) {
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dataDir, "index.db"), 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(metaBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize index database: %w", err)
+	}
+
+	return &Indexer{
+		db:         db,
+		sessions:   sessions,
+		interval:   interval,
+		sources:    make(map[string]source),
+		refreshing: make(map[string]bool),
+		logger:     logger,
+	}, nil
+}
+
+// Close closes the index database
+func (ix *Indexer) Close() error {
+	if ix == nil {
+		return nil
+	}
+	return ix.db.Close()
+}
+
+// Enabled reports whether indexing is available
+func (ix *Indexer) Enabled() bool {
+	return ix != nil
+}
+
+// ScopeID identifies the index of bucket as seen by one principal on one endpoint
+func ScopeID(endpoint, principal, bucket string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + principal + "\x00" + bucket))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Attach makes the session's client the source of scheduled refreshes for an
+// existing index. It does nothing if the bucket is not indexed.
+func (ix *Indexer) Attach(sessionID string, client *s3.Client, scopeID string) {
+	if ix == nil {
+		return
+	}
+	if _, err := ix.Status(scopeID); err != nil {
+		return
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.sources[scopeID] = source{sessionID: sessionID, client: client}
+}
+
+// Enable creates the index for a bucket if needed and attaches the session as its source
+func (ix *Indexer) Enable(sessionID string, client *s3.Client, scopeID, endpoint, bucket string) error {
+	err := ix.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta.Get([]byte(scopeID)) != nil {
+			return nil
+		}
+		if _, err := tx.CreateBucketIfNotExists(objectsBucket(scopeID)); err != nil {
+			return err
+		}
+		return putStatus(meta, &models.IndexStatus{
+			ID:       scopeID,
+			Endpoint: endpoint,
+			Bucket:   bucket,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	ix.mu.Lock()
+	ix.sources[scopeID] = source{sessionID: sessionID, client: client}
+	ix.mu.Unlock()
+	return nil
+}
+
+// Drop removes an index and everything stored in it
+func (ix *Indexer) Drop(scopeID string) error {
+	if ix == nil {
+		return nil
+	}
+
+	ix.mu.Lock()
+	delete(ix.sources, scopeID)
+	ix.mu.Unlock()
+
+	return ix.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(metaBucket).Delete([]byte(scopeID)); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(objectsBucket(scopeID)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Status returns the state of one index, including how stale it is
+func (ix *Indexer) Status(scopeID string) (*models.IndexStatus, error) {
+	if ix == nil {
+		return nil, ErrNotIndexed
+	}
+
+	var status *models.IndexStatus
+	err := ix.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(metaBucket).Get([]byte(scopeID))
+		if raw == nil {
+			return ErrNotIndexed
+		}
+		status = &models.IndexStatus{}
+		return json.Unmarshal(raw, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ix.decorate(status)
+	return status, nil
+}
+
+// List returns the status of every index whose ID is accepted by include
+func (ix *Indexer) List(include func(status *models.IndexStatus) bool) ([]*models.IndexStatus, error) {
+	statuses := make([]*models.IndexStatus, 0)
+	if ix == nil {
+		return statuses, nil
+	}
+
+	err := ix.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).ForEach(func(_, raw []byte) error {
+			status := &models.IndexStatus{}
+			if err := json.Unmarshal(raw, status); err != nil {
+				return err
+			}
+			if include(status) {
+				statuses = append(statuses, status)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, status := range statuses {
+		ix.decorate(status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Bucket < statuses[j].Bucket
+	})
+	return statuses, nil
+}
+
+// decorate fills in the fields derived from in-memory state and the clock
+func (ix *Indexer) decorate(status *models.IndexStatus) {
+	ix.mu.Lock()
+	status.Refreshing = ix.refreshing[status.ID]
+	ix.mu.Unlock()
+
+	if status.RefreshedAt == nil {
+		status.Stale = true
+		return
+	}
+	status.AgeSeconds = int64(time.Since(*status.RefreshedAt).Seconds())
+	status.Stale = status.LastError != "" || time.Since(*status.RefreshedAt) > 2*ix.interval
+}
+
+// Refresh brings the index in line with the bucket listing. Only entries
+// that were added, changed or removed since the last refresh are written.
+func (ix *Indexer) Refresh(ctx context.Context, client *s3.Client, scopeID string, progress func(scanned int64)) error {
+	ix.mu.Lock()
+	if ix.refreshing[scopeID] {
+		ix.mu.Unlock()
+		return fmt.Errorf("index is already being refreshed")
+	}
+	ix.refreshing[scopeID] = true
+	ix.mu.Unlock()
+
+	defer func() {
+		ix.mu.Lock()
+		delete(ix.refreshing, scopeID)
+		ix.mu.Unlock()
+	}()
+
+	status, err := ix.Status(scopeID)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	count, size, err := ix.sync(ctx, client, scopeID, status.Bucket, progress)
+
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		status.ObjectCount = count
+		status.TotalSize = size
+		status.RefreshedAt = &started
+	}
+	updateErr := ix.db.Update(func(tx *bolt.Tx) error {
+		// The index may have been dropped while the refresh was running
+		meta := tx.Bucket(metaBucket)
+		if meta.Get([]byte(scopeID)) == nil {
+			return nil
+		}
+		return putStatus(meta, status)
+	})
+	if err != nil {
+		return err
+	}
+	if updateErr != nil {
+		return fmt.Errorf("failed to record index status: %w", updateErr)
+	}
+
+	ix.logger.Info("Index refreshed",
+		slog.String("bucket", status.Bucket),
+		slog.Int64("objects", count),
+		slog.Duration("duration", time.Since(started)))
+	return nil
+}
+
+// sync merges the sorted bucket listing into the sorted stored keys, one
+// listing page per transaction
+func (ix *Indexer) sync(ctx context.Context, client *s3.Client, scopeID, bucket string, progress func(scanned int64)) (int64, int64, error) {
+	var count, size int64
+	var last []byte
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		err = ix.db.Update(func(tx *bolt.Tx) error {
+			objects := tx.Bucket(objectsBucket(scopeID))
+			if objects == nil {
+				return ErrNotIndexed
+			}
+			cursor := objects.Cursor()
+
+			for _, obj := range page.Contents {
+				if obj.Key == nil {
+					continue
+				}
+				key := []byte(aws.ToString(obj.Key))
+				value, err := json.Marshal(toEntry(obj))
+				if err != nil {
+					return err
+				}
+
+				// Remove stored keys that sort between the previous listed key and this one
+				if err := deleteRange(cursor, last, key); err != nil {
+					return err
+				}
+				if !bytes.Equal(objects.Get(key), value) {
+					if err := objects.Put(key, value); err != nil {
+						return err
+					}
+				}
+
+				last = key
+				count++
+				size += aws.ToInt64(obj.Size)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if progress != nil {
+			progress(count)
+		}
+	}
+
+	// Everything after the last listed key no longer exists
+	err := ix.db.Update(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(objectsBucket(scopeID))
+		if objects == nil {
+			return ErrNotIndexed
+		}
+		return deleteRange(objects.Cursor(), last, nil)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, size, nil
+}
+
+// deleteRange deletes the keys strictly between after and before; a nil
+// after starts at the first key and a nil before runs to the last one
+func deleteRange(cursor *bolt.Cursor, after, before []byte) error {
+	var k []byte
+	if after == nil {
+		k, _ = cursor.First()
+	} else {
+		k, _ = cursor.Seek(after)
+		if bytes.Equal(k, after) {
+			k, _ = cursor.Next()
+		}
+	}
+
+	for k != nil && (before == nil || bytes.Compare(k, before) < 0) {
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+		// Delete moves the cursor back, so re-seek past the removed key
+		k, _ = cursor.Seek(k)
+	}
+	return nil
+}
+
+// walkBatch is how many objects Walk reads per transaction
+const walkBatch = 1000
+
+// Walk calls fn for every indexed object under prefix, in key order. Objects
+// are read in batches and fn is called between transactions, so a slow
+// caller does not hold a read transaction open against writers.
+func (ix *Indexer) Walk(ctx context.Context, scopeID, prefix string, fn func(obj types.Object) error) error {
+	if ix == nil {
+		return ErrNotIndexed
+	}
+
+	var after []byte
+	batch := make([]types.Object, 0, walkBatch)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+		err := ix.db.View(func(tx *bolt.Tx) error {
+			objects := tx.Bucket(objectsBucket(scopeID))
+			if objects == nil {
+				return ErrNotIndexed
+			}
+
+			cursor := objects.Cursor()
+			var k, v []byte
+			if after == nil {
+				k, v = cursor.Seek([]byte(prefix))
+			} else if k, v = cursor.Seek(after); bytes.Equal(k, after) {
+				k, v = cursor.Next()
+			}
+			for ; k != nil && bytes.HasPrefix(k, []byte(prefix)) && len(batch) < walkBatch; k, v = cursor.Next() {
+				var e entry
+				if err := json.Unmarshal(v, &e); err != nil {
+					return fmt.Errorf("corrupt index entry %q: %w", k, err)
+				}
+				batch = append(batch, e.toObject(string(k)))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range batch {
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		if len(batch) < walkBatch {
+			return nil
+		}
+		after = []byte(aws.ToString(batch[len(batch)-1].Key))
+	}
+}
+
+// Put records an object written through s3-browser in an existing index
+func (ix *Indexer) Put(scopeID string, obj types.Object) {
+	if ix == nil || obj.Key == nil {
+		return
+	}
+
+	err := ix.db.Update(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(objectsBucket(scopeID))
+		if objects == nil {
+			return nil
+		}
+		value, err := json.Marshal(toEntry(obj))
+		if err != nil {
+			return err
+		}
+		return objects.Put([]byte(aws.ToString(obj.Key)), value)
+	})
+	if err != nil {
+		ix.logger.Warn("Failed to update index", slog.String("key", aws.ToString(obj.Key)), slog.String("error", err.Error()))
+	}
+}
+
+// Delete removes an object deleted through s3-browser from an existing index
+func (ix *Indexer) Delete(scopeID, key string) {
+	if ix == nil {
+		return
+	}
+
+	err := ix.db.Update(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(objectsBucket(scopeID))
+		if objects == nil {
+			return nil
+		}
+		return objects.Delete([]byte(key))
+	})
+	if err != nil {
+		ix.logger.Warn("Failed to update index", slog.String("key", key), slog.String("error", err.Error()))
+	}
+}
+
+// StartRefreshRoutine refreshes every index that has a live source on each tick
+func (ix *Indexer) StartRefreshRoutine(ctx context.Context) {
+	if ix == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(ix.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ix.refreshAll(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (ix *Indexer) refreshAll(ctx context.Context) {
+	ix.mu.Lock()
+	sources := make(map[string]source, len(ix.sources))
+	for scopeID, src := range ix.sources {
+		if !ix.sessions.HasSession(src.sessionID) {
+			// Without a live session there are no credentials to refresh with
+			delete(ix.sources, scopeID)
+			continue
+		}
+		sources[scopeID] = src
+	}
+	ix.mu.Unlock()
+
+	for scopeID, src := range sources {
+		if err := ix.Refresh(ctx, src.client, scopeID, nil); err != nil {
+			ix.logger.Warn("Scheduled index refresh failed",
+				slog.String("index_id", scopeID),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+func objectsBucket(scopeID string) []byte {
+	return []byte("objects:" + scopeID)
+}
+
+func putStatus(meta *bolt.Bucket, status *models.IndexStatus) error {
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return meta.Put([]byte(status.ID), raw)
+}
+
+func toEntry(obj types.Object) entry {
+	return entry{
+		Size:         aws.ToInt64(obj.Size),
+		ETag:         aws.ToString(obj.ETag),
+		StorageClass: string(obj.StorageClass),
+		LastModified: aws.ToTime(obj.LastModified).UTC(),
+	}
+}
+
+func (e entry) toObject(key string) types.Object {
+	obj := types.Object{
+		Key:          aws.String(key),
+		Size:         aws.Int64(e.Size),
+		StorageClass: types.ObjectStorageClass(e.StorageClass),
+		LastModified: aws.Time(e.LastModified),
+	}
+	if e.ETag != "" {
+		obj.ETag = aws.String(e.ETag)
+	}
+	return obj
+}
@@ -0,0 +1,103 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// newTestIndex opens an indexer with one empty index, scope
+func newTestIndex(t *testing.T) *Indexer {
+	t.Helper()
+	ix, err := Open(t.TempDir(), 0, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { ix.Close() })
+	if err := ix.Enable("session", nil, "scope", "https://s3.example.com", "logs"); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	return ix
+}
+
+func TestWalk(t *testing.T) {
+	ix := newTestIndex(t)
+	// More objects under the prefix than Walk reads per transaction
+	for i := range 2*walkBatch + 10 {
+		ix.Put("scope", types.Object{Key: aws.String(fmt.Sprintf("app/%05d", i)), Size: aws.Int64(int64(i))})
+	}
+	ix.Put("scope", types.Object{Key: aws.String("api/first"), Size: aws.Int64(1)})
+	ix.Put("scope", types.Object{Key: aws.String("web/last"), Size: aws.Int64(1)})
+
+	var keys []string
+	err := ix.Walk(context.Background(), "scope", "app/", func(obj types.Object) error {
+		if open := ix.db.Stats().OpenTxN; open != 0 {
+			t.Fatalf("fn called with %d read transactions open", open)
+		}
+		keys = append(keys, aws.ToString(obj.Key))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if len(keys) != 2*walkBatch+10 {
+		t.Fatalf("Walk returned %d objects, want %d", len(keys), 2*walkBatch+10)
+	}
+	for i, key := range keys {
+		if want := fmt.Sprintf("app/%05d", i); key != want {
+			t.Fatalf("Walk object %d = %s, want %s", i, key, want)
+		}
+	}
+}
+
+func TestWalkSeesWritesBetweenBatches(t *testing.T) {
+	ix := newTestIndex(t)
+	for i := range walkBatch + 1 {
+		ix.Put("scope", types.Object{Key: aws.String(fmt.Sprintf("%05d", i))})
+	}
+
+	var walked int
+	err := ix.Walk(context.Background(), "scope", "", func(obj types.Object) error {
+		walked++
+		// Writers are not blocked while the caller handles a batch
+		if walked == 1 {
+			ix.Delete("scope", fmt.Sprintf("%05d", walkBatch))
+			ix.Put("scope", types.Object{Key: aws.String("zzzzz")})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	// The deleted key is gone by the second batch and the added one is there
+	if walked != walkBatch+1 {
+		t.Errorf("Walk returned %d objects, want %d", walked, walkBatch+1)
+	}
+}
+
+func TestWalkStops(t *testing.T) {
+	ix := newTestIndex(t)
+	for i := range 3 {
+		ix.Put("scope", types.Object{Key: aws.String(fmt.Sprint(i))})
+	}
+
+	stop := errors.New("stop")
+	var walked int
+	err := ix.Walk(context.Background(), "scope", "", func(obj types.Object) error {
+		walked++
+		return stop
+	})
+	if !errors.Is(err, stop) || walked != 1 {
+		t.Errorf("Walk = %v after %d objects, want the error of fn after 1", err, walked)
+	}
+
+	if err := ix.Walk(context.Background(), "missing", "", func(types.Object) error { return nil }); !errors.Is(err, ErrNotIndexed) {
+		t.Errorf("Walk of a missing index = %v, want ErrNotIndexed", err)
+	}
+}
@@ -0,0 +1,70 @@
+package listing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// WalkFunc enumerates the objects stored under prefix, in key order
+type WalkFunc func(ctx context.Context, prefix string, fn func(obj types.Object) error) error
+
+// Bucket returns a WalkFunc that lists bucket live through client
+func Bucket(client s3.ListObjectsV2APIClient, bucket string) WalkFunc {
+	return func(ctx context.Context, prefix string, fn func(obj types.Object) error) error {
+		return Walk(ctx, client, bucket, prefix, fn)
+	}
+}
+
+// Walk calls fn for every object stored under prefix in bucket, following
+// ListObjectsV2 continuation tokens until the listing is exhausted. It stops
+// at the first error returned by fn or when ctx is cancelled.
+func Walk(ctx context.Context, client s3.ListObjectsV2APIClient, bucket, prefix string, fn func(obj types.Object) error) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			if obj.Key == nil {
+				continue
+			}
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// ToS3Object converts a listed object into its API representation, with the
+// last-modified time formatted with layout
+func ToS3Object(obj types.Object, layout string) models.S3Object {
+	s3Object := models.S3Object{
+		Key:  aws.ToString(obj.Key),
+		Size: aws.ToInt64(obj.Size),
+	}
+
+	if obj.ETag != nil {
+		s3Object.ETag = aws.ToString(obj.ETag)
+	}
+	if obj.StorageClass != "" {
+		s3Object.StorageClass = string(obj.StorageClass)
+	}
+	if obj.LastModified != nil {
+		s3Object.LastModified = obj.LastModified.UTC().Format(layout)
+	}
+	return s3Object
+}
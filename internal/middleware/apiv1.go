@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/s3errors"
+)
+
+// APIv1Prefix is the root of the versioned API
+const APIv1Prefix = "/api/v1/"
+
+// deprecatedSince is when the unversioned routes were superseded by /api/v1
+var deprecatedSince = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// IsAPIv1 reports whether r was made to the versioned API
+func IsAPIv1(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, APIv1Prefix)
+}
+
+// Deprecated marks the responses of a route of the unversioned API as
+// deprecated (RFC 9745) and points clients to its successor
+func Deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecatedSince.Unix()))
+		w.Header().Set("Link", "<"+APIv1Prefix+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
+
+// JSONErrors rewrites the plain-text error responses of next, such as those
+// written by http.Error, into models.ErrorResponse bodies, so that every
+// error of the versioned API has the same shape
+func JSONErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew := &errorWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		if ew.status == 0 {
+			return
+		}
+
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(ew.status)
+		json.NewEncoder(w).Encode(models.ErrorResponse{
+			Code:      s3errors.StatusCode(ew.status),
+			Message:   strings.TrimSpace(ew.message.String()),
+			Retryable: s3errors.Retryable(ew.status),
+		})
+	})
+}
+
+// errorWriter holds back a plain-text error response until it can be
+// rewritten as JSON; everything else passes straight through
+type errorWriter struct {
+	http.ResponseWriter
+	status  int
+	message bytes.Buffer
+}
+
+func (w *errorWriter) WriteHeader(status int) {
+	if status >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	if w.status != 0 {
+		return w.message.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer to flush streams
+func (w *errorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -2,9 +2,13 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/cksidharthan/s3-browser/internal/identity"
 	"github.com/cksidharthan/s3-browser/internal/models"
 	"github.com/cksidharthan/s3-browser/internal/session"
 )
@@ -14,6 +18,7 @@ type contextKey string
 
 const (
 	SessionContextKey contextKey = "session"
+	OwnerContextKey   contextKey = "owner"
 )
 
 // Auth provides authentication middleware
@@ -30,21 +35,120 @@ func New(sessionManager *session.Manager, logger *slog.Logger) *Auth {
 	}
 }
 
-// RequireSession middleware to check for valid session
+// RequireSession middleware to check for valid session. The handler gets the
+// connection named by the connection query parameter, or else the active
+// connection of the browser session. Instead of the session cookie, requests
+// may carry a personal API token as "Authorization: Bearer <token>"; the
+// token's session then acts as a browser session with a single connection.
 func (a *Auth) RequireSession(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		session := a.sessionManager.GetSessionFromCookie(r)
-		if session == nil {
+		var owner *models.Session
+		if token, ok := bearerToken(r); ok {
+			var err error
+			owner, err = a.sessionManager.AuthenticateToken(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+				return
+			}
+		} else {
+			owner = a.sessionManager.GetSessionFromCookie(r)
+		}
+		if owner == nil {
 			http.Error(w, "No valid session", http.StatusUnauthorized)
 			return
 		}
+		session, err := a.sessionManager.Resolve(owner, r.URL.Query().Get("connection"))
+		if err != nil {
+			http.Error(w, "Unknown connection", http.StatusNotFound)
+			return
+		}
+		if session.CredentialsHaveExpired() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			if IsAPIv1(r) {
+				json.NewEncoder(w).Encode(models.ErrorResponse{
+					Code:    "CredentialsExpired",
+					Message: "Your S3 credentials have expired. Please reconnect.",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":   "credentials_expired",
+				"message": "Your S3 credentials have expired. Please reconnect.",
+			})
+			return
+		}
 
 		// Store session in context for use by handlers
 		ctx := context.WithValue(r.Context(), SessionContextKey, session)
+		ctx = context.WithValue(ctx, OwnerContextKey, owner)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
+// RequireWritableSession is RequireSession for routes that change data in S3;
+// read-only sessions are rejected with 403
+func (a *Auth) RequireWritableSession(next http.HandlerFunc) http.HandlerFunc {
+	return a.RequireSession(func(w http.ResponseWriter, r *http.Request) {
+		if a.sessionManager.IsReadOnly(GetSessionFromContext(r.Context())) {
+			http.Error(w, "This connection is read-only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// RequireUser puts sign-in in front of next: only users with an app session
+// in users get through, with the user in the request context. The sign-in
+// routes under /auth/ stay open, and so do requests with an API token that a
+// signed-in user created. Browser page loads are redirected to sign in at
+// loginPath; API requests get 401.
+func (a *Auth) RequireUser(users *identity.Sessions, loginPath string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/auth/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if user := users.Get(r); user != nil {
+			next.ServeHTTP(w, r.WithContext(identity.WithUser(r.Context(), user)))
+			return
+		}
+		if token, ok := bearerToken(r); ok {
+			if session, err := a.sessionManager.AuthenticateToken(token); err == nil && session.User != "" {
+				// The token's holder acts as its user, but cannot use their other connections
+				user := &models.User{ID: session.User}
+				next.ServeHTTP(w, r.WithContext(identity.WithUser(r.Context(), user)))
+				return
+			}
+		}
+
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Redirect(w, r, loginPath+"?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
+		if IsAPIv1(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(models.ErrorResponse{
+				Code:    "SignInRequired",
+				Message: "Sign in at " + loginPath + " first",
+			})
+			return
+		}
+		http.Error(w, "Sign in at "+loginPath+" first", http.StatusUnauthorized)
+	})
+}
+
 // GetSessionFromContext retrieves session from request context
 func GetSessionFromContext(ctx context.Context) *models.Session {
 	if session, ok := ctx.Value(SessionContextKey).(*models.Session); ok {
@@ -52,3 +156,12 @@ func GetSessionFromContext(ctx context.Context) *models.Session {
 	}
 	return nil
 }
+
+// GetOwnerFromContext retrieves the browser session that holds the connection
+// returned by GetSessionFromContext
+func GetOwnerFromContext(ctx context.Context) *models.Session {
+	if owner, ok := ctx.Value(OwnerContextKey).(*models.Session); ok {
+		return owner
+	}
+	return nil
+}
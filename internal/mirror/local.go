@@ -0,0 +1,94 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cksidharthan/s3-browser/internal/syncer"
+)
+
+// cleanDir turns a user supplied directory into a path relative to a root
+func cleanDir(dir string) string {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// localPath returns where key is stored under dir
+func localPath(dir, key string) string {
+	return path.Join(dir, key)
+}
+
+// Local lists the regular files under dir in root, keyed by their path
+// relative to dir. Symlinks and other special files are skipped, and a
+// directory that does not exist yet lists as empty.
+func Local(root *os.Root, dir string) syncer.Lister {
+	return func(ctx context.Context, fn func(entry syncer.Entry) error) error {
+		var entries []syncer.Entry
+		err := fs.WalkDir(root.FS(), dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if p == dir && errors.Is(err, fs.ErrNotExist) {
+					return fs.SkipAll
+				}
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			key := strings.TrimPrefix(p, dir+"/")
+			if dir == "." {
+				key = p
+			}
+			entries = append(entries, syncer.Entry{
+				Key:          key,
+				Size:         info.Size(),
+				LastModified: info.ModTime(),
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Directories are walked name by name, which is not the byte order of
+		// full keys that a merge with an S3 listing needs ("a-b" < "a/b")
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Key < entries[j].Key
+		})
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// mkdirAll creates dir and any missing parents inside root
+func mkdirAll(root *os.Root, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+	current := ""
+	for _, part := range strings.Split(dir, "/") {
+		current = path.Join(current, part)
+		if err := root.Mkdir(current, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
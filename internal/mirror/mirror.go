@@ -0,0 +1,234 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+	"github.com/cksidharthan/s3-browser/internal/syncer"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// Directions a mirror copies in
+const (
+	// Export copies a bucket prefix down to a local directory
+	Export = "export"
+	// Import pushes a local directory up to a bucket prefix
+	Import = "import"
+)
+
+// Mirror makes a local directory match a bucket prefix or the other way
+// round. Like a sync, both sides are compared by size and modification time
+// and only what differs is copied.
+type Mirror struct {
+	direction string
+	remote    transfer.Location
+	root      *os.Root
+	dir       string
+	delete    bool
+	logger    *slog.Logger
+}
+
+// New prepares a mirror between remote and dir inside root. With del, files
+// or objects missing from the source are removed from the destination.
+func New(direction string, remote transfer.Location, root *os.Root, dir string, del bool, logger *slog.Logger) *Mirror {
+	return &Mirror{
+		direction: direction,
+		remote:    remote,
+		root:      root,
+		dir:       cleanDir(dir),
+		delete:    del,
+		logger:    logger,
+	}
+}
+
+// Run compares both sides and copies what differs. Everything copied by an
+// earlier run compares as unchanged, so resuming simply runs it again.
+func (m *Mirror) Run(ctx context.Context, op *operations.Operation) error {
+	op.SetPhase("comparing")
+	remote := files(syncer.S3(m.remote.Client, m.remote.Bucket, m.remote.Prefix))
+	local := Local(m.root, m.dir)
+	source, destination := remote, local
+	if m.direction == Import {
+		source, destination = local, remote
+	}
+	items, _, err := syncer.Plan(ctx, source, destination, syncer.Options{Compare: syncer.CompareModified, Delete: m.delete})
+	if err != nil {
+		return err
+	}
+
+	var copies, deletes []string
+	for _, item := range items {
+		if item.Action == models.SyncDelete {
+			deletes = append(deletes, item.Key)
+		} else {
+			copies = append(copies, item.Key)
+		}
+	}
+	op.SetTotal(int64(len(items)))
+
+	op.SetPhase("copying files")
+	if n := m.copyAll(ctx, op, copies); n > 0 {
+		return fmt.Errorf("%d files could not be copied; resume the operation to retry them", n)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(deletes) > 0 {
+		op.SetPhase("deleting extraneous files")
+		return m.deleteAll(ctx, op, deletes)
+	}
+	return nil
+}
+
+// copyAll copies keys with transfer.Concurrency workers and returns how many failed
+func (m *Mirror) copyAll(ctx context.Context, op *operations.Operation, keys []string) int64 {
+	var failed atomic.Int64
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for range transfer.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range queue {
+				copyFile := m.download
+				if m.direction == Import {
+					copyFile = m.upload
+				}
+				size, err := copyFile(ctx, key)
+				if err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					failed.Add(1)
+					op.AddFailed(1)
+					m.logger.Warn("Failed to mirror file",
+						slog.String("direction", m.direction),
+						slog.String("key", key),
+						slog.String("error", err.Error()))
+					continue
+				}
+				op.AddDone(1, size)
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		select {
+		case queue <- key:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return failed.Load()
+}
+
+// download writes the object key to its local path, removing the partial
+// file if the copy fails
+func (m *Mirror) download(ctx context.Context, key string) (int64, error) {
+	p := localPath(m.dir, key)
+	if !fs.ValidPath(p) {
+		return 0, fmt.Errorf("key %q cannot be stored under the mirror root", key)
+	}
+
+	object, err := m.remote.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(m.remote.Bucket),
+		Key:    aws.String(m.remote.Prefix + key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read object: %w", err)
+	}
+	defer object.Body.Close()
+
+	if err := mkdirAll(m.root, path.Dir(p)); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := m.root.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	n, err := io.Copy(f, object.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if size := aws.ToInt64(object.ContentLength); err == nil && n != size {
+		err = fmt.Errorf("read %d bytes, expected %d", n, size)
+	}
+	if err != nil {
+		m.root.Remove(p)
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+	return n, nil
+}
+
+// upload writes the local file key to its object
+func (m *Mirror) upload(ctx context.Context, key string) (int64, error) {
+	f, err := m.root.Open(localPath(m.dir, key))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if err := transfer.Upload(ctx, m.remote, m.remote.Prefix+key, f, info.Size(), contentType, m.logger); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// deleteAll removes keys from the destination side
+func (m *Mirror) deleteAll(ctx context.Context, op *operations.Operation, keys []string) error {
+	if m.direction == Import {
+		objects := make([]string, 0, len(keys))
+		for _, key := range keys {
+			objects = append(objects, m.remote.Prefix+key)
+		}
+		return syncer.DeleteKeys(ctx, m.remote, objects, op)
+	}
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := m.root.Remove(localPath(m.dir, key)); err != nil {
+			op.AddFailed(1)
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+		op.AddDone(1, 0)
+	}
+	return nil
+}
+
+// files drops folder markers, and the object named like the prefix itself,
+// from an S3 listing; neither has a file to mirror
+func files(list syncer.Lister) syncer.Lister {
+	return func(ctx context.Context, fn func(entry syncer.Entry) error) error {
+		return list(ctx, func(entry syncer.Entry) error {
+			if entry.Key == "" || strings.HasSuffix(entry.Key, "/") {
+				return nil
+			}
+			return fn(entry)
+		})
+	}
+}
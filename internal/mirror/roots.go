@@ -0,0 +1,92 @@
+package mirror
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownRoot is returned for root names the operator has not configured
+var ErrUnknownRoot = errors.New("unknown mirror root")
+
+// Roots are the local directories the operator allows mirroring to and from,
+// by name. A nil Roots has none, as when mirroring is not enabled. Paths are
+// only ever opened through an os.Root, so neither ".." nor symlinks can reach
+// outside of them.
+type Roots struct {
+	roots map[string]*os.Root
+}
+
+// ParseRoots parses a comma-separated list of name=directory pairs
+func ParseRoots(value string) (map[string]string, error) {
+	dirs := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, dir, ok := strings.Cut(item, "=")
+		name, dir = strings.TrimSpace(name), strings.TrimSpace(dir)
+		if !ok || name == "" || dir == "" {
+			return nil, fmt.Errorf("invalid mirror root %q, expected name=directory", item)
+		}
+		if _, exists := dirs[name]; exists {
+			return nil, fmt.Errorf("mirror root %q is declared twice", name)
+		}
+		dirs[name] = dir
+	}
+	return dirs, nil
+}
+
+// OpenRoots opens the directories of dirs, keyed by name
+func OpenRoots(dirs map[string]string) (*Roots, error) {
+	r := &Roots{roots: make(map[string]*os.Root, len(dirs))}
+	for name, dir := range dirs {
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to open mirror root %q: %w", name, err)
+		}
+		r.roots[name] = root
+	}
+	return r, nil
+}
+
+// Names returns the configured root names in sorted order
+func (r *Roots) Names() []string {
+	if r == nil {
+		return []string{}
+	}
+	names := make([]string, 0, len(r.roots))
+	for name := range r.roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the root called name
+func (r *Roots) Get(name string) (*os.Root, error) {
+	if r == nil {
+		return nil, ErrUnknownRoot
+	}
+	root, exists := r.roots[name]
+	if !exists {
+		return nil, ErrUnknownRoot
+	}
+	return root, nil
+}
+
+// Close releases the root directories
+func (r *Roots) Close() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for _, root := range r.roots {
+		errs = append(errs, root.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package models
+
+// Classes of a key in a comparison of two locations
+const (
+	CompareOnlyLeft    = "only-left"
+	CompareOnlyRight   = "only-right"
+	CompareSizeDiffers = "size-differs"
+	CompareETagDiffers = "etag-differs"
+	// CompareETagIncomparable is a key with the same size on both sides whose
+	// ETags have different shapes, e.g. copies uploaded in other part sizes
+	CompareETagIncomparable = "etag-incomparable"
+	CompareIdentical        = "identical"
+)
+
+// CompareEntry is a key found in either location, relative to the prefixes
+type CompareEntry struct {
+	Key       string `json:"key"`
+	Class     string `json:"class"`
+	LeftSize  *int64 `json:"left_size,omitempty"`
+	RightSize *int64 `json:"right_size,omitempty"`
+	LeftETag  string `json:"left_etag,omitempty"`
+	RightETag string `json:"right_etag,omitempty"`
+}
+
+// CompareSummary totals a comparison; Match is true when no key is missing
+// or differs, counting keys whose ETags cannot be compared as matching by size
+type CompareSummary struct {
+	OnlyLeft         int64 `json:"only_left"`
+	OnlyRight        int64 `json:"only_right"`
+	SizeDiffers      int64 `json:"size_differs"`
+	ETagDiffers      int64 `json:"etag_differs"`
+	ETagIncomparable int64 `json:"etag_incomparable"`
+	Identical        int64 `json:"identical"`
+	Match            bool  `json:"match"`
+}
+
+// CompareResult is one line of a streamed comparison. Entries carry a key;
+// the final line is a summary, or an error if a listing failed.
+type CompareResult struct {
+	Type    string          `json:"type"`
+	Entry   *CompareEntry   `json:"entry,omitempty"`
+	Summary *CompareSummary `json:"summary,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// CompareReport is a complete comparison, as exported in JSON
+type CompareReport struct {
+	Left    TransferLocation `json:"left"`
+	Right   TransferLocation `json:"right"`
+	Entries []CompareEntry   `json:"entries"`
+	Summary CompareSummary   `json:"summary"`
+}
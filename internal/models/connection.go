@@ -0,0 +1,17 @@
+package models
+
+// ConnectionInfo describes an operator-defined connection to users
+type ConnectionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Endpoint    string `json:"endpoint"`
+	Region      string `json:"region,omitempty"`
+	ReadOnly    bool   `json:"read_only"`
+}
+
+// ConnectionListResponse lists the connections a user may pick and whether
+// they may also enter an endpoint and keys of their own
+type ConnectionListResponse struct {
+	Connections  []ConnectionInfo `json:"connections"`
+	AdHocAllowed bool             `json:"ad_hoc_allowed"`
+}
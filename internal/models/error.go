@@ -0,0 +1,15 @@
+package models
+
+// ErrorResponse is the body of every error response on /api/v1, and of
+// failed S3 requests on the deprecated routes
+type ErrorResponse struct {
+	// Code is the S3 error code, e.g. NoSuchKey or SlowDown, or the HTTP
+	// status text without spaces, e.g. BadRequest, for other errors
+	Code string `json:"code"`
+	// Message explains the error to the user
+	Message string `json:"message"`
+	// RequestID identifies the failed request to the storage provider
+	RequestID string `json:"request_id,omitempty"`
+	// Retryable is true when the same request may succeed later
+	Retryable bool `json:"retryable"`
+}
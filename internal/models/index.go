@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IndexStatus describes a local snapshot of a bucket listing and how stale it is
+type IndexStatus struct {
+	ID          string     `json:"id"`
+	Endpoint    string     `json:"endpoint"`
+	Bucket      string     `json:"bucket"`
+	ObjectCount int64      `json:"object_count"`
+	TotalSize   int64      `json:"total_size"`
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+	AgeSeconds  int64      `json:"age_seconds"`
+	Stale       bool       `json:"stale"`
+	Refreshing  bool       `json:"refreshing"`
+	LastError   string     `json:"last_error,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// MirrorRequest asks for a bucket prefix and a directory under one of the
+// operator's mirror roots to be made to match. Direction is "export" (S3 to
+// the directory) or "import" (the directory to S3).
+type MirrorRequest struct {
+	Direction  string `json:"direction"`
+	Connection string `json:"connection,omitempty"`
+	Bucket     string `json:"bucket"`
+	Prefix     string `json:"prefix,omitempty"`
+	Root       string `json:"root"`
+	Path       string `json:"path,omitempty"`
+	Delete     bool   `json:"delete,omitempty"`
+}
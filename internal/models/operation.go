@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// OperationStatus describes the lifecycle state of a background operation
+type OperationStatus string
+
+const (
+	OperationPending   OperationStatus = "pending"
+	OperationRunning   OperationStatus = "running"
+	OperationCompleted OperationStatus = "completed"
+	OperationFailed    OperationStatus = "failed"
+	OperationCancelled OperationStatus = "cancelled"
+)
+
+// OperationProgress reports how far a background operation has got
+type OperationProgress struct {
+	Phase  string `json:"phase,omitempty"`
+	Total  int64  `json:"total"`
+	Done   int64  `json:"done"`
+	Failed int64  `json:"failed"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// Operation represents a long-running task started by a session
+type Operation struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Target     string            `json:"target"`
+	Status     OperationStatus   `json:"status"`
+	Progress   OperationProgress `json:"progress"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Page is one page of a list in the v1 API. Passing NextCursor back as the
+// cursor query parameter fetches the following page; it is empty on the last.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ObjectPage is a page of objects. Listed with a delimiter, the common
+// prefixes of the keys that were rolled up are returned in Prefixes.
+type ObjectPage struct {
+	Items      []S3Object `json:"items"`
+	Prefixes   []string   `json:"prefixes,omitempty"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
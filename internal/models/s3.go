@@ -1,16 +1,31 @@
 package models
 
+import "time"
+
+// LegacyTimeLayout formats timestamps on the deprecated unversioned routes,
+// which predate /api/v1 and its RFC 3339 timestamps
+const LegacyTimeLayout = "2006-01-02 15:04:05"
+
 // S3Object represents an S3 object with its metadata
 type S3Object struct {
 	Key          string `json:"key"`
 	Size         int64  `json:"size"`
 	ETag         string `json:"etag"`
 	StorageClass string `json:"storage_class"`
-	LastModified string `json:"last_modified,omitempty"`
+	LastModified string `json:"last_modified,omitempty" format:"date-time"`
 }
 
-// S3Bucket represents an S3 bucket with its metadata
+// S3Bucket represents an S3 bucket with its metadata. CreationDate is
+// unknown for buckets declared when connecting.
 type S3Bucket struct {
 	Name         string `json:"name"`
-	CreationDate string `json:"creation_date"`
+	CreationDate string `json:"creation_date,omitempty" format:"date-time"`
+	// Prefix is set for sessions limited to part of the bucket
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// PresignedURL is a temporary URL that gives direct access to an object
+type PresignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
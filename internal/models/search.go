@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SearchResult is one line of a streamed search response. Matches carry an
+// object; the final line is a summary, or an error if the walk failed.
+type SearchResult struct {
+	Type    string         `json:"type"`
+	Object  *S3Object      `json:"object,omitempty"`
+	Summary *SearchSummary `json:"summary,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// SearchSummary reports how much of the bucket a search examined and whether
+// it was answered from a live listing or from the local index
+type SearchSummary struct {
+	Scanned   int64      `json:"scanned"`
+	Matched   int64      `json:"matched"`
+	Truncated bool       `json:"truncated"`
+	Source    string     `json:"source"`
+	IndexedAt *time.Time `json:"indexed_at,omitempty"`
+}
@@ -1,22 +1,131 @@
 package models
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-// Session stores connection information
+// Session stores connection information. Everything except the S3 client is
+// persisted by the session store; the client is rebuilt from the stored
+// parameters. Secrets only ever exist in Credentials, encrypted.
 type Session struct {
-	ID        string
-	Endpoint  string
-	AccessKey string
-	SecretKey string
-	Region    string
-	UseSSL    bool
-	S3Client  *s3.Client
-	CreatedAt time.Time
-	LastUsed  time.Time
+	ID        string `json:"id"`
+	Endpoint  string `json:"endpoint"`
+	AccessKey string `json:"access_key"`
+	// Profile is set instead of AccessKey when the session uses server-side credentials
+	Profile     string        `json:"profile,omitempty"`
+	Credentials *SealedSecret `json:"credentials"`
+	Region      string        `json:"region"`
+	UseSSL      bool          `json:"use_ssl"`
+	S3Client    *s3.Client    `json:"-"`
+	CreatedAt   time.Time     `json:"created_at"`
+	LastUsed    time.Time     `json:"last_used"`
+	// ExpiresAt is when temporary credentials stop working, if known
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// CredentialsExpired is set once S3 has rejected the credentials as expired
+	CredentialsExpired bool `json:"credentials_expired"`
+	// AssumeRole is set when S3 is accessed through a role assumed with the
+	// base credentials above
+	AssumeRole *AssumedRole `json:"assume_role,omitempty"`
+	// Connection names the operator-defined connection the session was opened with
+	Connection string `json:"connection,omitempty"`
+	// ReadOnly sessions may not change anything in S3
+	ReadOnly bool `json:"read_only,omitempty"`
+	// VirtualHosted selects virtual-hosted style addressing instead of path style
+	VirtualHosted bool `json:"virtual_hosted,omitempty"`
+	// CACertPEM holds extra CA certificates trusted for the endpoint
+	CACertPEM string `json:"ca_cert_pem,omitempty"`
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// Anonymous sessions send unsigned requests, for public buckets
+	Anonymous bool `json:"anonymous,omitempty"`
+	// Buckets are the buckets declared when connecting; when set they are
+	// listed instead of calling ListBuckets and no other bucket can be used
+	Buckets []string `json:"buckets,omitempty"`
+	// Prefix further limits a session with declared buckets to keys under it
+	Prefix string `json:"prefix,omitempty"`
+	// Owner is the ID of the browser session a connection was added to; the
+	// browser session itself is the one named by the session cookie
+	Owner string `json:"owner,omitempty"`
+	// ActiveConnection is the connection of a browser session that requests
+	// use when they do not name one; empty means the browser session itself
+	ActiveConnection string `json:"active_connection,omitempty"`
+	// User is the ID of the signed-in user who opened the session, when
+	// users have to sign in; nobody else can use it
+	User string `json:"user,omitempty"`
+	// Token is set on sessions that stand for a personal API token; they are
+	// only reachable with the token, never with the session cookie
+	Token *TokenGrant `json:"token,omitempty"`
+}
+
+// AssumedRole holds the non-secret parameters of a role assumed via STS
+type AssumedRole struct {
+	RoleARN         string `json:"role_arn"`
+	SessionName     string `json:"session_name"`
+	DurationSeconds int32  `json:"duration_seconds,omitempty"`
+	STSEndpoint     string `json:"sts_endpoint,omitempty"`
+}
+
+// Principal identifies who acts for the session: the operator-defined
+// connection, the assumed role, the server profile or the access key
+func (s *Session) Principal() string {
+	if s.Connection != "" {
+		return "connection:" + s.Connection
+	}
+	if s.AssumeRole != nil {
+		return s.AssumeRole.RoleARN
+	}
+	if s.Profile != "" {
+		return "profile:" + s.Profile
+	}
+	if s.Anonymous {
+		return "anonymous"
+	}
+	return s.AccessKey
+}
+
+// CredentialsHaveExpired reports whether the session needs to reconnect with new credentials
+func (s *Session) CredentialsHaveExpired() bool {
+	return s.CredentialsExpired || (s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt))
+}
+
+// LogValue keeps sessions logged as a whole down to non-sensitive fields
+func (s *Session) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", s.ID),
+		slog.String("endpoint", s.Endpoint),
+		slog.String("region", s.Region),
+		slog.String("principal", s.Principal()),
+	)
+}
+
+// SessionSecrets are the credential parts that must never be stored or logged in plaintext
+type SessionSecrets struct {
+	SecretKey    string `json:"secret_key"`
+	SessionToken string `json:"session_token,omitempty"`
+	ExternalID   string `json:"external_id,omitempty"`
+	// ProxyURL may carry proxy credentials, so it is kept with the secrets
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// RoleCredentials are the temporary credentials of an assumed role that
+	// cannot be refreshed without user input, i.e. one assumed with MFA
+	RoleCredentials *RoleCredentials `json:"role_credentials,omitempty"`
+}
+
+// RoleCredentials are temporary credentials issued by STS
+type RoleCredentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+}
+
+// SealedSecret is a value encrypted with a per-secret data key, which is in
+// turn encrypted (wrapped) with the server master key identified by KeyID
+type SealedSecret struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Ciphertext []byte `json:"ciphertext"`
 }
 
 // ConnectionRequest holds the parameters for establishing an S3 connection
@@ -26,6 +135,61 @@ type ConnectionRequest struct {
 	SecretKey string `json:"secret_key"`
 	Region    string `json:"region"`
 	UseSSL    bool   `json:"use_ssl"`
+	// SessionToken and Expiration come with temporary (STS-issued) credentials
+	SessionToken string     `json:"session_token,omitempty"`
+	Expiration   *time.Time `json:"expiration,omitempty"`
+	// Profile connects with a server-side AWS profile (see /api/profiles)
+	// instead of AccessKey and SecretKey; "default" uses the SDK default chain
+	Profile string `json:"profile,omitempty"`
+	// AssumeRole makes the session access S3 through a role assumed with the credentials above
+	AssumeRole *AssumeRoleRequest `json:"assume_role,omitempty"`
+	// PathStyle selects path-style addressing (the default) or virtual-hosted style when false
+	PathStyle *bool `json:"path_style,omitempty"`
+	// CACertPEM adds CA certificates to trust, e.g. an internal CA or a self-signed certificate
+	CACertPEM string `json:"ca_cert_pem,omitempty"`
+	// CACertFile reads the CA bundle from the server; only allowed in the connections file
+	CACertFile string `json:"ca_cert_file,omitempty"`
+	// InsecureSkipVerify disables TLS certificate verification. Use only for testing.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// ProxyURL sends S3 and STS requests through an HTTP(S) proxy
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// Anonymous connects without credentials; Buckets must then name the buckets to browse
+	Anonymous bool `json:"anonymous,omitempty"`
+	// Buckets and Prefix scope the session for credentials that may not call
+	// ListBuckets; they are tested with HeadBucket or ListObjectsV2 instead
+	Buckets []string `json:"buckets,omitempty"`
+	Prefix  string   `json:"prefix,omitempty"`
+	// ReadOnly rejects every change for the lifetime of the session
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// AssumeRoleRequest holds the parameters of an STS AssumeRole call. Without
+// MFA the role credentials are refreshed automatically; with MFA they are
+// fetched once and the session must reconnect when they expire.
+type AssumeRoleRequest struct {
+	RoleARN     string `json:"role_arn"`
+	ExternalID  string `json:"external_id,omitempty"`
+	SessionName string `json:"session_name,omitempty"`
+	// DurationSeconds is the lifetime of each set of role credentials (900-43200)
+	DurationSeconds int32  `json:"duration_seconds,omitempty"`
+	MFASerial       string `json:"mfa_serial,omitempty"`
+	MFAToken        string `json:"mfa_token,omitempty"`
+	// STSEndpoint overrides the STS endpoint, e.g. for a local STS stand-in
+	STSEndpoint string `json:"sts_endpoint,omitempty"`
+}
+
+// LogValue redacts the secret key when a connection request is logged
+func (c ConnectionRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endpoint", c.Endpoint),
+		slog.String("region", c.Region),
+		slog.Bool("use_ssl", c.UseSSL),
+		slog.Bool("temporary_credentials", c.SessionToken != ""),
+		slog.String("profile", c.Profile),
+		slog.Bool("anonymous", c.Anonymous),
+		slog.Bool("assume_role", c.AssumeRole != nil),
+		slog.Bool("insecure_skip_verify", c.InsecureSkipVerify),
+	)
 }
 
 // ConnectionResponse represents the response from a connection attempt
@@ -35,7 +199,38 @@ type ConnectionResponse struct {
 	SessionID string `json:"session_id,omitempty"`
 }
 
-// SessionStatusResponse represents the current session status
+// Profile is a server-side AWS profile users may connect with
+type Profile struct {
+	Name   string `json:"name"`
+	Region string `json:"region,omitempty"`
+	// DefaultChain is set for the SDK default credential chain
+	DefaultChain bool `json:"default_chain"`
+}
+
+// SessionStatusResponse represents the current session status. When
+// CredentialsExpired is set the user has to reconnect with new credentials.
+// ReadOnly tells the UI to hide actions that change data. Both describe the
+// active connection; Connections lists every connection of the browser session.
+// User is set when users have to sign in to S3 Browser.
 type SessionStatusResponse struct {
-	HasSession bool `json:"has_session"`
+	HasSession         bool                `json:"has_session"`
+	CredentialsExpired bool                `json:"credentials_expired"`
+	ExpiresAt          *time.Time          `json:"expires_at,omitempty"`
+	ReadOnly           bool                `json:"read_only"`
+	ActiveConnection   string              `json:"active_connection,omitempty"`
+	Connections        []SessionConnection `json:"connections,omitempty"`
+	User               *User               `json:"user,omitempty"`
+}
+
+// SessionConnection describes one connection held by a browser session. ID
+// is what API routes accept in their connection parameter.
+type SessionConnection struct {
+	ID                 string    `json:"id"`
+	Connection         string    `json:"connection,omitempty"`
+	Endpoint           string    `json:"endpoint"`
+	Region             string    `json:"region"`
+	ReadOnly           bool      `json:"read_only"`
+	CredentialsExpired bool      `json:"credentials_expired"`
+	Active             bool      `json:"active"`
+	CreatedAt          time.Time `json:"created_at"`
 }
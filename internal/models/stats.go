@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// StatsBreakdown aggregates the objects that fall into one category
+type StatsBreakdown struct {
+	Count int64 `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+// BucketStats summarizes the objects stored in a bucket or under a prefix
+type BucketStats struct {
+	Bucket         string                    `json:"bucket"`
+	Prefix         string                    `json:"prefix,omitempty"`
+	ObjectCount    int64                     `json:"object_count"`
+	TotalSize      int64                     `json:"total_size"`
+	StorageClasses map[string]StatsBreakdown `json:"storage_classes"`
+	Prefixes       map[string]StatsBreakdown `json:"prefixes"`
+	Extensions     map[string]StatsBreakdown `json:"extensions"`
+	Ages           map[string]StatsBreakdown `json:"ages"`
+	ComputedAt     time.Time                 `json:"computed_at"`
+	Cached         bool                      `json:"cached"`
+	Source         string                    `json:"source"`
+	IndexedAt      *time.Time                `json:"indexed_at,omitempty"`
+}
+
+// DiskUsageNode is one prefix in a storage usage tree
+type DiskUsageNode struct {
+	Name        string           `json:"name"`
+	Prefix      string           `json:"prefix"`
+	Size        int64            `json:"size"`
+	ObjectCount int64            `json:"object_count"`
+	Largest     []S3Object       `json:"largest"`
+	Children    []*DiskUsageNode `json:"children,omitempty"`
+}
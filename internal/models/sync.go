@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// SyncAction is what executing a sync plan does to a key
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncDelete SyncAction = "delete"
+)
+
+// SyncRequest asks for a plan that makes the destination match the source.
+// Include and exclude globs apply to keys relative to the prefixes; Compare
+// is "etag" (the default) or "modified" for providers whose ETags differ.
+type SyncRequest struct {
+	Source      TransferLocation `json:"source"`
+	Destination TransferLocation `json:"destination"`
+	Include     []string         `json:"include,omitempty"`
+	Exclude     []string         `json:"exclude,omitempty"`
+	Compare     string           `json:"compare,omitempty"`
+	Delete      bool             `json:"delete,omitempty"`
+}
+
+// SyncItem is a single step of a sync plan. Key is relative to the prefixes.
+type SyncItem struct {
+	Action SyncAction `json:"action"`
+	Key    string     `json:"key"`
+	Size   int64      `json:"size"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// SyncSummary totals a sync plan
+type SyncSummary struct {
+	Creates   int64 `json:"creates"`
+	Updates   int64 `json:"updates"`
+	Deletes   int64 `json:"deletes"`
+	Unchanged int64 `json:"unchanged"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// SyncPlan is the dry-run report of a sync, kept until it is executed or expires
+type SyncPlan struct {
+	ID          string           `json:"id"`
+	Source      TransferLocation `json:"source"`
+	Destination TransferLocation `json:"destination"`
+	Compare     string           `json:"compare"`
+	Delete      bool             `json:"delete"`
+	Items       []SyncItem       `json:"items"`
+	Summary     SyncSummary      `json:"summary"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+}
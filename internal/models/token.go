@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// TokenGrant is what makes a stored session an API token. Only a hash of the
+// token's secret is kept.
+type TokenGrant struct {
+	Name       string    `json:"name"`
+	SecretHash string    `json:"secret_hash"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Owner is the ID of the browser session that created the token. Tokens
+	// created by a signed-in user belong to the user instead.
+	Owner string `json:"owner"`
+}
+
+// APITokenRequest asks for a personal API token for one of the connections of
+// the browser session
+type APITokenRequest struct {
+	Name string `json:"name"`
+	// Connection is the ID of the connection to bind the token to; empty means the active one
+	Connection string `json:"connection,omitempty"`
+	// ReadOnly tokens may not change anything in S3, even on a writable connection
+	ReadOnly bool `json:"read_only,omitempty"`
+	// Buckets are the only buckets the token may use; when the connection
+	// declares buckets they must be among them
+	Buckets []string `json:"buckets,omitempty"`
+	// ExpiresAt defaults to 30 days from now and may be at most a year away
+	ExpiresAt *time.Time `json:"expires_at,omitempty" format:"date-time"`
+}
+
+// APIToken describes a personal API token without its secret
+type APIToken struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Endpoint   string    `json:"endpoint"`
+	Connection string    `json:"connection,omitempty"`
+	ReadOnly   bool      `json:"read_only"`
+	Buckets    []string  `json:"buckets,omitempty"`
+	CreatedAt  time.Time `json:"created_at" format:"date-time"`
+	LastUsed   time.Time `json:"last_used" format:"date-time"`
+	ExpiresAt  time.Time `json:"expires_at" format:"date-time"`
+}
+
+// CreatedAPIToken is returned once, when a token is created; Token is the
+// bearer credential and cannot be retrieved again
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// TransferLocation is a bucket, or a prefix within it, on one of the
+// connections of the browser session
+type TransferLocation struct {
+	Connection string `json:"connection,omitempty"`
+	Bucket     string `json:"bucket"`
+	Prefix     string `json:"prefix,omitempty"`
+}
+
+// TransferRequest asks for objects to be copied between two locations. With
+// no keys, everything under the source prefix is copied.
+type TransferRequest struct {
+	Source       TransferLocation `json:"source"`
+	Destination  TransferLocation `json:"destination"`
+	Keys         []string         `json:"keys,omitempty"`
+	SkipExisting bool             `json:"skip_existing,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// User is someone signed in to S3 Browser itself, as opposed to the S3
+// credentials of a session
+type User struct {
+	// ID is unique per user, e.g. the issuer and subject of an OIDC identity
+	ID     string   `json:"id"`
+	Name   string   `json:"name,omitempty"`
+	Email  string   `json:"email,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	// Connections are the operator-defined connections the user may use
+	Connections []string  `json:"connections"`
+	ExpiresAt   time.Time `json:"expires_at" format:"date-time"`
+}
+
+// AppSession is what is stored for the app session of a signed-in user
+type AppSession struct {
+	User User `json:"user"`
+	// Credential fingerprints the password a local user signed in with, so
+	// that changing it signs them out, even across restarts
+	Credential string `json:"credential,omitempty"`
+}
+
+// LoginRequest signs in a local user, one declared in the users file
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
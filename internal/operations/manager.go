@@ -0,0 +1,300 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/google/uuid"
+)
+
+// Func is the body of a background operation. It should return promptly
+// once ctx is cancelled. A Func may be run again to resume an operation that
+// failed or was cancelled, so it should skip work it has already done.
+type Func func(ctx context.Context, op *Operation) error
+
+var (
+	// ErrNotFound is returned for operations that do not exist or belong to someone else
+	ErrNotFound = errors.New("operation not found")
+	// ErrNotResumable is returned when resuming an operation that is still running or completed
+	ErrNotResumable = errors.New("only failed or cancelled operations can be resumed")
+)
+
+// Operation is a running or finished background task
+type Operation struct {
+	mu         sync.Mutex
+	id         string
+	owner      string
+	kind       string
+	target     string
+	status     models.OperationStatus
+	progress   models.OperationProgress
+	err        string
+	createdAt  time.Time
+	updatedAt  time.Time
+	finishedAt time.Time
+	fn         Func
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// ID returns the operation identifier
+func (o *Operation) ID() string {
+	return o.id
+}
+
+// Owner returns the session ID that started the operation
+func (o *Operation) Owner() string {
+	return o.owner
+}
+
+// SetPhase records a human-readable description of the current step
+func (o *Operation) SetPhase(phase string) {
+	o.update(func(p *models.OperationProgress) { p.Phase = phase })
+}
+
+// SetTotal records the number of items the operation expects to process
+func (o *Operation) SetTotal(total int64) {
+	o.update(func(p *models.OperationProgress) { p.Total = total })
+}
+
+// AddTotal grows the expected number of items, for operations that discover work as they go
+func (o *Operation) AddTotal(n int64) {
+	o.update(func(p *models.OperationProgress) { p.Total += n })
+}
+
+// AddDone records processed items and the bytes they accounted for
+func (o *Operation) AddDone(items, bytes int64) {
+	o.update(func(p *models.OperationProgress) {
+		p.Done += items
+		p.Bytes += bytes
+	})
+}
+
+// SetDone records the absolute number of processed items
+func (o *Operation) SetDone(items int64) {
+	o.update(func(p *models.OperationProgress) { p.Done = items })
+}
+
+// AddFailed records items that could not be processed
+func (o *Operation) AddFailed(items int64) {
+	o.update(func(p *models.OperationProgress) { p.Failed += items })
+}
+
+// Done returns a channel that is closed once the operation has finished
+func (o *Operation) Done() <-chan struct{} {
+	return o.done
+}
+
+// Snapshot returns a copy of the operation state suitable for serialization
+func (o *Operation) Snapshot() models.Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	snapshot := models.Operation{
+		ID:        o.id,
+		Type:      o.kind,
+		Target:    o.target,
+		Status:    o.status,
+		Progress:  o.progress,
+		Error:     o.err,
+		CreatedAt: o.createdAt,
+		UpdatedAt: o.updatedAt,
+	}
+	if !o.finishedAt.IsZero() {
+		finishedAt := o.finishedAt
+		snapshot.FinishedAt = &finishedAt
+	}
+	return snapshot
+}
+
+func (o *Operation) update(fn func(p *models.OperationProgress)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fn(&o.progress)
+	o.updatedAt = time.Now()
+}
+
+func (o *Operation) setStatus(status models.OperationStatus, err string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.status = status
+	o.err = err
+	o.updatedAt = time.Now()
+	if status != models.OperationPending && status != models.OperationRunning {
+		o.finishedAt = o.updatedAt
+	}
+}
+
+func (o *Operation) finished() (bool, time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return !o.finishedAt.IsZero(), o.finishedAt
+}
+
+// Manager runs background operations and keeps track of their progress
+type Manager struct {
+	operations map[string]*Operation
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	logger     *slog.Logger
+}
+
+// New creates a new operation manager
+func New(logger *slog.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		operations: make(map[string]*Operation),
+		ctx:        ctx,
+		cancel:     cancel,
+		logger:     logger,
+	}
+}
+
+// Start runs fn in the background on behalf of owner and returns immediately
+func (m *Manager) Start(owner, kind, target string, fn Func) *Operation {
+	ctx, cancel := context.WithCancel(m.ctx)
+	now := time.Now()
+	op := &Operation{
+		id:        uuid.New().String(),
+		owner:     owner,
+		kind:      kind,
+		target:    target,
+		status:    models.OperationPending,
+		createdAt: now,
+		updatedAt: now,
+		fn:        fn,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.operations[op.id] = op
+	m.mu.Unlock()
+
+	m.logger.Info("Operation started",
+		slog.String("operation_id", op.id),
+		slog.String("type", kind),
+		slog.String("target", target))
+
+	go m.run(ctx, op, fn)
+	return op
+}
+
+func (m *Manager) run(ctx context.Context, op *Operation, fn Func) {
+	defer close(op.done)
+	defer op.cancel()
+
+	op.setStatus(models.OperationRunning, "")
+	err := fn(ctx, op)
+
+	switch {
+	case err == nil:
+		op.setStatus(models.OperationCompleted, "")
+		m.logger.Info("Operation completed", slog.String("operation_id", op.id))
+	case errors.Is(err, context.Canceled) || ctx.Err() != nil:
+		op.setStatus(models.OperationCancelled, "operation was cancelled")
+		m.logger.Info("Operation cancelled", slog.String("operation_id", op.id))
+	default:
+		op.setStatus(models.OperationFailed, err.Error())
+		m.logger.Error("Operation failed",
+			slog.String("operation_id", op.id),
+			slog.String("error", err.Error()))
+	}
+}
+
+// Get returns the operation with the given ID if it belongs to owner
+func (m *Manager) Get(owner, id string) *Operation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	op, exists := m.operations[id]
+	if !exists || op.owner != owner {
+		return nil
+	}
+	return op
+}
+
+// List returns all operations belonging to owner, newest first
+func (m *Manager) List(owner string) []*Operation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ops := make([]*Operation, 0)
+	for _, op := range m.operations {
+		if op.owner == owner {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].createdAt.After(ops[j].createdAt)
+	})
+	return ops
+}
+
+// Cancel requests cancellation of the operation with the given ID.
+// It returns false if no such operation belongs to owner.
+func (m *Manager) Cancel(owner, id string) bool {
+	op := m.Get(owner, id)
+	if op == nil {
+		return false
+	}
+	op.cancel()
+	return true
+}
+
+// Resume starts a failed or cancelled operation of owner again as a new
+// operation with the same type, target and function
+func (m *Manager) Resume(owner, id string) (*Operation, error) {
+	op := m.Get(owner, id)
+	if op == nil {
+		return nil, ErrNotFound
+	}
+
+	op.mu.Lock()
+	status := op.status
+	op.mu.Unlock()
+	if status != models.OperationFailed && status != models.OperationCancelled {
+		return nil, ErrNotResumable
+	}
+
+	m.logger.Info("Operation resumed", slog.String("operation_id", id))
+	return m.Start(owner, op.kind, op.target, op.fn), nil
+}
+
+// CleanupFinishedOperations removes operations that finished more than 24 hours ago
+func (m *Manager) CleanupFinishedOperations() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiry := time.Now().Add(-24 * time.Hour)
+	for id, op := range m.operations {
+		if finished, at := op.finished(); finished && at.Before(expiry) {
+			delete(m.operations, id)
+		}
+	}
+}
+
+// StartCleanupRoutine starts a background routine that forgets old operations
+// and cancels running ones once ctx is done
+func (m *Manager) StartCleanupRoutine(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.CleanupFinishedOperations()
+			case <-ctx.Done():
+				m.cancel()
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+// Package s3errors translates errors returned by S3 into HTTP responses
+package s3errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// Codes used when S3 gave no error code of its own
+const (
+	CodeUnavailable = "ServiceUnavailable"
+	CodeInternal    = "InternalServerError"
+	CodeCancelled   = "RequestCancelled"
+)
+
+// codeStatus is the HTTP status each known S3 error code is reported with
+var codeStatus = map[string]int{
+	"InvalidBucketName":         http.StatusBadRequest,
+	"InvalidArgument":           http.StatusBadRequest,
+	"InvalidRequest":            http.StatusBadRequest,
+	"InvalidStorageClass":       http.StatusBadRequest,
+	"InvalidLocationConstraint": http.StatusBadRequest,
+	"KeyTooLongError":           http.StatusBadRequest,
+	"MetadataTooLarge":          http.StatusBadRequest,
+	"EntityTooLarge":            http.StatusBadRequest,
+	"EntityTooSmall":            http.StatusBadRequest,
+	"MalformedXML":              http.StatusBadRequest,
+	"TooManyBuckets":            http.StatusBadRequest,
+
+	"AccessDenied":          http.StatusForbidden,
+	"AllAccessDisabled":     http.StatusForbidden,
+	"AccountProblem":        http.StatusForbidden,
+	"InvalidAccessKeyId":    http.StatusForbidden,
+	"SignatureDoesNotMatch": http.StatusForbidden,
+	"RequestTimeTooSkewed":  http.StatusForbidden,
+	"ExpiredToken":          http.StatusForbidden,
+	"InvalidToken":          http.StatusForbidden,
+	"InvalidObjectState":    http.StatusForbidden,
+
+	"NoSuchBucket":  http.StatusNotFound,
+	"NoSuchKey":     http.StatusNotFound,
+	"NoSuchUpload":  http.StatusNotFound,
+	"NoSuchVersion": http.StatusNotFound,
+	"NotFound":      http.StatusNotFound,
+
+	"BucketAlreadyExists":     http.StatusConflict,
+	"BucketAlreadyOwnedByYou": http.StatusConflict,
+	"BucketNotEmpty":          http.StatusConflict,
+	"InvalidBucketState":      http.StatusConflict,
+	"OperationAborted":        http.StatusConflict,
+
+	"PreconditionFailed": http.StatusPreconditionFailed,
+	"InvalidRange":       http.StatusRequestedRangeNotSatisfiable,
+
+	"SlowDown":             http.StatusTooManyRequests,
+	"TooManyRequests":      http.StatusTooManyRequests,
+	"Throttling":           http.StatusTooManyRequests,
+	"ThrottlingException":  http.StatusTooManyRequests,
+	"RequestLimitExceeded": http.StatusTooManyRequests,
+
+	"ServiceUnavailable": http.StatusServiceUnavailable,
+	"InternalError":      http.StatusServiceUnavailable,
+	"RequestTimeout":     http.StatusServiceUnavailable,
+}
+
+// codeMessage is what users are told for the most common S3 error codes
+var codeMessage = map[string]string{
+	"NoSuchBucket":            "Bucket not found.",
+	"NoSuchKey":               "Object not found.",
+	"NotFound":                "Not found.",
+	"NoSuchUpload":            "The multipart upload does not exist or has been completed.",
+	"AccessDenied":            "Access denied: you don't have permission to do this.",
+	"InvalidAccessKeyId":      "The access key is not valid for this endpoint.",
+	"SignatureDoesNotMatch":   "The secret key is not valid for this access key.",
+	"ExpiredToken":            "The session credentials have expired; reconnect to continue.",
+	"InvalidObjectState":      "The object is archived and must be restored before it can be read.",
+	"BucketAlreadyExists":     "Bucket already exists. Bucket names must be globally unique.",
+	"BucketAlreadyOwnedByYou": "You already own a bucket with this name.",
+	"BucketNotEmpty":          "The bucket is not empty. Delete all objects first, or force delete it.",
+	"PreconditionFailed":      "The object has changed since it was read.",
+	"InvalidRange":            "The requested range is not satisfiable.",
+	"InvalidBucketName":       "Invalid bucket name. Bucket names must follow S3 naming conventions.",
+	"RequestTimeTooSkewed":    "The server clock differs too much from the storage service clock.",
+	"SlowDown":                "The storage service is throttling requests; try again shortly.",
+	"ServiceUnavailable":      "The storage service is unavailable; try again shortly.",
+	"InternalError":           "The storage service failed to handle the request; try again shortly.",
+}
+
+// Translate maps an error returned by an S3 call to the HTTP status and
+// error body it should be reported with. Known error codes decide the
+// status; otherwise the status S3 responded with is kept where it is one
+// clients can act on, and server-side failures become 503. Errors that did
+// not come from S3 are reported as 500.
+func Translate(err error) (int, models.ErrorResponse) {
+	var response models.ErrorResponse
+	var respErr *awshttp.ResponseError
+	httpStatus := 0
+	if errors.As(err, &respErr) {
+		httpStatus = respErr.HTTPStatusCode()
+		response.RequestID = respErr.ServiceRequestID()
+	}
+
+	var apiErr smithy.APIError
+	var opErr *smithy.OperationError
+	switch {
+	case errors.As(err, &apiErr):
+		response.Code = apiErr.ErrorCode()
+		response.Message = apiErr.ErrorMessage()
+	case errors.Is(err, context.Canceled):
+		response.Code = CodeCancelled
+		response.Message = "The request was cancelled."
+	case httpStatus == 0 && errors.As(err, &opErr):
+		// An S3 call got no response at all: the endpoint could not be reached
+		response.Code = CodeUnavailable
+		response.Message = "The storage service could not be reached: " + err.Error()
+	case httpStatus == 0:
+		// Not an S3 error, e.g. a listing that failed a local check
+		return http.StatusInternalServerError, models.ErrorResponse{Code: CodeInternal, Message: err.Error()}
+	default:
+		response.Code = StatusCode(httpStatus)
+		response.Message = err.Error()
+	}
+
+	status, known := codeStatus[response.Code]
+	if !known {
+		status = statusFor(httpStatus)
+	}
+	// Errors raised before a request was sent, such as by the read-only
+	// guard, already explain themselves
+	if message, ok := codeMessage[response.Code]; ok && (httpStatus != 0 || response.Message == "") {
+		response.Message = message
+	}
+	if response.Message == "" {
+		response.Message = response.Code
+	}
+	response.Retryable = Retryable(status)
+	return status, response
+}
+
+// StatusCode is the error code of a failure that has nothing but an HTTP
+// status to go by: the status text without spaces, e.g. NotFound
+func StatusCode(status int) string {
+	return strings.ReplaceAll(http.StatusText(status), " ", "")
+}
+
+// Retryable reports whether a request that failed with status may succeed
+// when repeated later
+func Retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// statusFor picks the status for an error S3 responded with but whose code
+// is not known
+func statusFor(httpStatus int) int {
+	switch {
+	case httpStatus == 0, httpStatus >= 500:
+		return http.StatusServiceUnavailable
+	case httpStatus == http.StatusBadRequest,
+		httpStatus == http.StatusForbidden,
+		httpStatus == http.StatusNotFound,
+		httpStatus == http.StatusConflict,
+		httpStatus == http.StatusPreconditionFailed,
+		httpStatus == http.StatusRequestedRangeNotSatisfiable,
+		httpStatus == http.StatusTooManyRequests:
+		return httpStatus
+	case httpStatus == http.StatusUnauthorized:
+		return http.StatusForbidden
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,145 @@
+package s3errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// responseError is an S3 call that got a response with status, failing with err
+func responseError(status int, err error) error {
+	return &smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: "GetObject",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      err,
+			},
+			RequestID: "request-1",
+		},
+	}
+}
+
+// apiError is an S3 call that got a response with status and an error code
+func apiError(status int, code, message string) error {
+	return responseError(status, &smithy.GenericAPIError{Code: code, Message: message})
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		code      string
+		message   string
+		requestID string
+	}{
+		{
+			name:   "known code",
+			err:    apiError(404, "NoSuchKey", "The specified key does not exist."),
+			status: 404, code: "NoSuchKey", message: "Object not found.", requestID: "request-1",
+		},
+		{
+			name:   "code deciding over the response status",
+			err:    apiError(503, "SlowDown", "Please reduce your request rate."),
+			status: 429, code: "SlowDown", message: "The storage service is throttling requests; try again shortly.", requestID: "request-1",
+		},
+		{
+			name:   "unknown code",
+			err:    apiError(400, "XAmzContentSHA256Mismatch", "The provided content hash does not match."),
+			status: 400, code: "XAmzContentSHA256Mismatch", message: "The provided content hash does not match.", requestID: "request-1",
+		},
+		{
+			name:   "unknown code of a server failure",
+			err:    apiError(500, "WeirdFailure", ""),
+			status: 503, code: "WeirdFailure", message: "WeirdFailure", requestID: "request-1",
+		},
+		{
+			name:   "raw 5xx",
+			err:    responseError(502, errors.New("unexpected EOF")),
+			status: 503, code: "BadGateway", requestID: "request-1",
+		},
+		{
+			name:   "raw 401",
+			err:    responseError(401, errors.New("unauthorized")),
+			status: 403, code: "Unauthorized", requestID: "request-1",
+		},
+		{
+			name:   "raw status clients cannot act on",
+			err:    responseError(405, errors.New("method not allowed")),
+			status: 502, code: "MethodNotAllowed", requestID: "request-1",
+		},
+		{
+			name:   "no response",
+			err:    &smithy.OperationError{ServiceID: "S3", OperationName: "ListBuckets", Err: errors.New("dial tcp: connection refused")},
+			status: 503, code: CodeUnavailable,
+		},
+		{
+			name:   "cancelled",
+			err:    &smithy.OperationError{ServiceID: "S3", OperationName: "ListObjectsV2", Err: fmt.Errorf("request canceled: %w", context.Canceled)},
+			status: 503, code: CodeCancelled, message: "The request was cancelled.",
+		},
+		{
+			name:   "refused before sending",
+			err:    &smithy.OperationError{ServiceID: "S3", OperationName: "PutObject", Err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "This session is read-only."}},
+			status: 403, code: "AccessDenied", message: "This session is read-only.",
+		},
+		{
+			name:   "refused before sending without a message",
+			err:    &smithy.GenericAPIError{Code: "AccessDenied"},
+			status: 403, code: "AccessDenied", message: "Access denied: you don't have permission to do this.",
+		},
+		{
+			name:   "not from S3",
+			err:    errors.New("listing is not in key order"),
+			status: 500, code: CodeInternal, message: "listing is not in key order",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := Translate(tt.err)
+			if status != tt.status || body.Code != tt.code || body.RequestID != tt.requestID {
+				t.Errorf("Translate = %d %s (request %q), want %d %s (request %q)", status, body.Code, body.RequestID, tt.status, tt.code, tt.requestID)
+			}
+			if tt.message != "" && body.Message != tt.message {
+				t.Errorf("Translate message = %q, want %q", body.Message, tt.message)
+			}
+			if body.Message == "" {
+				t.Error("Translate gave no message")
+			}
+			if body.Retryable != Retryable(status) {
+				t.Errorf("Translate retryable = %v for status %d", body.Retryable, status)
+			}
+		})
+	}
+}
+
+func TestTranslateKnownCodes(t *testing.T) {
+	for code, want := range codeStatus {
+		// The code decides whatever status the response had
+		for _, responded := range []int{400, 500} {
+			status, body := Translate(apiError(responded, code, "message from S3"))
+			if status != want || body.Code != code {
+				t.Errorf("Translate of %s answered with %d = %d %s, want %d", code, responded, status, body.Code, want)
+			}
+			if message, ok := codeMessage[code]; ok && body.Message != message {
+				t.Errorf("Translate of %s message = %q, want %q", code, body.Message, message)
+			}
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for status, want := range map[int]bool{400: false, 403: false, 404: false, 429: true, 500: false, 502: false, 503: true} {
+		if got := Retryable(status); got != want {
+			t.Errorf("Retryable(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
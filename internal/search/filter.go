@@ -0,0 +1,175 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Filter selects objects by key pattern and metadata. Zero values match everything.
+type Filter struct {
+	Pattern        *regexp.Regexp
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	StorageClasses map[string]bool
+	ETag           string
+}
+
+// ParseFilter builds a filter from query parameters: glob or regex (mutually
+// exclusive), ignore_case, min_size, max_size, modified_after and
+// modified_before (RFC 3339), storage_class (comma-separated) and etag
+func ParseFilter(values url.Values) (*Filter, error) {
+	filter := &Filter{MaxSize: -1}
+
+	glob := values.Get("glob")
+	expr := values.Get("regex")
+	ignoreCase := values.Get("ignore_case") == "true"
+	switch {
+	case glob != "" && expr != "":
+		return nil, fmt.Errorf("glob and regex cannot be combined")
+	case glob != "":
+		expr = GlobToRegexp(glob)
+		fallthrough
+	case expr != "":
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key pattern: %w", err)
+		}
+		filter.Pattern = pattern
+	}
+
+	var err error
+	if filter.MinSize, err = parseSize(values, "min_size", 0); err != nil {
+		return nil, err
+	}
+	if filter.MaxSize, err = parseSize(values, "max_size", -1); err != nil {
+		return nil, err
+	}
+	if filter.ModifiedAfter, err = parseTime(values, "modified_after"); err != nil {
+		return nil, err
+	}
+	if filter.ModifiedBefore, err = parseTime(values, "modified_before"); err != nil {
+		return nil, err
+	}
+
+	if classes := values.Get("storage_class"); classes != "" {
+		filter.StorageClasses = make(map[string]bool)
+		for _, class := range strings.Split(classes, ",") {
+			filter.StorageClasses[strings.ToUpper(strings.TrimSpace(class))] = true
+		}
+	}
+	filter.ETag = strings.Trim(values.Get("etag"), `"`)
+
+	return filter, nil
+}
+
+// Match reports whether obj satisfies every criterion of the filter
+func (f *Filter) Match(obj types.Object) bool {
+	if f.Pattern != nil && !f.Pattern.MatchString(aws.ToString(obj.Key)) {
+		return false
+	}
+
+	size := aws.ToInt64(obj.Size)
+	if size < f.MinSize || (f.MaxSize >= 0 && size > f.MaxSize) {
+		return false
+	}
+
+	lastModified := aws.ToTime(obj.LastModified)
+	if !f.ModifiedAfter.IsZero() && !lastModified.After(f.ModifiedAfter) {
+		return false
+	}
+	if !f.ModifiedBefore.IsZero() && !lastModified.Before(f.ModifiedBefore) {
+		return false
+	}
+
+	if f.StorageClasses != nil {
+		storageClass := string(obj.StorageClass)
+		if storageClass == "" {
+			storageClass = string(types.ObjectStorageClassStandard)
+		}
+		if !f.StorageClasses[storageClass] {
+			return false
+		}
+	}
+
+	if f.ETag != "" && strings.Trim(aws.ToString(obj.ETag), `"`) != f.ETag {
+		return false
+	}
+	return true
+}
+
+// GlobToRegexp translates a glob into an anchored regular expression. "*"
+// and "?" do not cross "/" boundaries, "**" matches any number of path
+// segments, and bracket expressions are passed through.
+func GlobToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				// "**/" also matches zero directories
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func parseSize(values url.Values, name string, defaultValue int64) (int64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+	size, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number of bytes", name)
+	}
+	return size, nil
+}
+
+func parseTime(values url.Values, name string) (time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
@@ -0,0 +1,210 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// keySize is the length of master and data keys (AES-256)
+const keySize = 32
+
+// ErrUnknownKey is returned when a secret was sealed with a key that is not in the keyring
+var ErrUnknownKey = errors.New("secret was encrypted with an unknown master key")
+
+// Keyring holds the server master keys. The primary key encrypts new
+// secrets; every key in the ring can decrypt, which allows rotating keys by
+// putting a new key first while the old ones are still listed.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// ParseKeyring reads base64-encoded 32-byte keys separated by newlines or
+// commas. The first key is the primary; blank lines and # comments are ignored.
+func ParseKeyring(spec string) (*Keyring, error) {
+	ring := &Keyring{keys: make(map[string][]byte)}
+
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == '\n' || r == ',' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("master keys must be %d random bytes encoded as base64", keySize)
+		}
+
+		id := keyID(key)
+		if ring.primary == "" {
+			ring.primary = id
+		}
+		ring.keys[id] = key
+	}
+
+	if ring.primary == "" {
+		return nil, fmt.Errorf("no master key found")
+	}
+	return ring, nil
+}
+
+// LoadKeyring reads the keyring from a file
+func LoadKeyring(path string) (*Keyring, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	return ParseKeyring(string(raw))
+}
+
+// NewEphemeralKeyring creates a keyring with a random key that only lives as
+// long as the process
+func NewEphemeralKeyring() (*Keyring, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	id := keyID(key)
+	return &Keyring{primary: id, keys: map[string][]byte{id: key}}, nil
+}
+
+// PrimaryKeyID returns the identifier of the key used for new secrets
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// Seal encrypts plaintext under a fresh data key and wraps the data key with
+// the primary master key. Both are bound to id, the record the secret is
+// kept in, so a secret copied onto another record does not open.
+func (k *Keyring) Seal(id string, plaintext []byte) (*models.SealedSecret, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := encrypt(dataKey, plaintext, []byte(id))
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := encrypt(k.keys[k.primary], dataKey, wrapData(k.primary, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SealedSecret{
+		KeyID:      k.primary,
+		WrappedKey: wrappedKey,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// Open decrypts a secret sealed for the record id
+func (k *Keyring) Open(id string, sealed *models.SealedSecret) ([]byte, error) {
+	dataKey, err := k.unwrap(id, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decrypt(dataKey, sealed.Ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap re-encrypts the data key of a secret sealed for the record id under
+// an older master key with the primary key. It reports whether anything
+// changed.
+func (k *Keyring) Rewrap(id string, sealed *models.SealedSecret) (bool, error) {
+	if sealed.KeyID == k.primary {
+		return false, nil
+	}
+
+	dataKey, err := k.unwrap(id, sealed)
+	if err != nil {
+		return false, err
+	}
+	wrappedKey, err := encrypt(k.keys[k.primary], dataKey, wrapData(k.primary, id))
+	if err != nil {
+		return false, err
+	}
+
+	sealed.KeyID = k.primary
+	sealed.WrappedKey = wrappedKey
+	return true, nil
+}
+
+func (k *Keyring) unwrap(id string, sealed *models.SealedSecret) ([]byte, error) {
+	if sealed == nil {
+		return nil, fmt.Errorf("no secret stored")
+	}
+
+	masterKey, exists := k.keys[sealed.KeyID]
+	if !exists {
+		return nil, ErrUnknownKey
+	}
+	dataKey, err := decrypt(masterKey, sealed.WrappedKey, wrapData(sealed.KeyID, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// wrapData is the additional data a data key is wrapped with: the master key
+// and the record the secret belongs to
+func wrapData(keyID, id string) []byte {
+	return []byte(keyID + "\x00" + id)
+}
+
+// keyID derives a stable, non-secret identifier from key material
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// encrypt seals plaintext with AES-GCM, prefixing the random nonce
+func encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// decrypt opens a nonce-prefixed AES-GCM ciphertext
+func decrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,229 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newKey returns a random master key encoded as in a key file
+func newKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestParseKeyring(t *testing.T) {
+	first, second := newKey(t), newKey(t)
+
+	ring, err := ParseKeyring("# rotated in March\n" + first + "\n\n" + second + "\n")
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	commas, err := ParseKeyring(first + "," + second)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	if ring.PrimaryKeyID() != commas.PrimaryKeyID() || len(ring.keys) != 2 {
+		t.Errorf("keys separated by newlines and by commas parse differently")
+	}
+
+	for name, spec := range map[string]string{
+		"empty":         "",
+		"comments only": "# no keys yet",
+		"not base64":    "not a key",
+		"short key":     base64.StdEncoding.EncodeToString([]byte("too short")),
+	} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring of %s succeeded", name)
+		}
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key := newKey(t)
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	parsed, _ := ParseKeyring(key)
+	if ring.PrimaryKeyID() != parsed.PrimaryKeyID() {
+		t.Errorf("LoadKeyring primary = %s, want %s", ring.PrimaryKeyID(), parsed.PrimaryKeyID())
+	}
+	if _, err := LoadKeyring(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadKeyring of a missing file succeeded")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	ring, err := NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	plaintext := []byte(`{"secret_key":"wJalrXUtnFEMI"}`)
+
+	sealed, err := ring.Seal("session", plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if sealed.KeyID != ring.PrimaryKeyID() {
+		t.Errorf("sealed with key %s, want the primary %s", sealed.KeyID, ring.PrimaryKeyID())
+	}
+	if bytes.Contains(sealed.Ciphertext, plaintext) || bytes.Contains(sealed.Ciphertext, []byte("wJalrXUtnFEMI")) {
+		t.Error("the ciphertext contains the plaintext")
+	}
+
+	opened, err := ring.Open("session", sealed)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %q, want %q", opened, plaintext)
+	}
+
+	// Every secret gets a data key of its own
+	again, err := ring.Seal("session", plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Equal(again.WrappedKey, sealed.WrappedKey) || bytes.Equal(again.Ciphertext, sealed.Ciphertext) {
+		t.Error("sealing the same plaintext twice gave the same result")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	ring, err := NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	other, err := NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	sealed, err := ring.Seal("session", []byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	if _, err := other.Open("session", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open with another keyring = %v, want ErrUnknownKey", err)
+	}
+	if _, err := ring.Open("session", nil); err == nil {
+		t.Error("Open of nothing succeeded")
+	}
+
+	tampered := *sealed
+	tampered.Ciphertext = bytes.Clone(sealed.Ciphertext)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	if _, err := ring.Open("session", &tampered); err == nil {
+		t.Error("Open of a tampered ciphertext succeeded")
+	}
+
+	tampered = *sealed
+	tampered.WrappedKey = bytes.Clone(sealed.WrappedKey)
+	tampered.WrappedKey[len(tampered.WrappedKey)-1] ^= 1
+	if _, err := ring.Open("session", &tampered); err == nil {
+		t.Error("Open of a tampered data key succeeded")
+	}
+}
+
+func TestSealBindsRecord(t *testing.T) {
+	ring, err := NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	sealed, err := ring.Seal("alice", []byte("alice's secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// A secret copied onto the record of another session stays sealed
+	copied := *sealed
+	if _, err := ring.Open("mallory", &copied); err == nil {
+		t.Error("Open of a secret copied to another record succeeded")
+	}
+	if _, err := ring.Open("", &copied); err == nil {
+		t.Error("Open of a secret without its record succeeded")
+	}
+
+	// The data key is bound too, so rotating keys does not launder a copy
+	retired, current := newKey(t), newKey(t)
+	before, _ := ParseKeyring(retired)
+	old, err := before.Seal("alice", []byte("alice's secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	rotated, _ := ParseKeyring(current + "\n" + retired)
+	if _, err := rotated.Rewrap("mallory", old); err == nil {
+		t.Error("Rewrap of a secret copied to another record succeeded")
+	}
+	if _, err := rotated.Rewrap("alice", old); err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if _, err := rotated.Open("mallory", old); err == nil {
+		t.Error("Open of a rewrapped secret copied to another record succeeded")
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	retired, current := newKey(t), newKey(t)
+	before, err := ParseKeyring(retired)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	sealed, err := before.Seal("session", []byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	ciphertext := bytes.Clone(sealed.Ciphertext)
+
+	// The new key is put first while the old one is still listed
+	rotated, err := ParseKeyring(current + "\n" + retired)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	if opened, err := rotated.Open("session", sealed); err != nil || string(opened) != "secret" {
+		t.Fatalf("Open under the old key after rotation = %q, %v", opened, err)
+	}
+
+	changed, err := rotated.Rewrap("session", sealed)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if !changed || sealed.KeyID != rotated.PrimaryKeyID() {
+		t.Errorf("Rewrap = %v with key %s, want the secret moved to the primary %s", changed, sealed.KeyID, rotated.PrimaryKeyID())
+	}
+	if !bytes.Equal(sealed.Ciphertext, ciphertext) {
+		t.Error("Rewrap re-encrypted the secret itself instead of only its data key")
+	}
+
+	changed, err = rotated.Rewrap("session", sealed)
+	if err != nil || changed {
+		t.Errorf("Rewrap of a secret under the primary key = %v, %v, want no change", changed, err)
+	}
+
+	// Once the old key is dropped, only rewrapped secrets can still be opened
+	after, err := ParseKeyring(current)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	if opened, err := after.Open("session", sealed); err != nil || string(opened) != "secret" {
+		t.Errorf("Open after the old key was dropped = %q, %v", opened, err)
+	}
+	stale, _ := before.Seal("session", []byte("secret"))
+	if _, err := after.Rewrap("session", stale); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Rewrap of a secret under a dropped key = %v, want ErrUnknownKey", err)
+	}
+}
@@ -3,65 +3,307 @@ package server
 import (
 	"context"
 	"embed"
+	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/cksidharthan/s3-browser/internal/connections"
 	"github.com/cksidharthan/s3-browser/internal/handlers"
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/index"
 	"github.com/cksidharthan/s3-browser/internal/middleware"
+	"github.com/cksidharthan/s3-browser/internal/mirror"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+	"github.com/cksidharthan/s3-browser/internal/secrets"
 	"github.com/cksidharthan/s3-browser/internal/session"
+	"github.com/cksidharthan/s3-browser/internal/sso"
+	"github.com/cksidharthan/s3-browser/internal/stats"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
+// Options holds optional server features
+type Options struct {
+	// DataDir enables the persistent key index when set
+	DataDir string
+	// IndexInterval is how often indexed buckets are refreshed
+	IndexInterval time.Duration
+	// SessionStore selects where sessions are kept: memory (default), bolt or redis
+	SessionStore string
+	// RedisURL locates the Redis-compatible server used by the redis session store
+	RedisURL string
+	// MasterKeyFile and MasterKeys provide the keys that encrypt stored
+	// credentials; without either a random key is generated at startup
+	MasterKeyFile string
+	MasterKeys    string
+	// PurgeUnreadableSessions removes stored sessions at startup whose
+	// credentials none of the master keys can open
+	PurgeUnreadableSessions bool
+	// AllowedProfiles are the server-side AWS profiles users may connect
+	// with; "default" offers the SDK default credential chain
+	AllowedProfiles []string
+	// ConnectionsFile declares operator-defined connections
+	ConnectionsFile string
+	// ReadOnly rejects every change to S3 for all sessions
+	ReadOnly bool
+	// MirrorRoots enables mirroring to and from the local directories it
+	// lists, as comma-separated name=directory pairs
+	MirrorRoots string
+	// OIDC puts sign-in with an OpenID Connect provider in front of every
+	// route when its Issuer is set
+	OIDC sso.Config
+	// UsersFile puts sign-in as one of the local users it declares in front
+	// of every route; it cannot be combined with OIDC
+	UsersFile string
+}
+
+// signInTTL is how long users stay signed in to S3 Browser
+const signInTTL = 12 * time.Hour
+
 // Server represents the HTTP server
 type Server struct {
-	sessionManager *session.Manager
-	auth           *middleware.Auth
-	sessionHandler *handlers.SessionHandler
-	bucketHandler  *handlers.BucketHandler
-	objectHandler  *handlers.ObjectHandler
-	logger         *slog.Logger
-	mux            *http.ServeMux
+	sessionManager   *session.Manager
+	operationManager *operations.Manager
+	indexer          *index.Indexer
+	mirrorRoots      *mirror.Roots
+	auth             *middleware.Auth
+	sessionHandler   *handlers.SessionHandler
+	bucketHandler    *handlers.BucketHandler
+	objectHandler    *handlers.ObjectHandler
+	operationHandler *handlers.OperationHandler
+	statsHandler     *handlers.StatsHandler
+	searchHandler    *handlers.SearchHandler
+	indexHandler     *handlers.IndexHandler
+	transferHandler  *handlers.TransferHandler
+	syncHandler      *handlers.SyncHandler
+	compareHandler   *handlers.CompareHandler
+	mirrorHandler    *handlers.MirrorHandler
+	tokenHandler     *handlers.TokenHandler
+	authHandler      *handlers.AuthHandler
+	users            *identity.Sessions
+	localUsers       *identity.LocalUsers
+	logger           *slog.Logger
+	mux              *http.ServeMux
 }
 
 // New creates a new server instance
-func New(logger *slog.Logger, frontendFS embed.FS) *Server {
-	sessionManager := session.New(logger)
+func New(logger *slog.Logger, frontendFS embed.FS, opts Options) (*Server, error) {
+	sessionStore, err := newSessionStore(opts)
+	if err != nil {
+		return nil, err
+	}
+	keyring, err := newKeyring(opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	var conns *connections.Config
+	if opts.ConnectionsFile != "" {
+		conns, err = connections.Load(opts.ConnectionsFile)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Loaded configured connections",
+			slog.Int("connections", len(conns.Connections)),
+			slog.Bool("ad_hoc_allowed", conns.AdHocAllowed()))
+		for _, conn := range conns.Connections {
+			if conn.InsecureSkipVerify {
+				logger.Warn("TLS CERTIFICATE VERIFICATION IS DISABLED for a configured connection; credentials and data can be intercepted",
+					slog.String("connection", conn.Name),
+					slog.String("endpoint", conn.Endpoint))
+			}
+		}
+	}
+	sessionManager := session.New(sessionStore, keyring, opts.AllowedProfiles, conns, opts.ReadOnly, logger)
+	if opts.ReadOnly {
+		logger.Info("Read-only mode enabled; uploads, deletes and bucket changes are rejected")
+	}
+	rotated, unreadable, err := sessionManager.RotateKeys(opts.PurgeUnreadableSessions)
+	if err != nil {
+		return nil, err
+	}
+	if rotated > 0 {
+		logger.Info("Re-encrypted stored sessions with the primary master key", slog.Int("sessions", rotated))
+	}
+	switch {
+	case unreadable > 0 && opts.PurgeUnreadableSessions:
+		logger.Info("Removed stored sessions the master keys cannot open", slog.Int("sessions", unreadable))
+	case unreadable > 0:
+		logger.Warn("Stored sessions cannot be opened with the configured master keys and were left in place; configure the key that sealed them, or start with -purge-unreadable-sessions to remove them",
+			slog.Int("sessions", unreadable))
+	}
+	operationManager := operations.New(logger)
 	auth := middleware.New(sessionManager, logger)
 
+	var indexer *index.Indexer
+	if opts.DataDir != "" {
+		indexer, err = index.Open(opts.DataDir, opts.IndexInterval, sessionManager, logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Key index enabled",
+			slog.String("data_dir", opts.DataDir),
+			slog.Duration("refresh_interval", opts.IndexInterval))
+	}
+
+	var mirrorRoots *mirror.Roots
+	if opts.MirrorRoots != "" {
+		dirs, err := mirror.ParseRoots(opts.MirrorRoots)
+		if err != nil {
+			return nil, err
+		}
+		mirrorRoots, err = mirror.OpenRoots(dirs)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Local filesystem mirroring enabled", slog.Any("roots", mirrorRoots.Names()))
+	}
+
+	var authHandler *handlers.AuthHandler
+	var users *identity.Sessions
+	var localUsers *identity.LocalUsers
+	switch {
+	case opts.OIDC.Issuer != "" && opts.UsersFile != "":
+		return nil, fmt.Errorf("use either -oidc-issuer or -users-file, not both")
+	case opts.OIDC.Issuer != "":
+		provider, err := sso.New(context.Background(), opts.OIDC, conns.ForGroups, keyring)
+		if err != nil {
+			return nil, err
+		}
+		users = identity.NewSessions(signInTTL, sessionStore, logger)
+		authHandler = handlers.NewAuthHandler(provider, nil, users, logger)
+		logger.Info("OIDC sign-in required",
+			slog.String("issuer", opts.OIDC.Issuer),
+			slog.String("client_id", opts.OIDC.ClientID))
+	case opts.UsersFile != "":
+		users = identity.NewSessions(signInTTL, sessionStore, logger)
+		localUsers, err = identity.LoadLocalUsers(opts.UsersFile, conns.Names(), users, logger)
+		if err != nil {
+			return nil, err
+		}
+		authHandler = handlers.NewAuthHandler(nil, localUsers, users, logger)
+		logger.Info("Local user sign-in required",
+			slog.String("users_file", opts.UsersFile),
+			slog.Int("users", localUsers.Len()))
+	}
+
 	server := &Server{
-		sessionManager: sessionManager,
-		auth:           auth,
-		sessionHandler: handlers.NewSessionHandler(sessionManager, logger),
-		bucketHandler:  handlers.NewBucketHandler(logger),
-		objectHandler:  handlers.NewObjectHandler(logger),
-		logger:         logger,
-		mux:            http.NewServeMux(),
+		sessionManager:   sessionManager,
+		operationManager: operationManager,
+		indexer:          indexer,
+		mirrorRoots:      mirrorRoots,
+		auth:             auth,
+		sessionHandler:   handlers.NewSessionHandler(sessionManager, logger),
+		bucketHandler:    handlers.NewBucketHandler(operationManager, indexer, logger),
+		objectHandler:    handlers.NewObjectHandler(indexer, logger),
+		operationHandler: handlers.NewOperationHandler(operationManager, logger),
+		statsHandler:     handlers.NewStatsHandler(stats.NewCache(1*time.Hour), indexer, logger),
+		searchHandler:    handlers.NewSearchHandler(indexer, logger),
+		indexHandler:     handlers.NewIndexHandler(indexer, operationManager, logger),
+		transferHandler:  handlers.NewTransferHandler(sessionManager, operationManager, logger),
+		syncHandler:      handlers.NewSyncHandler(sessionManager, operationManager, logger),
+		compareHandler:   handlers.NewCompareHandler(sessionManager, logger),
+		mirrorHandler:    handlers.NewMirrorHandler(sessionManager, operationManager, mirrorRoots, logger),
+		tokenHandler:     handlers.NewTokenHandler(sessionManager, logger),
+		authHandler:      authHandler,
+		users:            users,
+		localUsers:       localUsers,
+		logger:           logger,
+		mux:              http.NewServeMux(),
 	}
 
 	server.setupRoutes(frontendFS)
-	return server
+	return server, nil
+}
+
+// newSessionStore creates the session store selected in opts
+func newSessionStore(opts Options) (session.Store, error) {
+	switch opts.SessionStore {
+	case "", "memory":
+		return session.NewMemoryStore(), nil
+	case "bolt":
+		if opts.DataDir == "" {
+			return nil, fmt.Errorf("the bolt session store requires a data directory")
+		}
+		return session.NewBoltStore(filepath.Join(opts.DataDir, "sessions.db"))
+	case "redis":
+		if opts.RedisURL == "" {
+			return nil, fmt.Errorf("the redis session store requires a redis url")
+		}
+		return session.NewRedisStore(opts.RedisURL, 24*time.Hour)
+	default:
+		return nil, fmt.Errorf("unknown session store %q", opts.SessionStore)
+	}
+}
+
+// newKeyring loads the master keys configured in opts, falling back to a key
+// that only lives as long as the process
+func newKeyring(opts Options, logger *slog.Logger) (*secrets.Keyring, error) {
+	switch {
+	case opts.MasterKeyFile != "":
+		return secrets.LoadKeyring(opts.MasterKeyFile)
+	case opts.MasterKeys != "":
+		return secrets.ParseKeyring(opts.MasterKeys)
+	}
+
+	if opts.SessionStore != "" && opts.SessionStore != "memory" {
+		logger.Warn("No master key configured; stored sessions will not survive a restart")
+	}
+	return secrets.NewEphemeralKeyring()
 }
 
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes(frontendFS embed.FS) {
+	s.setupV1Routes()
+
+	// Sign-in endpoints, when users have to sign in
+	if s.authHandler != nil {
+		s.mux.HandleFunc("GET /auth/login", s.authHandler.Login)
+		if s.localUsers != nil {
+			s.mux.HandleFunc("POST /auth/login", s.authHandler.LoginLocal)
+		} else {
+			s.mux.HandleFunc("GET /auth/callback", s.authHandler.Callback)
+		}
+		s.mux.HandleFunc("POST /auth/logout", s.authHandler.Logout)
+	}
+
+	// The unversioned routes below are deprecated aliases of /api/v1
+
 	// Session management endpoints (no auth required)
-	s.mux.HandleFunc("/api/session/status", s.sessionHandler.CheckSession)
-	s.mux.HandleFunc("/api/connect", s.requireMethod(s.sessionHandler.Connect, http.MethodPost))
-	s.mux.HandleFunc("/api/logout", s.requireMethod(s.sessionHandler.Logout, http.MethodPost))
+	s.handleDeprecated("/api/session/status", s.sessionHandler.CheckSession)
+	s.handleDeprecated("/api/session/connections/", s.handleSessionConnectionOperations)
+	s.handleDeprecated("/api/connect", s.requireMethod(s.sessionHandler.Connect, http.MethodPost))
+	s.handleDeprecated("/api/logout", s.requireMethod(s.sessionHandler.Logout, http.MethodPost))
+	s.handleDeprecated("/api/profiles", s.requireMethod(s.sessionHandler.ListProfiles, http.MethodGet))
+	s.handleDeprecated("/api/connections", s.requireMethod(s.sessionHandler.ListConnections, http.MethodGet))
+	s.handleDeprecated("/api/connections/", s.requireMethod(s.sessionHandler.ConnectNamed, http.MethodPost))
 
 	// Protected bucket endpoints
-	s.mux.HandleFunc("/api/buckets", s.requireMethod(s.auth.RequireSession(s.bucketHandler.ListBuckets), http.MethodGet))
-	s.mux.HandleFunc("/api/buckets/", s.handleBucketOperations)
+	s.handleDeprecated("/api/buckets", s.requireMethod(s.auth.RequireSession(s.bucketHandler.ListBuckets), http.MethodGet))
+	s.handleDeprecated("/api/buckets/", s.handleBucketOperations)
 
 	// Protected object endpoints
-	s.mux.HandleFunc("/api/objects", s.requireMethod(s.auth.RequireSession(s.objectHandler.ListObjects), http.MethodGet))
-	s.mux.HandleFunc("/api/objects/", s.handleObjectOperations)
-	s.mux.HandleFunc("/api/presigned-url", s.requireMethod(s.auth.RequireSession(s.objectHandler.GetPresignedURL), http.MethodGet))
+	s.handleDeprecated("/api/objects", s.requireMethod(s.auth.RequireSession(s.objectHandler.ListObjects), http.MethodGet))
+	s.handleDeprecated("/api/objects/", s.handleObjectOperations)
+	s.handleDeprecated("/api/presigned-url", s.requireMethod(s.auth.RequireSession(s.objectHandler.GetPresignedURL), http.MethodGet))
+
+	// Protected background operation endpoints
+	s.handleDeprecated("/api/operations", s.requireMethod(s.auth.RequireSession(s.operationHandler.ListOperations), http.MethodGet))
+	s.handleDeprecated("/api/operations/", s.handleOperationOperations)
+	s.handleDeprecated("/api/transfers", s.requireMethod(s.auth.RequireSession(s.transferHandler.StartTransfer), http.MethodPost))
+	s.handleDeprecated("/api/syncs", s.requireMethod(s.auth.RequireSession(s.syncHandler.PlanSync), http.MethodPost))
+	s.handleDeprecated("/api/syncs/", s.handleSyncOperations)
+	s.handleDeprecated("/api/compare", s.requireMethod(s.auth.RequireSession(s.compareHandler.Compare), http.MethodGet))
+	s.handleDeprecated("/api/mirror", s.requireMethod(s.auth.RequireSession(s.mirrorHandler.StartMirror), http.MethodPost))
+	s.handleDeprecated("/api/mirror/roots", s.requireMethod(s.auth.RequireSession(s.mirrorHandler.ListMirrorRoots), http.MethodGet))
+
+	// Protected key index endpoints
+	s.handleDeprecated("/api/index", s.requireMethod(s.auth.RequireSession(s.indexHandler.ListIndexes), http.MethodGet))
+	s.handleDeprecated("/api/index/", s.handleIndexOperations)
 
 	// Swagger documentation
 	s.mux.Handle("/api/swagger/", httpSwagger.WrapHandler)
@@ -70,27 +312,181 @@ func (s *Server) setupRoutes(frontendFS embed.FS) {
 	s.setupFrontendRoutes(frontendFS)
 }
 
+// setupV1Routes configures the versioned API. Buckets and keys are path
+// segments, lists are paginated and every error is a JSON models.ErrorResponse.
+func (s *Server) setupV1Routes() {
+	v1 := http.NewServeMux()
+
+	// Session management endpoints (no auth required)
+	v1.HandleFunc("GET /api/v1/session", s.sessionHandler.CheckSession)
+	v1.HandleFunc("POST /api/v1/session", s.sessionHandler.Connect)
+	v1.HandleFunc("DELETE /api/v1/session", s.sessionHandler.Logout)
+	v1.HandleFunc("POST /api/v1/session/connections/{id}/activate", s.sessionHandler.ActivateConnection)
+	v1.HandleFunc("DELETE /api/v1/session/connections/{id}", s.sessionHandler.DisconnectConnection)
+	v1.HandleFunc("GET /api/v1/profiles", s.sessionHandler.ListProfiles)
+	v1.HandleFunc("GET /api/v1/connections", s.sessionHandler.ListConnections)
+	v1.HandleFunc("POST /api/v1/connections/{name}/connect", s.sessionHandler.ConnectNamed)
+
+	// Personal API token endpoints
+	v1.HandleFunc("GET /api/v1/tokens", s.auth.RequireSession(s.tokenHandler.ListTokens))
+	v1.HandleFunc("POST /api/v1/tokens", s.auth.RequireSession(s.tokenHandler.CreateToken))
+	v1.HandleFunc("DELETE /api/v1/tokens/{id}", s.auth.RequireSession(s.tokenHandler.RevokeToken))
+
+	// Protected bucket endpoints
+	v1.HandleFunc("GET /api/v1/buckets", s.auth.RequireSession(s.bucketHandler.ListBucketsV1))
+	v1.HandleFunc("PUT /api/v1/buckets/{bucket}", s.auth.RequireWritableSession(s.bucketHandler.CreateBucket))
+	v1.HandleFunc("DELETE /api/v1/buckets/{bucket}", s.auth.RequireWritableSession(s.bucketHandler.DeleteBucket))
+	v1.HandleFunc("GET /api/v1/buckets/{bucket}/stats", s.auth.RequireSession(s.statsHandler.GetBucketStats))
+	v1.HandleFunc("GET /api/v1/buckets/{bucket}/du", s.auth.RequireSession(s.statsHandler.GetDiskUsage))
+	v1.HandleFunc("GET /api/v1/buckets/{bucket}/search", s.auth.RequireSession(s.searchHandler.Search))
+	v1.HandleFunc("PUT /api/v1/buckets/{bucket}/index", s.auth.RequireSession(s.indexHandler.RefreshIndex))
+	v1.HandleFunc("DELETE /api/v1/buckets/{bucket}/index", s.auth.RequireSession(s.indexHandler.DeleteIndex))
+
+	// Protected object endpoints
+	v1.HandleFunc("GET /api/v1/buckets/{bucket}/objects", s.auth.RequireSession(s.objectHandler.ListObjectsV1))
+	v1.HandleFunc("GET /api/v1/buckets/{bucket}/objects/{key...}", s.auth.RequireSession(s.objectHandler.ViewObject))
+	v1.HandleFunc("PUT /api/v1/buckets/{bucket}/objects/{key...}", s.auth.RequireWritableSession(s.objectHandler.PutObject))
+	v1.HandleFunc("DELETE /api/v1/buckets/{bucket}/objects/{key...}", s.auth.RequireWritableSession(s.objectHandler.DeleteObject))
+	v1.HandleFunc("GET /api/v1/buckets/{bucket}/presigned-urls/{key...}", s.auth.RequireSession(s.objectHandler.GetPresignedURL))
+
+	// Protected background operation endpoints
+	v1.HandleFunc("GET /api/v1/operations", s.auth.RequireSession(s.operationHandler.ListOperations))
+	v1.HandleFunc("GET /api/v1/operations/{id}", s.auth.RequireSession(s.operationHandler.GetOperation))
+	v1.HandleFunc("DELETE /api/v1/operations/{id}", s.auth.RequireSession(s.operationHandler.CancelOperation))
+	v1.HandleFunc("POST /api/v1/operations/{id}/resume", s.auth.RequireSession(s.operationHandler.ResumeOperation))
+	v1.HandleFunc("POST /api/v1/transfers", s.auth.RequireSession(s.transferHandler.StartTransfer))
+	v1.HandleFunc("POST /api/v1/syncs", s.auth.RequireSession(s.syncHandler.PlanSync))
+	v1.HandleFunc("GET /api/v1/syncs/{id}", s.auth.RequireSession(s.syncHandler.GetSync))
+	v1.HandleFunc("DELETE /api/v1/syncs/{id}", s.auth.RequireSession(s.syncHandler.DiscardSync))
+	v1.HandleFunc("POST /api/v1/syncs/{id}/execute", s.auth.RequireSession(s.syncHandler.ExecuteSync))
+	v1.HandleFunc("GET /api/v1/compare", s.auth.RequireSession(s.compareHandler.Compare))
+	v1.HandleFunc("GET /api/v1/mirror-roots", s.auth.RequireSession(s.mirrorHandler.ListMirrorRoots))
+	v1.HandleFunc("POST /api/v1/mirrors", s.auth.RequireSession(s.mirrorHandler.StartMirror))
+
+	// Protected key index endpoints
+	v1.HandleFunc("GET /api/v1/indexes", s.auth.RequireSession(s.indexHandler.ListIndexes))
+
+	s.mux.Handle(middleware.APIv1Prefix, middleware.JSONErrors(v1))
+}
+
+// Handler returns the server's routes, behind sign-in when it is required
+func (s *Server) Handler() http.Handler {
+	if s.users == nil {
+		return s.mux
+	}
+	return s.auth.RequireUser(s.users, "/auth/login", s.mux)
+}
+
+// handleDeprecated registers a route of the unversioned API
+func (s *Server) handleDeprecated(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, middleware.Deprecated(handler))
+}
+
+// handleSessionConnectionOperations handles switching between and removing the
+// connections of a browser session
+func (s *Server) handleSessionConnectionOperations(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/activate") {
+		s.requireMethod(s.sessionHandler.ActivateConnection, http.MethodPost)(w, r)
+		return
+	}
+	s.requireMethod(s.sessionHandler.DisconnectConnection, http.MethodDelete)(w, r)
+}
+
 // handleBucketOperations handles bucket operations based on HTTP method
 func (s *Server) handleBucketOperations(w http.ResponseWriter, r *http.Request) {
+	switch bucketSubresource(r.URL.Path) {
+	case "":
+	case "stats":
+		s.requireMethod(s.auth.RequireSession(s.statsHandler.GetBucketStats), http.MethodGet)(w, r)
+		return
+	case "du":
+		s.requireMethod(s.auth.RequireSession(s.statsHandler.GetDiskUsage), http.MethodGet)(w, r)
+		return
+	case "search":
+		s.requireMethod(s.auth.RequireSession(s.searchHandler.Search), http.MethodGet)(w, r)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodPut:
-		s.auth.RequireSession(s.bucketHandler.CreateBucket)(w, r)
+		s.auth.RequireWritableSession(s.bucketHandler.CreateBucket)(w, r)
 	case http.MethodDelete:
-		s.auth.RequireSession(s.bucketHandler.DeleteBucket)(w, r)
+		s.auth.RequireWritableSession(s.bucketHandler.DeleteBucket)(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// bucketSubresource returns the sub-resource of a path like "/api/buckets/{name}/{subresource}",
+// or an empty string for the bucket itself
+func bucketSubresource(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 4 {
+		return parts[3]
+	}
+	return ""
+}
+
 // handleObjectOperations handles object operations based on HTTP method
 func (s *Server) handleObjectOperations(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		s.auth.RequireSession(s.objectHandler.ViewObject)(w, r)
 	case http.MethodPost:
-		s.auth.RequireSession(s.objectHandler.UploadObject)(w, r)
+		s.auth.RequireWritableSession(s.objectHandler.UploadObject)(w, r)
+	case http.MethodDelete:
+		s.auth.RequireWritableSession(s.objectHandler.DeleteObject)(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOperationOperations handles background operation queries based on HTTP method
+func (s *Server) handleOperationOperations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.auth.RequireSession(s.operationHandler.GetOperation)(w, r)
+	case http.MethodPost:
+		if !strings.HasSuffix(r.URL.Path, "/resume") {
+			http.NotFound(w, r)
+			return
+		}
+		s.auth.RequireSession(s.operationHandler.ResumeOperation)(w, r)
+	case http.MethodDelete:
+		s.auth.RequireSession(s.operationHandler.CancelOperation)(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSyncOperations handles sync plans based on HTTP method
+func (s *Server) handleSyncOperations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.auth.RequireSession(s.syncHandler.GetSync)(w, r)
+	case http.MethodPost:
+		if !strings.HasSuffix(r.URL.Path, "/execute") {
+			http.NotFound(w, r)
+			return
+		}
+		s.auth.RequireSession(s.syncHandler.ExecuteSync)(w, r)
+	case http.MethodDelete:
+		s.auth.RequireSession(s.syncHandler.DiscardSync)(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleIndexOperations handles key index operations based on HTTP method
+func (s *Server) handleIndexOperations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		s.auth.RequireSession(s.indexHandler.RefreshIndex)(w, r)
 	case http.MethodDelete:
-		s.auth.RequireSession(s.objectHandler.DeleteObject)(w, r)
+		s.auth.RequireSession(s.indexHandler.DeleteIndex)(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -159,10 +555,16 @@ func (s *Server) requireMethod(handler http.HandlerFunc, allowedMethods ...strin
 func (s *Server) Start(ctx context.Context, addr string) error {
 	// Start session cleanup routine
 	s.sessionManager.StartCleanupRoutine(ctx)
+	defer s.sessionManager.Close()
+	s.operationManager.StartCleanupRoutine(ctx)
+	s.indexer.StartRefreshRoutine(ctx)
+	s.localUsers.StartReloadRoutine(ctx)
+	defer s.indexer.Close()
+	defer s.mirrorRoots.Close()
 
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      s.mux,
+		Handler:      s.Handler(),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// defaultRoleSessionName identifies the browser in CloudTrail when the user gives no session name
+const defaultRoleSessionName = "s3-browser"
+
+// validateAssumeRole checks the AssumeRole parameters of a connection request
+func validateAssumeRole(req *models.AssumeRoleRequest) error {
+	if req.RoleARN == "" {
+		return errors.New("role_arn is required to assume a role")
+	}
+	if req.DurationSeconds != 0 && (req.DurationSeconds < 900 || req.DurationSeconds > 43200) {
+		return errors.New("duration_seconds must be between 900 and 43200")
+	}
+	if (req.MFASerial == "") != (req.MFAToken == "") {
+		return errors.New("mfa_serial and mfa_token must be given together")
+	}
+	return nil
+}
+
+// assumedRole returns the parameters of req that are stored with the session
+func assumedRole(req *models.AssumeRoleRequest, useSSL bool) *models.AssumedRole {
+	role := &models.AssumedRole{
+		RoleARN:         req.RoleARN,
+		SessionName:     req.SessionName,
+		DurationSeconds: req.DurationSeconds,
+	}
+	if role.SessionName == "" {
+		role.SessionName = defaultRoleSessionName
+	}
+	if req.STSEndpoint != "" {
+		role.STSEndpoint = withScheme(req.STSEndpoint, useSSL)
+	}
+	return role
+}
+
+// newSTSClient builds an STS client that signs with the base credentials of
+// a session and shares the TLS and proxy settings of its S3 client
+func newSTSClient(ctx context.Context, session *models.Session, base aws.CredentialsProvider, httpClient aws.HTTPClient) (*sts.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(session.Region),
+		config.WithCredentialsProvider(base),
+		config.WithHTTPClient(httpClient),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load STS configuration: %w", err)
+	}
+
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if session.AssumeRole.STSEndpoint != "" {
+			o.BaseEndpoint = aws.String(session.AssumeRole.STSEndpoint)
+		}
+	}), nil
+}
+
+// assumeRoleProvider returns credentials for the session's role that are
+// refreshed through STS shortly before they expire
+func assumeRoleProvider(ctx context.Context, session *models.Session, sessionSecrets models.SessionSecrets, httpClient aws.HTTPClient) (aws.CredentialsProvider, error) {
+	base, err := baseCredentials(ctx, session, sessionSecrets)
+	if err != nil {
+		return nil, err
+	}
+	stsClient, err := newSTSClient(ctx, session, base, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	role := session.AssumeRole
+	provider := stscreds.NewAssumeRoleProvider(stsClient, role.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = role.SessionName
+		if sessionSecrets.ExternalID != "" {
+			o.ExternalID = aws.String(sessionSecrets.ExternalID)
+		}
+		if role.DurationSeconds > 0 {
+			o.Duration = time.Duration(role.DurationSeconds) * time.Second
+		}
+	})
+	return aws.NewCredentialsCache(provider), nil
+}
+
+// assumeRoleWithMFA calls AssumeRole once with a one-time MFA code. The
+// result cannot be refreshed, so the session expires with it.
+func assumeRoleWithMFA(ctx context.Context, session *models.Session, sessionSecrets models.SessionSecrets, req *models.AssumeRoleRequest, httpClient aws.HTTPClient) (*models.RoleCredentials, *time.Time, error) {
+	base, err := baseCredentials(ctx, session, sessionSecrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	stsClient, err := newSTSClient(ctx, session, base, httpClient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(session.AssumeRole.RoleARN),
+		RoleSessionName: aws.String(session.AssumeRole.SessionName),
+		SerialNumber:    aws.String(req.MFASerial),
+		TokenCode:       aws.String(req.MFAToken),
+	}
+	if req.ExternalID != "" {
+		input.ExternalId = aws.String(req.ExternalID)
+	}
+	if req.DurationSeconds > 0 {
+		input.DurationSeconds = aws.Int32(req.DurationSeconds)
+	}
+
+	output, err := stsClient.AssumeRole(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to assume role: %w", err)
+	}
+	if output.Credentials == nil {
+		return nil, nil, errors.New("failed to assume role: STS returned no credentials")
+	}
+
+	roleCredentials := &models.RoleCredentials{
+		AccessKeyID:     aws.ToString(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(output.Credentials.SessionToken),
+	}
+	return roleCredentials, output.Credentials.Expiration, nil
+}
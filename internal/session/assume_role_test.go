@@ -0,0 +1,185 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// fakeSTS answers AssumeRole calls with numbered credentials, or with the
+// error code in fail, and records the calls it got
+type fakeSTS struct {
+	fail  string
+	mu    sync.Mutex
+	calls []url.Values
+	auth  []string
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, r.PostForm)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	n := len(f.calls)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/xml")
+	if f.fail != "" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>not allowed</Message></Error><RequestId>r%d</RequestId></ErrorResponse>`, f.fail, n)
+		return
+	}
+	fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials>`+
+		`<AccessKeyId>ASIAROLE%d</AccessKeyId><SecretAccessKey>role-secret</SecretAccessKey><SessionToken>role-token</SessionToken>`+
+		`<Expiration>%s</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`,
+		n, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+}
+
+// roleSession returns a session with base credentials that assumes a role
+// through the STS at endpoint, and the HTTP client it is built with
+func roleSession(t *testing.T, req *models.AssumeRoleRequest, endpoint string, secrets models.SessionSecrets) (*models.Session, aws.HTTPClient) {
+	t.Helper()
+	req.STSEndpoint = endpoint
+	session := &models.Session{
+		Region:     "us-east-1",
+		AccessKey:  "AKIABASE",
+		AssumeRole: assumedRole(req, false),
+	}
+	httpClient, err := newHTTPClient(session, secrets)
+	if err != nil {
+		t.Fatalf("newHTTPClient: %v", err)
+	}
+	return session, httpClient
+}
+
+func TestValidateAssumeRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.AssumeRoleRequest
+		wantErr bool
+	}{
+		{"role only", models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/r"}, false},
+		{"no role", models.AssumeRoleRequest{}, true},
+		{"duration in range", models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/r", DurationSeconds: 900}, false},
+		{"duration too short", models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/r", DurationSeconds: 899}, true},
+		{"duration too long", models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/r", DurationSeconds: 43201}, true},
+		{"mfa", models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/r", MFASerial: "arn:aws:iam::1:mfa/u", MFAToken: "123456"}, false},
+		{"mfa serial without token", models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/r", MFASerial: "arn:aws:iam::1:mfa/u"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAssumeRole(&tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("validateAssumeRole() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssumeRoleProvider(t *testing.T) {
+	sts := &fakeSTS{}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	secrets := models.SessionSecrets{SecretKey: "base-secret", ExternalID: "ext-1"}
+	session, httpClient := roleSession(t, &models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/reader", DurationSeconds: 900}, server.URL, secrets)
+	provider, err := assumeRoleProvider(context.Background(), session, secrets, httpClient)
+	if err != nil {
+		t.Fatalf("assumeRoleProvider: %v", err)
+	}
+
+	creds, err := provider.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if creds.AccessKeyID != "ASIAROLE1" || creds.SessionToken != "role-token" || !creds.CanExpire {
+		t.Errorf("Retrieve = %+v, want the expiring role credentials", creds)
+	}
+	// Cached credentials are not fetched again until they are about to expire
+	if _, err := provider.Retrieve(context.Background()); err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+
+	if len(sts.calls) != 1 {
+		t.Fatalf("STS got %d calls, want 1", len(sts.calls))
+	}
+	call := sts.calls[0]
+	for field, want := range map[string]string{
+		"Action":          "AssumeRole",
+		"RoleArn":         "arn:aws:iam::1:role/reader",
+		"RoleSessionName": defaultRoleSessionName,
+		"ExternalId":      "ext-1",
+		"DurationSeconds": "900",
+	} {
+		if got := call.Get(field); got != want {
+			t.Errorf("AssumeRole %s = %q, want %q", field, got, want)
+		}
+	}
+	if !strings.Contains(sts.auth[0], "Credential=AKIABASE/") {
+		t.Errorf("AssumeRole was not signed with the base credentials: %q", sts.auth[0])
+	}
+}
+
+func TestAssumeRoleWithMFA(t *testing.T) {
+	sts := &fakeSTS{}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	req := &models.AssumeRoleRequest{
+		RoleARN:     "arn:aws:iam::1:role/admin",
+		SessionName: "alice",
+		MFASerial:   "arn:aws:iam::1:mfa/alice",
+		MFAToken:    "123456",
+	}
+	secrets := models.SessionSecrets{SecretKey: "base-secret"}
+	session, httpClient := roleSession(t, req, server.URL, secrets)
+	creds, expiration, err := assumeRoleWithMFA(context.Background(), session, secrets, req, httpClient)
+	if err != nil {
+		t.Fatalf("assumeRoleWithMFA: %v", err)
+	}
+	if creds.AccessKeyID != "ASIAROLE1" || creds.SecretAccessKey != "role-secret" || creds.SessionToken != "role-token" {
+		t.Errorf("assumeRoleWithMFA credentials = %+v", creds)
+	}
+	if expiration == nil || time.Until(*expiration) < 50*time.Minute {
+		t.Errorf("assumeRoleWithMFA expiration = %v, want the one STS returned", expiration)
+	}
+
+	call := sts.calls[0]
+	for field, want := range map[string]string{
+		"RoleSessionName": "alice",
+		"SerialNumber":    "arn:aws:iam::1:mfa/alice",
+		"TokenCode":       "123456",
+	} {
+		if got := call.Get(field); got != want {
+			t.Errorf("AssumeRole %s = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestAssumeRoleWithMFADenied(t *testing.T) {
+	server := httptest.NewServer(&fakeSTS{fail: "AccessDenied"})
+	defer server.Close()
+
+	req := &models.AssumeRoleRequest{RoleARN: "arn:aws:iam::1:role/admin", MFASerial: "arn:aws:iam::1:mfa/alice", MFAToken: "000000"}
+	secrets := models.SessionSecrets{SecretKey: "base-secret"}
+	session, httpClient := roleSession(t, req, server.URL, secrets)
+	_, _, err := assumeRoleWithMFA(context.Background(), session, secrets, req, httpClient)
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+		t.Errorf("assumeRoleWithMFA = %v, want the AccessDenied error of STS", err)
+	}
+}
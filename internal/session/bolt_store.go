@@ -0,0 +1,247 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	sessionsBucket    = []byte("sessions")
+	appSessionsBucket = []byte("app_sessions")
+	// ownersBucket holds a bucket of session IDs for each owner
+	ownersBucket = []byte("owners")
+)
+
+// BoltStore keeps sessions in a bbolt database file so they survive restarts
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the session database at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{sessionsBucket, appSessionsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if tx.Bucket(ownersBucket) != nil {
+			return nil
+		}
+		// Index the sessions stored before there was an index
+		if _, err := tx.CreateBucket(ownersBucket); err != nil {
+			return err
+		}
+		return tx.Bucket(sessionsBucket).ForEach(func(id, raw []byte) error {
+			session := &models.Session{}
+			if err := json.Unmarshal(raw, session); err != nil {
+				return err
+			}
+			return index(tx, id, session)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Get returns the session with the given ID
+func (s *BoltStore) Get(id string) (*models.Session, error) {
+	var session *models.Session
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(sessionsBucket).Get([]byte(id))
+		if raw == nil {
+			return ErrSessionNotFound
+		}
+		session = &models.Session{}
+		return json.Unmarshal(raw, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Put creates or replaces a session
+func (s *BoltStore) Put(session *models.Session) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := unindex(tx, []byte(session.ID)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(sessionsBucket).Put([]byte(session.ID), raw); err != nil {
+			return err
+		}
+		return index(tx, []byte(session.ID), session)
+	})
+}
+
+// Delete removes a session
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := unindex(tx, []byte(id)); err != nil {
+			return err
+		}
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	})
+}
+
+// index adds a session to the sessions of its owner
+func index(tx *bolt.Tx, id []byte, session *models.Session) error {
+	owner := ownerOf(session)
+	if owner == "" {
+		return nil
+	}
+	owned, err := tx.Bucket(ownersBucket).CreateBucketIfNotExists([]byte(owner))
+	if err != nil {
+		return err
+	}
+	return owned.Put(id, nil)
+}
+
+// unindex removes a stored session from the sessions of its owner
+func unindex(tx *bolt.Tx, id []byte) error {
+	raw := tx.Bucket(sessionsBucket).Get(id)
+	if raw == nil {
+		return nil
+	}
+	session := &models.Session{}
+	if err := json.Unmarshal(raw, session); err != nil {
+		return err
+	}
+	owner := []byte(ownerOf(session))
+	owned := tx.Bucket(ownersBucket).Bucket(owner)
+	if owned == nil {
+		return nil
+	}
+	if err := owned.Delete(id); err != nil {
+		return err
+	}
+	if k, _ := owned.Cursor().First(); k == nil {
+		return tx.Bucket(ownersBucket).DeleteBucket(owner)
+	}
+	return nil
+}
+
+// List returns every stored session
+func (s *BoltStore) List() ([]*models.Session, error) {
+	sessions := make([]*models.Session, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(_, raw []byte) error {
+			session := &models.Session{}
+			if err := json.Unmarshal(raw, session); err != nil {
+				return err
+			}
+			sessions = append(sessions, session)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListOwned returns the sessions of owner
+func (s *BoltStore) ListOwned(owner string) ([]*models.Session, error) {
+	sessions := make([]*models.Session, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		owned := tx.Bucket(ownersBucket).Bucket([]byte(owner))
+		if owned == nil {
+			return nil
+		}
+		stored := tx.Bucket(sessionsBucket)
+		return owned.ForEach(func(id, _ []byte) error {
+			session := &models.Session{}
+			if err := json.Unmarshal(stored.Get(id), session); err != nil {
+				return err
+			}
+			sessions = append(sessions, session)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetAppSession returns the app session with the given ID
+func (s *BoltStore) GetAppSession(id string) (*models.AppSession, error) {
+	var session *models.AppSession
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(appSessionsBucket).Get([]byte(id))
+		if raw == nil {
+			return identity.ErrAppSessionNotFound
+		}
+		session = &models.AppSession{}
+		return json.Unmarshal(raw, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// PutAppSession creates or replaces an app session
+func (s *BoltStore) PutAppSession(id string, session *models.AppSession) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(appSessionsBucket).Put([]byte(id), raw)
+	})
+}
+
+// DeleteAppSession removes an app session
+func (s *BoltStore) DeleteAppSession(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(appSessionsBucket).Delete([]byte(id))
+	})
+}
+
+// ListAppSessions returns every stored app session by ID
+func (s *BoltStore) ListAppSessions() (map[string]*models.AppSession, error) {
+	sessions := make(map[string]*models.AppSession)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(appSessionsBucket).ForEach(func(id, raw []byte) error {
+			session := &models.AppSession{}
+			if err := json.Unmarshal(raw, session); err != nil {
+				return err
+			}
+			sessions[string(id)] = session
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Close closes the database file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
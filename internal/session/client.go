@@ -0,0 +1,133 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/secrets"
+)
+
+// expiredTokenCodes are the error codes S3-compatible services return for
+// temporary credentials that are past their expiration
+var expiredTokenCodes = map[string]bool{
+	"ExpiredToken":          true,
+	"ExpiredTokenException": true,
+	"TokenRefreshRequired":  true,
+}
+
+// IsExpiredTokenError reports whether err means the credentials have expired
+func IsExpiredTokenError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && expiredTokenCodes[apiErr.ErrorCode()]
+}
+
+// newS3Client builds an S3 client from the connection parameters of a
+// session. This is the only place where stored secrets are decrypted.
+// A readOnly client refuses every operation that could change data.
+// onExpired is called whenever S3 rejects the credentials as expired.
+func newS3Client(ctx context.Context, session *models.Session, keyring *secrets.Keyring, readOnly bool, onExpired func()) (*s3.Client, error) {
+	plaintext, err := keyring.Open(session.ID, session.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session credentials: %w", err)
+	}
+	var sessionSecrets models.SessionSecrets
+	if err := json.Unmarshal(plaintext, &sessionSecrets); err != nil {
+		return nil, fmt.Errorf("failed to decode session credentials: %w", err)
+	}
+
+	httpClient, err := newHTTPClient(session, sessionSecrets)
+	if err != nil {
+		return nil, err
+	}
+	credProvider, err := credentialsProvider(ctx, session, sessionSecrets, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(session.Region),
+		config.WithCredentialsProvider(credProvider),
+		config.WithBaseEndpoint(session.Endpoint),
+		config.WithHTTPClient(httpClient),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = !session.VirtualHosted
+		o.DisableLogOutputChecksumValidationSkipped = true
+		o.APIOptions = append(o.APIOptions, detectExpiredToken(onExpired))
+		if len(session.Buckets) > 0 {
+			o.APIOptions = append(o.APIOptions, scopeGuard(session.Buckets, session.Prefix))
+		}
+		if readOnly {
+			o.APIOptions = append(o.APIOptions, readOnlyGuard)
+		}
+	})
+	return client, nil
+}
+
+// credentialsProvider picks how the S3 client of a session authenticates
+func credentialsProvider(ctx context.Context, session *models.Session, sessionSecrets models.SessionSecrets, httpClient aws.HTTPClient) (aws.CredentialsProvider, error) {
+	switch {
+	case sessionSecrets.RoleCredentials != nil:
+		role := sessionSecrets.RoleCredentials
+		return credentials.NewStaticCredentialsProvider(role.AccessKeyID, role.SecretAccessKey, role.SessionToken), nil
+	case session.AssumeRole != nil:
+		return assumeRoleProvider(ctx, session, sessionSecrets, httpClient)
+	default:
+		return baseCredentials(ctx, session, sessionSecrets)
+	}
+}
+
+// baseCredentials returns the credentials the user connected with: none for
+// anonymous sessions, a server-side profile or the keys they entered
+func baseCredentials(ctx context.Context, session *models.Session, sessionSecrets models.SessionSecrets) (aws.CredentialsProvider, error) {
+	if session.Anonymous {
+		return aws.AnonymousCredentials{}, nil
+	}
+	if session.Profile != "" {
+		cfg, err := loadProfileConfig(ctx, session.Profile)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.Credentials, nil
+	}
+	return credentials.NewStaticCredentialsProvider(session.AccessKey, sessionSecrets.SecretKey, sessionSecrets.SessionToken), nil
+}
+
+// withScheme prefixes endpoint with http:// or https:// unless it already has a scheme
+func withScheme(endpoint string, useSSL bool) string {
+	if strings.HasPrefix(endpoint, "http") {
+		return endpoint
+	}
+	if useSSL {
+		return "https://" + endpoint
+	}
+	return "http://" + endpoint
+}
+
+// detectExpiredToken adds a middleware that reports expired credentials
+// after the SDK has given up retrying an operation
+func detectExpiredToken(onExpired func()) func(stack *middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("DetectExpiredToken",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				if err != nil && onExpired != nil && IsExpiredTokenError(err) {
+					onExpired()
+				}
+				return out, metadata, err
+			}), middleware.Before)
+	}
+}
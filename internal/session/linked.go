@@ -0,0 +1,113 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// ErrUnknownSessionConnection is returned for connection IDs that do not
+// belong to the browser session
+var ErrUnknownSessionConnection = errors.New("no such connection in this session")
+
+// Resolve returns the connection of the browser session owner that a request
+// works with: the one named by id, or the active connection when id is empty.
+// A browser session is itself its first connection.
+func (sm *Manager) Resolve(owner *models.Session, id string) (*models.Session, error) {
+	if id == "" {
+		if owner.ActiveConnection == "" {
+			return owner, nil
+		}
+		if session := sm.linkedSession(owner, owner.ActiveConnection); session != nil {
+			return session, nil
+		}
+		// The active connection was disconnected or has expired
+		return owner, nil
+	}
+
+	if id == owner.ID {
+		return owner, nil
+	}
+	if session := sm.linkedSession(owner, id); session != nil {
+		return session, nil
+	}
+	return nil, ErrUnknownSessionConnection
+}
+
+// Activate makes the connection id the one requests of owner use by default
+func (sm *Manager) Activate(owner *models.Session, id string) error {
+	session, err := sm.Resolve(owner, id)
+	if err != nil {
+		return err
+	}
+
+	owner.ActiveConnection = ""
+	if session.ID != owner.ID {
+		owner.ActiveConnection = session.ID
+	}
+	if err := sm.store.Put(owner); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// Disconnect removes a connection that was added to the browser session owner.
+// The browser session itself ends with a logout instead.
+func (sm *Manager) Disconnect(owner *models.Session, id string) error {
+	session := sm.linkedSession(owner, id)
+	if session == nil {
+		return ErrUnknownSessionConnection
+	}
+
+	sm.deleteSession(session.ID)
+	if owner.ActiveConnection == session.ID {
+		owner.ActiveConnection = ""
+		if err := sm.store.Put(owner); err != nil {
+			return fmt.Errorf("failed to store session: %w", err)
+		}
+	}
+	sm.logger.Info("Connection removed from session",
+		slog.String("session_id", owner.ID),
+		slog.String("connection_id", session.ID))
+	return nil
+}
+
+// SessionConnections lists the connections of the browser session owner,
+// starting with the browser session itself
+func (sm *Manager) SessionConnections(owner *models.Session) []*models.Session {
+	return append([]*models.Session{owner}, sm.linkedSessions(owner.ID)...)
+}
+
+// linkedSession returns the connection id if it was added to owner
+func (sm *Manager) linkedSession(owner *models.Session, id string) *models.Session {
+	session := sm.GetSession(id)
+	if session == nil || session.Owner != owner.ID {
+		return nil
+	}
+	return session
+}
+
+// linkedSessions returns the connections added to the browser session
+// ownerID, oldest first
+func (sm *Manager) linkedSessions(ownerID string) []*models.Session {
+	sessions, err := sm.store.ListOwned(ownerID)
+	if err != nil {
+		sm.logger.Error("Failed to list sessions", slog.String("error", err.Error()))
+		return nil
+	}
+
+	var linked []*models.Session
+	for _, session := range sessions {
+		// Tokens created in the browser session are listed with its connections
+		if session.Owner == ownerID {
+			linked = append(linked, session)
+		}
+	}
+	sort.Slice(linked, func(i, j int) bool {
+		return linked[i].CreatedAt.Before(linked[j].CreatedAt)
+	})
+	return linked
+}
@@ -2,72 +2,123 @@ package session
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/cksidharthan/s3-browser/internal/connections"
+	"github.com/cksidharthan/s3-browser/internal/identity"
 	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/secrets"
 	"github.com/google/uuid"
 )
 
+// sessionTTL is how long an unused session stays valid
+const sessionTTL = 24 * time.Hour
+
+var (
+	// ErrAdHocDisabled is returned when the operator only allows named connections
+	ErrAdHocDisabled = errors.New("connecting with your own endpoint and keys is disabled; pick one of the configured connections")
+	// ErrUnknownConnection is returned for names that are not in the connection file
+	ErrUnknownConnection = errors.New("unknown connection")
+)
+
 // Manager manages user sessions
 type Manager struct {
-	sessions map[string]*models.Session
-	mu       sync.RWMutex
-	logger   *slog.Logger
+	store           Store
+	keyring         *secrets.Keyring
+	allowedProfiles []string
+	connections     *connections.Config
+	readOnly        bool
+	clients         map[string]*s3.Client
+	mu              sync.RWMutex
+	logger          *slog.Logger
 }
 
-// New creates a new session manager
-func New(logger *slog.Logger) *Manager {
+// New creates a new session manager backed by store. Session credentials are
+// encrypted with keyring before they reach the store. allowedProfiles are the
+// server-side AWS profiles users may connect with instead of pasting keys;
+// conns are the operator-defined connections and may be nil. readOnly makes
+// every session read-only, including ones stored before it was set.
+func New(store Store, keyring *secrets.Keyring, allowedProfiles []string, conns *connections.Config, readOnly bool, logger *slog.Logger) *Manager {
 	return &Manager{
-		sessions: make(map[string]*models.Session),
-		logger:   logger,
+		store:           store,
+		keyring:         keyring,
+		allowedProfiles: allowedProfiles,
+		connections:     conns,
+		readOnly:        readOnly,
+		clients:         make(map[string]*s3.Client),
+		logger:          logger,
 	}
 }
 
-// CreateSession creates a new session with the given connection parameters
-func (sm *Manager) CreateSession(ctx context.Context, connReq models.ConnectionRequest) (*models.Session, error) {
-	// Fix endpoint URL if needed
-	endpoint := connReq.Endpoint
-	if !strings.HasPrefix(endpoint, "http") {
-		if connReq.UseSSL {
-			endpoint = "https://" + endpoint
-		} else {
-			endpoint = "http://" + endpoint
-		}
-	}
-
-	// Create AWS config
-	credProvider := credentials.NewStaticCredentialsProvider(connReq.AccessKey, connReq.SecretKey, "")
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(connReq.Region),
-		config.WithCredentialsProvider(credProvider),
-		config.WithBaseEndpoint(endpoint),
-	)
+// CreateSession creates a new session with connection parameters entered by the
+// user. With an owner the session becomes another connection of that browser
+// session instead of a browser session of its own.
+func (sm *Manager) CreateSession(ctx context.Context, connReq models.ConnectionRequest, owner string) (*models.Session, error) {
+	if !sm.connections.AdHocAllowed() {
+		return nil, ErrAdHocDisabled
+	}
+	if connReq.Profile != "" && !sm.profileAllowed(connReq.Profile) {
+		return nil, fmt.Errorf("profile %q is not available", connReq.Profile)
+	}
+	return sm.createSession(ctx, connReq, nil, owner)
+}
+
+// ConnectNamed creates a new session for the operator-defined connection called
+// name, optionally as another connection of the browser session owner. A
+// signed-in user in ctx may only use the connections allowed to them.
+func (sm *Manager) ConnectNamed(ctx context.Context, name, owner string) (*models.Session, error) {
+	conn, ok := sm.connections.Get(name)
+	if !ok || !identity.MayUse(identity.UserFromContext(ctx), name) {
+		return nil, ErrUnknownConnection
+	}
+
+	connReq, err := conn.Request()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load configuration: %w", err)
+		return nil, err
 	}
+	return sm.createSession(ctx, connReq, conn, owner)
+}
 
-	// Create S3 client
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = true
-		o.DisableLogOutputChecksumValidationSkipped = true
-	})
+// AdHocAllowed reports whether users may connect with endpoints and keys of their own
+func (sm *Manager) AdHocAllowed() bool {
+	return sm.connections.AdHocAllowed()
+}
 
-	// Test the connection with timeout context
-	testCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+// IsReadOnly reports whether session may not change anything in S3, because it
+// was connected read-only or because the whole server is
+func (sm *Manager) IsReadOnly(session *models.Session) bool {
+	return sm.readOnly || session.ReadOnly
+}
 
-	_, err = client.ListBuckets(testCtx, &s3.ListBucketsInput{})
-	if err != nil {
-		return nil, fmt.Errorf("connection test failed: %w", err)
+// Connections lists the operator-defined connections user may use; a nil
+// user, i.e. no sign-in, may use all of them
+func (sm *Manager) Connections(user *models.User) []models.ConnectionInfo {
+	infos := make([]models.ConnectionInfo, 0)
+	for _, info := range sm.connections.List() {
+		if identity.MayUse(user, info.Name) {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// createSession tests connReq and stores a session for it. conn carries the
+// operator settings when the session is opened from a named connection.
+func (sm *Manager) createSession(ctx context.Context, connReq models.ConnectionRequest, conn *connections.Connection, owner string) (*models.Session, error) {
+	// Fix endpoint URL if needed
+	endpoint := withScheme(connReq.Endpoint, connReq.UseSSL)
+
+	if connReq.Expiration != nil && time.Now().After(*connReq.Expiration) {
+		return nil, fmt.Errorf("the temporary credentials expired at %s", connReq.Expiration.Format(time.RFC3339))
 	}
 
 	// Create session
@@ -75,66 +126,355 @@ func (sm *Manager) CreateSession(ctx context.Context, connReq models.ConnectionR
 	session := &models.Session{
 		ID:        sessionID,
 		Endpoint:  endpoint,
-		AccessKey: connReq.AccessKey,
-		SecretKey: connReq.SecretKey,
 		Region:    connReq.Region,
 		UseSSL:    connReq.UseSSL,
-		S3Client:  client,
 		CreatedAt: time.Now(),
 		LastUsed:  time.Now(),
+		ExpiresAt: connReq.Expiration,
+		ReadOnly:  connReq.ReadOnly,
+		Owner:     owner,
+	}
+	if user := identity.UserFromContext(ctx); user != nil {
+		session.User = user.ID
+	}
+
+	if conn != nil {
+		session.Connection = conn.Name
+	}
+
+	session.VirtualHosted = connReq.PathStyle != nil && !*connReq.PathStyle
+	session.InsecureSkipVerify = connReq.InsecureSkipVerify
+	session.CACertPEM = connReq.CACertPEM
+	if connReq.CACertFile != "" {
+		// Only operators may point at files on the server
+		if conn == nil {
+			return nil, errors.New("ca_cert_file can only be used in the connections file; upload the PEM instead")
+		}
+		if connReq.CACertPEM != "" {
+			return nil, errors.New("set either ca_cert_pem or ca_cert_file, not both")
+		}
+		pem, err := readCACertFile(connReq.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		session.CACertPEM = pem
 	}
 
+	session.Buckets = declaredBuckets(connReq.Buckets)
+	session.Prefix = connReq.Prefix
+	if session.Prefix != "" && len(session.Buckets) == 0 {
+		return nil, errors.New("a prefix can only be used together with buckets")
+	}
+
+	sessionSecrets := models.SessionSecrets{ProxyURL: connReq.ProxyURL}
+	switch {
+	case connReq.Anonymous:
+		if len(session.Buckets) == 0 {
+			return nil, errors.New("anonymous connections must name the buckets to browse")
+		}
+		if connReq.AssumeRole != nil {
+			return nil, errors.New("anonymous connections cannot assume a role")
+		}
+		session.Anonymous = true
+	case connReq.Profile != "":
+		// Server-side credentials never leave the server, so nothing is sealed for them
+		session.Profile = connReq.Profile
+		if session.Region == "" {
+			cfg, err := loadProfileConfig(ctx, connReq.Profile)
+			if err != nil {
+				return nil, err
+			}
+			session.Region = cfg.Region
+		}
+		if session.Region == "" {
+			return nil, fmt.Errorf("profile %q has no region; please enter one", connReq.Profile)
+		}
+	default:
+		session.AccessKey = connReq.AccessKey
+		sessionSecrets.SecretKey = connReq.SecretKey
+		sessionSecrets.SessionToken = connReq.SessionToken
+	}
+
+	// Check the TLS and proxy settings before anything is sent with them
+	httpClient, err := newHTTPClient(session, sessionSecrets)
+	if err != nil {
+		return nil, err
+	}
+	if session.InsecureSkipVerify {
+		sm.logger.Warn("TLS CERTIFICATE VERIFICATION IS DISABLED for this session; credentials and data can be intercepted",
+			slog.String("session_id", sessionID),
+			slog.String("endpoint", endpoint))
+	}
+	if roleReq := connReq.AssumeRole; roleReq != nil {
+		if err := validateAssumeRole(roleReq); err != nil {
+			return nil, err
+		}
+		session.AssumeRole = assumedRole(roleReq, connReq.UseSSL)
+		sessionSecrets.ExternalID = roleReq.ExternalID
+
+		// MFA codes are single-use, so the role can only be assumed once up front
+		if roleReq.MFASerial != "" {
+			roleCredentials, expiration, err := assumeRoleWithMFA(ctx, session, sessionSecrets, roleReq, httpClient)
+			if err != nil {
+				return nil, err
+			}
+			sessionSecrets.RoleCredentials = roleCredentials
+			session.ExpiresAt = expiration
+		}
+	}
+
+	// Encrypt credentials before they are kept anywhere
+	plaintext, err := json.Marshal(sessionSecrets)
+	if err != nil {
+		return nil, err
+	}
+	session.Credentials, err = sm.keyring.Seal(session.ID, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+
+	// Create S3 client
+	client, err := newS3Client(ctx, session, sm.keyring, sm.IsReadOnly(session), sm.expiredCallback(sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Test the connection with timeout context
+	testCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if len(session.Buckets) > 0 {
+		err = testScope(testCtx, client, session.Buckets, session.Prefix)
+	} else {
+		_, err = client.ListBuckets(testCtx, &s3.ListBucketsInput{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connection test failed: %w", err)
+	}
+
+	if err := sm.store.Put(session); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	session.S3Client = client
+
 	sm.mu.Lock()
-	sm.sessions[sessionID] = session
+	sm.clients[sessionID] = client
 	sm.mu.Unlock()
 
 	sm.logger.Info("Session created", slog.String("session_id", sessionID))
 	return session, nil
 }
 
-// GetSession retrieves a session by ID
+// GetSession retrieves a session by ID, rebuilding its S3 client if this
+// process has not built one yet (e.g. after a restart)
 func (sm *Manager) GetSession(sessionID string) *models.Session {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	session, err := sm.store.Get(sessionID)
+	if err != nil {
+		if !errors.Is(err, ErrSessionNotFound) {
+			sm.logger.Error("Failed to load session",
+				slog.String("session_id", sessionID),
+				slog.String("error", err.Error()))
+		}
+		return nil
+	}
 
-	session, exists := sm.sessions[sessionID]
-	if !exists {
+	if expired(session, time.Now()) {
+		sm.DeleteSession(sessionID)
 		return nil
 	}
 
-	// Update last used time
+	// Update last used time, writing it back at most once a minute
+	previous := session.LastUsed
 	session.LastUsed = time.Now()
+	if session.LastUsed.Sub(previous) > time.Minute {
+		if err := sm.store.Put(session); err != nil {
+			sm.logger.Warn("Failed to update session",
+				slog.String("session_id", sessionID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	client, err := sm.client(session)
+	if err != nil {
+		sm.logger.Error("Failed to rebuild S3 client",
+			slog.String("session_id", sessionID),
+			slog.String("error", err.Error()))
+		return nil
+	}
+	session.S3Client = client
 	return session
 }
 
-// GetSessionFromCookie gets session from HTTP cookie
+// expired reports whether a session has run out: an API token at its expiry,
+// any other session once it has not been used for sessionTTL
+func expired(session *models.Session, now time.Time) bool {
+	if session.Token != nil {
+		return now.After(session.Token.ExpiresAt)
+	}
+	return now.Sub(session.LastUsed) > sessionTTL
+}
+
+// client returns the cached S3 client of a session, building it on first use
+func (sm *Manager) client(session *models.Session) (*s3.Client, error) {
+	sm.mu.RLock()
+	client, exists := sm.clients[session.ID]
+	sm.mu.RUnlock()
+	if exists {
+		return client, nil
+	}
+
+	client, err := newS3Client(context.Background(), session, sm.keyring, sm.IsReadOnly(session), sm.expiredCallback(session.ID))
+	if err != nil {
+		return nil, err
+	}
+	if session.InsecureSkipVerify {
+		sm.logger.Warn("TLS CERTIFICATE VERIFICATION IS DISABLED for this session; credentials and data can be intercepted",
+			slog.String("session_id", session.ID),
+			slog.String("endpoint", session.Endpoint))
+	}
+
+	sm.mu.Lock()
+	sm.clients[session.ID] = client
+	sm.mu.Unlock()
+	return client, nil
+}
+
+// declaredBuckets trims and de-duplicates the bucket names given when connecting
+func declaredBuckets(names []string) []string {
+	var buckets []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(buckets, name) {
+			buckets = append(buckets, name)
+		}
+	}
+	return buckets
+}
+
+// expiredCallback returns the hook that flags a session once S3 reports its credentials as expired
+func (sm *Manager) expiredCallback(sessionID string) func() {
+	return func() {
+		session, err := sm.store.Get(sessionID)
+		if err != nil || session.CredentialsExpired {
+			return
+		}
+
+		session.CredentialsExpired = true
+		if err := sm.store.Put(session); err != nil {
+			sm.logger.Error("Failed to flag expired credentials",
+				slog.String("session_id", sessionID),
+				slog.String("error", err.Error()))
+			return
+		}
+		sm.logger.Warn("Session credentials expired", slog.String("session_id", sessionID))
+	}
+}
+
+// HasSession reports whether a session exists without marking it as used
+func (sm *Manager) HasSession(sessionID string) bool {
+	_, err := sm.store.Get(sessionID)
+	return err == nil
+}
+
+// GetSessionFromCookie gets the browser session from HTTP cookie. When users
+// have to sign in, only the user who opened the session gets it.
 func (sm *Manager) GetSessionFromCookie(r *http.Request) *models.Session {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
 		return nil
 	}
-	return sm.GetSession(cookie.Value)
+	session := sm.GetSession(cookie.Value)
+	if session == nil || session.Owner != "" || session.Token != nil {
+		// Connections added to a browser session are only reachable through
+		// it, and API tokens only with their secret
+		return nil
+	}
+	if user := identity.UserFromContext(r.Context()); user != nil && session.User != user.ID {
+		return nil
+	}
+	return session
 }
 
-// DeleteSession removes a session by ID
+// DeleteSession removes a session by ID, together with the connections added to it
 func (sm *Manager) DeleteSession(sessionID string) {
+	for _, linked := range sm.linkedSessions(sessionID) {
+		sm.deleteSession(linked.ID)
+	}
+	sm.deleteSession(sessionID)
+}
+
+// deleteSession removes a single session and its cached client
+func (sm *Manager) deleteSession(sessionID string) {
+	if err := sm.store.Delete(sessionID); err != nil {
+		sm.logger.Error("Failed to delete session",
+			slog.String("session_id", sessionID),
+			slog.String("error", err.Error()))
+	}
+
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
-	delete(sm.sessions, sessionID)
+	delete(sm.clients, sessionID)
+	sm.mu.Unlock()
 }
 
-// CleanupExpiredSessions removes sessions older than 24 hours
+// CleanupExpiredSessions removes sessions unused for 24 hours and expired API tokens
 func (sm *Manager) CleanupExpiredSessions() {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
+	sessions, err := sm.store.List()
+	if err != nil {
+		sm.logger.Error("Failed to list sessions", slog.String("error", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	for _, session := range sessions {
+		if expired(session, now) {
+			sm.DeleteSession(session.ID)
+			sm.logger.Info("Session expired and removed", slog.String("session_id", session.ID))
+		} else if session.Owner != "" && !sm.HasSession(session.Owner) {
+			sm.deleteSession(session.ID)
+			sm.logger.Info("Connection of an ended browser session removed", slog.String("session_id", session.ID))
+		}
+	}
+}
 
-	expiry := time.Now().Add(-24 * time.Hour)
-	for id, session := range sm.sessions {
-		if session.LastUsed.Before(expiry) {
-			delete(sm.sessions, id)
-			sm.logger.Info("Session expired and removed", slog.String("session_id", id))
+// RotateKeys re-encrypts the credentials of stored sessions that are not yet
+// under the primary master key. Sessions none of the configured keys can
+// open are left in place, in case the key was only missing from this start,
+// unless purge is set; it returns how many were re-encrypted and how many
+// could not be opened.
+func (sm *Manager) RotateKeys(purge bool) (rotated, unreadable int, err error) {
+	sessions, err := sm.store.List()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		changed, err := sm.keyring.Rewrap(session.ID, session.Credentials)
+		if err != nil {
+			unreadable++
+			sm.logger.Warn("Stored session has credentials the master keys cannot open",
+				slog.String("session_id", session.ID),
+				slog.String("error", err.Error()))
+			if purge {
+				if err := sm.store.Delete(session.ID); err != nil {
+					return rotated, unreadable, fmt.Errorf("failed to delete session %s: %w", session.ID, err)
+				}
+			}
+			continue
 		}
+		if !changed {
+			continue
+		}
+		if err := sm.store.Put(session); err != nil {
+			return rotated, unreadable, fmt.Errorf("failed to store re-encrypted session: %w", err)
+		}
+		rotated++
 	}
+	return rotated, unreadable, nil
+}
+
+// Close releases the session store
+func (sm *Manager) Close() error {
+	return sm.store.Close()
 }
 
 // StartCleanupRoutine starts a background routine to clean up expired sessions
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/secrets"
+)
+
+// failingDelete is a store that cannot delete sessions
+type failingDelete struct {
+	Store
+}
+
+func (failingDelete) Delete(sessionID string) error {
+	return errors.New("store unavailable")
+}
+
+func TestRotateKeys(t *testing.T) {
+	old, err := secrets.NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	current, err := secrets.NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	store := NewMemoryStore()
+	for id, keyring := range map[string]*secrets.Keyring{"current": current, "lost": old} {
+		sealed, err := keyring.Seal(id, []byte("{}"))
+		if err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		if err := store.Put(&models.Session{ID: id, Credentials: sealed}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	// A session sealed with a key this start does not have is kept
+	rotated, unreadable, err := New(store, current, nil, nil, false, logger).RotateKeys(false)
+	if err != nil || rotated != 0 || unreadable != 1 {
+		t.Fatalf("RotateKeys = %d, %d, %v, want 0, 1, nil", rotated, unreadable, err)
+	}
+	if _, err := store.Get("lost"); err != nil {
+		t.Fatalf("Get of the unreadable session = %v, want it left in place", err)
+	}
+
+	if _, _, err := New(failingDelete{store}, current, nil, nil, false, logger).RotateKeys(true); err == nil {
+		t.Error("RotateKeys purging through a failing store succeeded")
+	}
+
+	rotated, unreadable, err = New(store, current, nil, nil, false, logger).RotateKeys(true)
+	if err != nil || rotated != 0 || unreadable != 1 {
+		t.Fatalf("RotateKeys purging = %d, %d, %v, want 0, 1, nil", rotated, unreadable, err)
+	}
+	if _, err := store.Get("lost"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get of the purged session = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := store.Get("current"); err != nil {
+		t.Errorf("Get of the readable session = %v", err)
+	}
+}
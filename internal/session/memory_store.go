@@ -0,0 +1,157 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// MemoryStore keeps sessions in a map; they are lost when the process exits.
+// Like the persistent stores it hands out copies, so requests using the same
+// session never share one.
+type MemoryStore struct {
+	sessions map[string]*models.Session
+	// owned holds the IDs of the sessions of each owner
+	owned       map[string]map[string]bool
+	appSessions map[string]*models.AppSession
+	mu          sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:    make(map[string]*models.Session),
+		owned:       make(map[string]map[string]bool),
+		appSessions: make(map[string]*models.AppSession),
+	}
+}
+
+// Get returns the session with the given ID
+func (s *MemoryStore) Get(id string) (*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[id]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+// Put creates or replaces a session
+func (s *MemoryStore) Put(session *models.Session) error {
+	stored := *session
+	// S3 clients are not persisted by any store
+	stored.S3Client = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unindex(session.ID)
+	s.sessions[session.ID] = &stored
+	if owner := ownerOf(&stored); owner != "" {
+		if s.owned[owner] == nil {
+			s.owned[owner] = make(map[string]bool)
+		}
+		s.owned[owner][session.ID] = true
+	}
+	return nil
+}
+
+// Delete removes a session
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unindex(id)
+	delete(s.sessions, id)
+	return nil
+}
+
+// unindex removes a stored session from the sessions of its owner
+func (s *MemoryStore) unindex(id string) {
+	session, exists := s.sessions[id]
+	if !exists {
+		return
+	}
+	owner := ownerOf(session)
+	delete(s.owned[owner], id)
+	if len(s.owned[owner]) == 0 {
+		delete(s.owned, owner)
+	}
+}
+
+// List returns every stored session
+func (s *MemoryStore) List() ([]*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]*models.Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		copied := *session
+		sessions = append(sessions, &copied)
+	}
+	return sessions, nil
+}
+
+// ListOwned returns the sessions of owner
+func (s *MemoryStore) ListOwned(owner string) ([]*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]*models.Session, 0, len(s.owned[owner]))
+	for id := range s.owned[owner] {
+		copied := *s.sessions[id]
+		sessions = append(sessions, &copied)
+	}
+	return sessions, nil
+}
+
+// GetAppSession returns the app session with the given ID
+func (s *MemoryStore) GetAppSession(id string) (*models.AppSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.appSessions[id]
+	if !exists {
+		return nil, identity.ErrAppSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+// PutAppSession creates or replaces an app session
+func (s *MemoryStore) PutAppSession(id string, session *models.AppSession) error {
+	stored := *session
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appSessions[id] = &stored
+	return nil
+}
+
+// DeleteAppSession removes an app session
+func (s *MemoryStore) DeleteAppSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.appSessions, id)
+	return nil
+}
+
+// ListAppSessions returns every stored app session by ID
+func (s *MemoryStore) ListAppSessions() (map[string]*models.AppSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make(map[string]*models.AppSession, len(s.appSessions))
+	for id, session := range s.appSessions {
+		copied := *session
+		sessions[id] = &copied
+	}
+	return sessions, nil
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
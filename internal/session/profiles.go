@@ -0,0 +1,58 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// DefaultChainProfile selects the SDK default credential chain: environment
+// variables, the shared config (AWS_PROFILE or the default profile), web
+// identity token files, credential_process and instance or task roles
+const DefaultChainProfile = "default"
+
+// loadProfileConfig loads the server-side configuration of profile
+func loadProfileConfig(ctx context.Context, profile string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if profile != DefaultChainProfile {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load profile %q: %w", profile, err)
+	}
+	return cfg, nil
+}
+
+// profileAllowed reports whether the admin offers profile to users
+func (sm *Manager) profileAllowed(profile string) bool {
+	return slices.Contains(sm.allowedProfiles, profile)
+}
+
+// Profiles lists the allowed server-side profiles that can be loaded
+func (sm *Manager) Profiles(ctx context.Context) []models.Profile {
+	profiles := make([]models.Profile, 0, len(sm.allowedProfiles))
+	for _, name := range sm.allowedProfiles {
+		profile := models.Profile{
+			Name:         name,
+			DefaultChain: name == DefaultChainProfile,
+		}
+
+		cfg, err := loadProfileConfig(ctx, name)
+		if err != nil {
+			sm.logger.Debug("Skipping unavailable profile",
+				slog.String("profile", name),
+				slog.String("error", err.Error()))
+			continue
+		}
+		profile.Region = cfg.Region
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
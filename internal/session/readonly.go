@@ -0,0 +1,74 @@
+package session
+
+import (
+	"context"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// readOperations are the S3 operations that never change data. Anything not
+// listed, including operations added to the SDK later, is refused.
+var readOperations = map[string]bool{
+	"GetBucketAccelerateConfiguration":           true,
+	"GetBucketAcl":                               true,
+	"GetBucketAnalyticsConfiguration":            true,
+	"GetBucketCors":                              true,
+	"GetBucketEncryption":                        true,
+	"GetBucketIntelligentTieringConfiguration":   true,
+	"GetBucketInventoryConfiguration":            true,
+	"GetBucketLifecycleConfiguration":            true,
+	"GetBucketLocation":                          true,
+	"GetBucketLogging":                           true,
+	"GetBucketMetadataConfiguration":             true,
+	"GetBucketMetadataTableConfiguration":        true,
+	"GetBucketMetricsConfiguration":              true,
+	"GetBucketNotificationConfiguration":         true,
+	"GetBucketOwnershipControls":                 true,
+	"GetBucketPolicy":                            true,
+	"GetBucketPolicyStatus":                      true,
+	"GetBucketReplication":                       true,
+	"GetBucketRequestPayment":                    true,
+	"GetBucketTagging":                           true,
+	"GetBucketVersioning":                        true,
+	"GetBucketWebsite":                           true,
+	"GetObject":                                  true,
+	"GetObjectAcl":                               true,
+	"GetObjectAttributes":                        true,
+	"GetObjectLegalHold":                         true,
+	"GetObjectLockConfiguration":                 true,
+	"GetObjectRetention":                         true,
+	"GetObjectTagging":                           true,
+	"GetObjectTorrent":                           true,
+	"GetPublicAccessBlock":                       true,
+	"HeadBucket":                                 true,
+	"HeadObject":                                 true,
+	"ListBucketAnalyticsConfigurations":          true,
+	"ListBucketIntelligentTieringConfigurations": true,
+	"ListBucketInventoryConfigurations":          true,
+	"ListBucketMetricsConfigurations":            true,
+	"ListBuckets":                                true,
+	"ListDirectoryBuckets":                       true,
+	"ListMultipartUploads":                       true,
+	"ListObjectVersions":                         true,
+	"ListObjects":                                true,
+	"ListObjectsV2":                              true,
+	"ListParts":                                  true,
+}
+
+// readOnlyGuard rejects every S3 operation that could change data before it
+// is sent. Routes already refuse read-only sessions; this also covers
+// background operations and anything added later.
+func readOnlyGuard(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ReadOnlyGuard",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			if operation := middleware.GetOperationName(ctx); !readOperations[operation] {
+				return middleware.InitializeOutput{}, middleware.Metadata{}, &smithy.GenericAPIError{
+					Code:    "AccessDenied",
+					Message: operation + " is not allowed on a read-only connection",
+					Fault:   smithy.FaultClient,
+				}
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.Before)
+}
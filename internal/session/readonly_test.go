@@ -0,0 +1,112 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// guardedClient returns a client that goes through guard to an endpoint
+// answering every request with an empty success, counting the requests
+func guardedClient(t *testing.T, guard func(stack *middleware.Stack) error) (*s3.Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte("<ListBucketResult></ListBucketResult>"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIAEXAMPLE", "secret", ""),
+		Retryer:      aws.NopRetryer{},
+		APIOptions:   []func(*middleware.Stack) error{guard},
+	}), &requests
+}
+
+func TestReadOnlyGuard(t *testing.T) {
+	client, requests := guardedClient(t, readOnlyGuard)
+	ctx := context.Background()
+	bucket, key := aws.String("logs"), aws.String("app.log")
+
+	writes := map[string]func() error{
+		"PutObject": func() error {
+			_, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: bucket, Key: key, Body: strings.NewReader("data")})
+			return err
+		},
+		"DeleteObject": func() error {
+			_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: bucket, Key: key})
+			return err
+		},
+		"DeleteObjects": func() error {
+			_, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{Bucket: bucket, Delete: &types.Delete{Objects: []types.ObjectIdentifier{{Key: key}}}})
+			return err
+		},
+		"CopyObject": func() error {
+			_, err := client.CopyObject(ctx, &s3.CopyObjectInput{Bucket: bucket, Key: aws.String("copy.log"), CopySource: aws.String("logs/app.log")})
+			return err
+		},
+		"CreateMultipartUpload": func() error {
+			_, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: bucket, Key: key})
+			return err
+		},
+		"UploadPart": func() error {
+			_, err := client.UploadPart(ctx, &s3.UploadPartInput{Bucket: bucket, Key: key, UploadId: aws.String("upload"), PartNumber: aws.Int32(1), Body: strings.NewReader("data")})
+			return err
+		},
+		"CompleteMultipartUpload": func() error {
+			_, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{Bucket: bucket, Key: key, UploadId: aws.String("upload")})
+			return err
+		},
+		"AbortMultipartUpload": func() error {
+			_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{Bucket: bucket, Key: key, UploadId: aws.String("upload")})
+			return err
+		},
+		"PutObjectTagging": func() error {
+			_, err := client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{Bucket: bucket, Key: key, Tagging: &types.Tagging{}})
+			return err
+		},
+		"RestoreObject": func() error {
+			_, err := client.RestoreObject(ctx, &s3.RestoreObjectInput{Bucket: bucket, Key: key})
+			return err
+		},
+	}
+	for name, call := range writes {
+		t.Run(name, func(t *testing.T) {
+			var apiErr smithy.APIError
+			if err := call(); !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" || !strings.Contains(apiErr.ErrorMessage(), name) {
+				t.Errorf("%s on a read-only client = %v, want AccessDenied", name, err)
+			}
+		})
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d refused calls reached the endpoint", n)
+	}
+
+	if _, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: bucket}); err != nil {
+		t.Errorf("ListObjectsV2 on a read-only client = %v", err)
+	}
+	if _, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: bucket, Key: key}); err != nil {
+		t.Errorf("HeadObject on a read-only client = %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d reads reached the endpoint, want 2", n)
+	}
+}
@@ -0,0 +1,255 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Key prefixes namespace session keys in a shared Redis database
+const (
+	redisKeyPrefix           = "s3-browser:session:"
+	redisOwnerKeyPrefix      = "s3-browser:owner:"
+	redisAppSessionKeyPrefix = "s3-browser:app-session:"
+)
+
+// redisPut stores a session and adds it to the set of its owner, which is
+// kept for as long as its longest-lived session
+var redisPut = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+if KEYS[2] then
+	redis.call("SADD", KEYS[2], ARGV[3])
+	if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[2], ARGV[2])
+	end
+end
+return 1
+`)
+
+// RedisStore keeps sessions in Redis or any server speaking its protocol
+// (Valkey, KeyDB, Dragonfly, miniredis). Keys expire on their own after ttl.
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisStore connects to the server at url, e.g. "redis://localhost:6379/0"
+func NewRedisStore(url string, ttl time.Duration) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisStore{client: client, ttl: ttl}, nil
+}
+
+// Get returns the session with the given ID
+func (s *RedisStore) Get(id string) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	raw, err := s.client.Get(ctx, redisKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{}
+	if err := json.Unmarshal(raw, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Put creates or replaces a session and restarts its expiry. API tokens
+// expire when the token does instead.
+func (s *RedisStore) Put(session *models.Session) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ttl := s.ttl
+	if session.Token != nil {
+		ttl = max(time.Until(session.Token.ExpiresAt), time.Second)
+	}
+
+	keys := []string{redisKeyPrefix + session.ID}
+	if owner := ownerOf(session); owner != "" {
+		keys = append(keys, redisOwnerKeyPrefix+owner)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return redisPut.Run(ctx, s.client, keys, raw, ttl.Milliseconds(), session.ID).Err()
+}
+
+// Delete removes a session. It stays in the set of its owner until
+// ListOwned finds it gone.
+func (s *RedisStore) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.client.Del(ctx, redisKeyPrefix+id).Err()
+}
+
+// List returns every stored session
+func (s *RedisStore) List() ([]*models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sessions := make([]*models.Session, 0)
+	iter := s.client.Scan(ctx, 0, redisKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		raw, err := s.client.Get(ctx, iter.Val()).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// Expired between SCAN and GET
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		session := &models.Session{}
+		if err := json.Unmarshal(raw, session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListOwned returns the sessions of owner, dropping those that were deleted
+// or expired from its set
+func (s *RedisStore) ListOwned(owner string) ([]*models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ids, err := s.client.SMembers(ctx, redisOwnerKeyPrefix+owner).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisKeyPrefix + id
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.Session, 0, len(ids))
+	var gone []any
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			gone = append(gone, ids[i])
+			continue
+		}
+		session := &models.Session{}
+		if err := json.Unmarshal([]byte(raw), session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if len(gone) > 0 {
+		if err := s.client.SRem(ctx, redisOwnerKeyPrefix+owner, gone...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+// GetAppSession returns the app session with the given ID
+func (s *RedisStore) GetAppSession(id string) (*models.AppSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	raw, err := s.client.Get(ctx, redisAppSessionKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, identity.ErrAppSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.AppSession{}
+	if err := json.Unmarshal(raw, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// PutAppSession creates or replaces an app session, which expires when the
+// sign-in does
+func (s *RedisStore) PutAppSession(id string, session *models.AppSession) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ttl := max(time.Until(session.User.ExpiresAt), time.Second)
+	return s.client.Set(ctx, redisAppSessionKeyPrefix+id, raw, ttl).Err()
+}
+
+// DeleteAppSession removes an app session
+func (s *RedisStore) DeleteAppSession(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.client.Del(ctx, redisAppSessionKeyPrefix+id).Err()
+}
+
+// ListAppSessions returns every stored app session by ID
+func (s *RedisStore) ListAppSessions() (map[string]*models.AppSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sessions := make(map[string]*models.AppSession)
+	iter := s.client.Scan(ctx, 0, redisAppSessionKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		raw, err := s.client.Get(ctx, iter.Val()).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// Expired between SCAN and GET
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		session := &models.AppSession{}
+		if err := json.Unmarshal(raw, session); err != nil {
+			return nil, err
+		}
+		sessions[strings.TrimPrefix(iter.Val(), redisAppSessionKeyPrefix)] = session
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Close closes the connection pool
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// testScope checks that the session can reach each of its declared buckets.
+// HeadBucket needs access to the whole bucket, so prefix-scoped sessions list
+// their prefix instead.
+func testScope(ctx context.Context, client *s3.Client, buckets []string, prefix string) error {
+	for _, bucket := range buckets {
+		var err error
+		if prefix == "" {
+			_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+		} else {
+			_, err = client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+				Bucket:  aws.String(bucket),
+				Prefix:  aws.String(prefix),
+				MaxKeys: aws.Int32(1),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("bucket %s: %w", bucket, err)
+		}
+	}
+	return nil
+}
+
+// scopeGuard adds a middleware that rejects calls outside the declared
+// buckets and prefix before they are sent, so a scoped session behaves the
+// same whatever the credentials would allow
+func scopeGuard(buckets []string, prefix string) func(stack *middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ScopeGuard",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if err := checkScope(in.Parameters, buckets, prefix); err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, err
+				}
+				return next.HandleInitialize(ctx, in)
+			}), middleware.Before)
+	}
+}
+
+// checkScope inspects the Bucket, Key and Prefix fields shared by the S3
+// operation inputs, plus the keys of batch deletes and copy sources
+func checkScope(params any, buckets []string, prefix string) error {
+	switch input := params.(type) {
+	case *s3.DeleteObjectsInput:
+		if input.Delete != nil {
+			for _, obj := range input.Delete.Objects {
+				if !strings.HasPrefix(aws.ToString(obj.Key), prefix) {
+					return outOfScope("key " + aws.ToString(obj.Key))
+				}
+			}
+		}
+	case *s3.CopyObjectInput:
+		if err := checkCopySource(aws.ToString(input.CopySource), buckets, prefix); err != nil {
+			return err
+		}
+	case *s3.UploadPartCopyInput:
+		if err := checkCopySource(aws.ToString(input.CopySource), buckets, prefix); err != nil {
+			return err
+		}
+	}
+
+	value := reflect.ValueOf(params)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	value = value.Elem()
+
+	if bucket, ok := stringField(value, "Bucket"); ok && !slices.Contains(buckets, bucket) {
+		return outOfScope("bucket " + bucket)
+	}
+	if prefix == "" {
+		return nil
+	}
+	if key, ok := stringField(value, "Key"); ok && !strings.HasPrefix(key, prefix) {
+		return outOfScope("key " + key)
+	}
+	// Listings within a bucket must stay below the prefix; a missing prefix lists everything
+	if value.FieldByName("Bucket").IsValid() && value.FieldByName("Prefix").IsValid() {
+		listPrefix, _ := stringField(value, "Prefix")
+		if !strings.HasPrefix(listPrefix, prefix) {
+			return outOfScope("prefix " + listPrefix)
+		}
+	}
+	return nil
+}
+
+// checkCopySource checks a copy source of the form bucket/key, where the key
+// is URL-encoded and may be followed by ?versionId=
+func checkCopySource(source string, buckets []string, prefix string) error {
+	path, _, _ := strings.Cut(strings.TrimPrefix(source, "/"), "?")
+	path, err := url.PathUnescape(path)
+	if err != nil {
+		return outOfScope("copy source " + source)
+	}
+	sourceBucket, sourceKey, _ := strings.Cut(path, "/")
+	if !slices.Contains(buckets, sourceBucket) || !strings.HasPrefix(sourceKey, prefix) {
+		return outOfScope("copy source " + path)
+	}
+	return nil
+}
+
+// stringField returns the value of a *string field of v, if it is set
+func stringField(v reflect.Value, name string) (string, bool) {
+	field := v.FieldByName(name)
+	if !field.IsValid() || field.Type() != reflect.TypeFor[*string]() || field.IsNil() {
+		return "", false
+	}
+	return field.Elem().String(), true
+}
+
+// outOfScope reports an access outside the session scope the way S3 would
+func outOfScope(what string) error {
+	return &smithy.GenericAPIError{
+		Code:    "AccessDenied",
+		Message: what + " is outside the buckets and prefix this session was connected with",
+		Fault:   smithy.FaultClient,
+	}
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestCheckScope(t *testing.T) {
+	buckets, prefix := []string{"logs", "assets"}, "app/"
+	tests := []struct {
+		name    string
+		params  any
+		inScope bool
+	}{
+		{"object under the prefix", &s3.GetObjectInput{Bucket: aws.String("logs"), Key: aws.String("app/today.log")}, true},
+		{"object in another declared bucket", &s3.HeadObjectInput{Bucket: aws.String("assets"), Key: aws.String("app/logo.png")}, true},
+		{"undeclared bucket", &s3.GetObjectInput{Bucket: aws.String("billing"), Key: aws.String("app/today.log")}, false},
+		{"object outside the prefix", &s3.PutObjectInput{Bucket: aws.String("logs"), Key: aws.String("other/today.log")}, false},
+		{"object named like the prefix", &s3.GetObjectInput{Bucket: aws.String("logs"), Key: aws.String("app")}, false},
+		{"listing under the prefix", &s3.ListObjectsV2Input{Bucket: aws.String("logs"), Prefix: aws.String("app/2024/")}, true},
+		{"listing without a prefix", &s3.ListObjectsV2Input{Bucket: aws.String("logs")}, false},
+		{"listing another prefix", &s3.ListObjectsV2Input{Bucket: aws.String("logs"), Prefix: aws.String("ap")}, false},
+		{"listing versions without a prefix", &s3.ListObjectVersionsInput{Bucket: aws.String("logs")}, false},
+		{"listing buckets", &s3.ListBucketsInput{}, true},
+		{
+			"batch delete under the prefix",
+			&s3.DeleteObjectsInput{Bucket: aws.String("logs"), Delete: &types.Delete{Objects: []types.ObjectIdentifier{{Key: aws.String("app/a")}, {Key: aws.String("app/b")}}}},
+			true,
+		},
+		{
+			"batch delete reaching outside",
+			&s3.DeleteObjectsInput{Bucket: aws.String("logs"), Delete: &types.Delete{Objects: []types.ObjectIdentifier{{Key: aws.String("app/a")}, {Key: aws.String("other/b")}}}},
+			false,
+		},
+		{"copy within the scope", copyFrom("logs/app/today.log"), true},
+		{"copy from a leading slash", copyFrom("/assets/app/logo.png"), true},
+		{"copy of an encoded key", copyFrom("logs/app/caf%C3%A9%20menu.txt"), true},
+		{"copy of a version", copyFrom("logs/app/today.log?versionId=3HL4kqtJlcpXroDTDmJ"), true},
+		{"copy of a key with an encoded question mark", copyFrom("logs/app/what%3FversionId=1"), true},
+		{"copy from an undeclared bucket", copyFrom("billing/app/invoice.pdf"), false},
+		{"copy from outside the prefix", copyFrom("logs/other/secret.txt"), false},
+		{"copy from outside the prefix, encoded", copyFrom("logs%2Fother%2Fsecret.txt"), false},
+		{"copy from an undeclared bucket, encoded", copyFrom("billing%2Fapp%2Finvoice.pdf"), false},
+		{"copy of a version outside the prefix", copyFrom("logs/other/secret.txt?versionId=app/"), false},
+		{"copy of a version from an undeclared bucket", copyFrom("billing/app/invoice.pdf?versionId=1"), false},
+		{"copy with a broken encoding", copyFrom("logs/app/%zz"), false},
+		{"copy into another prefix", &s3.CopyObjectInput{Bucket: aws.String("logs"), Key: aws.String("other/today.log"), CopySource: aws.String("logs/app/today.log")}, false},
+		{
+			"part copied from within the scope",
+			&s3.UploadPartCopyInput{Bucket: aws.String("logs"), Key: aws.String("app/big.bin"), CopySource: aws.String("logs/app/big.bin?versionId=1")},
+			true,
+		},
+		{
+			"part copied from outside, encoded",
+			&s3.UploadPartCopyInput{Bucket: aws.String("logs"), Key: aws.String("app/big.bin"), CopySource: aws.String("logs/other%2Fbig.bin")},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkScope(tt.params, buckets, prefix)
+			if tt.inScope && err != nil {
+				t.Errorf("checkScope = %v, want the call allowed", err)
+			}
+			var apiErr smithy.APIError
+			if !tt.inScope && (!errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied") {
+				t.Errorf("checkScope = %v, want AccessDenied", err)
+			}
+		})
+	}
+}
+
+// copyFrom copies source to a key within the scope
+func copyFrom(source string) *s3.CopyObjectInput {
+	return &s3.CopyObjectInput{Bucket: aws.String("logs"), Key: aws.String("app/copy"), CopySource: aws.String(source)}
+}
+
+func TestCheckScopeBucketsOnly(t *testing.T) {
+	// Without a prefix, any key and listing in a declared bucket is in scope
+	for _, params := range []any{
+		&s3.ListObjectsV2Input{Bucket: aws.String("logs")},
+		&s3.GetObjectInput{Bucket: aws.String("logs"), Key: aws.String("anything")},
+		copyFrom("logs/anything?versionId=1"),
+	} {
+		if err := checkScope(params, []string{"logs"}, ""); err != nil {
+			t.Errorf("checkScope(%T) = %v, want it allowed", params, err)
+		}
+	}
+	if err := checkScope(copyFrom("billing%2Fanything"), []string{"logs"}, ""); err == nil {
+		t.Error("checkScope of a copy from an undeclared bucket succeeded")
+	}
+}
+
+func TestScopeGuard(t *testing.T) {
+	client, requests := guardedClient(t, scopeGuard([]string{"logs"}, "app/"))
+	ctx := context.Background()
+
+	_, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String("logs"),
+		Key:        aws.String("app/copy"),
+		CopySource: aws.String("logs/other%2Fsecret.txt?versionId=1"),
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+		t.Errorf("CopyObject from outside the scope = %v, want AccessDenied", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d calls outside the scope reached the endpoint", n)
+	}
+
+	if _, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String("logs"), Prefix: aws.String("app/")}); err != nil {
+		t.Errorf("ListObjectsV2 within the scope = %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// ErrSessionNotFound is returned by a Store when no session has the requested ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// Store persists sessions. Implementations only need to keep the
+// serializable connection parameters; S3 clients are rebuilt by the Manager.
+type Store interface {
+	// Get returns the session with the given ID or ErrSessionNotFound
+	Get(id string) (*models.Session, error)
+	// Put creates or replaces a session
+	Put(session *models.Session) error
+	// Delete removes a session; deleting a missing session is not an error
+	Delete(id string) error
+	// List returns every stored session
+	List() ([]*models.Session, error)
+	// ListOwned returns the sessions indexed under owner, see ownerOf
+	ListOwned(owner string) ([]*models.Session, error)
+	// Close releases the resources held by the store
+	Close() error
+
+	// The app sessions of signed-in users are kept apart from S3 sessions
+	identity.Store
+}
+
+// ownerOf is what a session is indexed under in its store, so that the
+// sessions of an owner are found without listing every session: the browser
+// session a connection was added to, or the user, else the browser session,
+// that created an API token. Browser sessions themselves have no owner.
+func ownerOf(session *models.Session) string {
+	switch {
+	case session.Token != nil && session.User != "":
+		return session.User
+	case session.Token != nil:
+		return session.Token.Owner
+	default:
+		return session.Owner
+	}
+}
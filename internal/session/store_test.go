@@ -0,0 +1,310 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestStores returns one store of every kind, each empty
+func newTestStores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+
+	redis, err := NewRedisStore("redis://"+miniredis.RunT(t).Addr(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	t.Cleanup(func() { redis.Close() })
+
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+		"redis":  redis,
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Get("missing"); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("Get of a missing session = %v, want ErrSessionNotFound", err)
+			}
+
+			session := &models.Session{
+				ID:          "one",
+				Endpoint:    "https://s3.example.com",
+				AccessKey:   "AKIAEXAMPLE",
+				Credentials: &models.SealedSecret{KeyID: "k1", WrappedKey: []byte("wrapped"), Ciphertext: []byte("sealed")},
+				Region:      "eu-west-1",
+				Buckets:     []string{"logs"},
+				Prefix:      "app/",
+			}
+			if err := store.Put(session); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if err := store.Put(&models.Session{ID: "two", Owner: "one"}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			got, err := store.Get("one")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.Endpoint != session.Endpoint || got.Prefix != "app/" || len(got.Buckets) != 1 ||
+				got.Credentials == nil || string(got.Credentials.Ciphertext) != "sealed" {
+				t.Errorf("Get = %+v, want the session that was put", got)
+			}
+
+			sessions, err := store.List()
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(sessions) != 2 {
+				t.Errorf("List returned %d sessions, want 2", len(sessions))
+			}
+
+			if err := store.Delete("one"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := store.Delete("one"); err != nil {
+				t.Errorf("Delete of a missing session = %v, want nil", err)
+			}
+			if _, err := store.Get("one"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Get after Delete = %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStoreHandsOutCopies(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Put(&models.Session{ID: "one", Region: "eu-west-1"}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			first, err := store.Get("one")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			first.Region = "us-east-1"
+			first.ActiveConnection = "other"
+
+			second, err := store.Get("one")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if second == first || second.Region != "eu-west-1" || second.ActiveConnection != "" {
+				t.Errorf("a change to a session that was not put back is visible: %+v", second)
+			}
+		})
+	}
+}
+
+func TestRedisStoreExpiry(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore("redis://"+server.Addr(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Put(&models.Session{ID: "browser"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	token := &models.Session{ID: "token", Token: &models.TokenGrant{ExpiresAt: time.Now().Add(3 * time.Hour)}}
+	if err := store.Put(token); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if _, err := store.Get("browser"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get of a session past its ttl = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := store.Get("token"); err != nil {
+		t.Errorf("Get of a token session before the token expired = %v", err)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if _, err := store.Get("token"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get of a token session after the token expired = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestStoreAppSessions(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.GetAppSession("missing"); !errors.Is(err, identity.ErrAppSessionNotFound) {
+				t.Fatalf("GetAppSession of a missing app session = %v, want ErrAppSessionNotFound", err)
+			}
+
+			session := &models.AppSession{
+				User:       models.User{ID: "local:alice", Connections: []string{"dev"}, ExpiresAt: time.Now().Add(time.Hour)},
+				Credential: "fingerprint",
+			}
+			if err := store.PutAppSession("app", session); err != nil {
+				t.Fatalf("PutAppSession: %v", err)
+			}
+			// App sessions are kept apart from S3 sessions
+			if err := store.Put(&models.Session{ID: "app"}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			got, err := store.GetAppSession("app")
+			if err != nil {
+				t.Fatalf("GetAppSession: %v", err)
+			}
+			if got.User.ID != "local:alice" || got.Credential != "fingerprint" || len(got.User.Connections) != 1 {
+				t.Errorf("GetAppSession = %+v, want the app session that was put", got)
+			}
+
+			sessions, err := store.ListAppSessions()
+			if err != nil {
+				t.Fatalf("ListAppSessions: %v", err)
+			}
+			if len(sessions) != 1 || sessions["app"] == nil {
+				t.Errorf("ListAppSessions = %v, want the app session by its ID", sessions)
+			}
+
+			if err := store.DeleteAppSession("app"); err != nil {
+				t.Fatalf("DeleteAppSession: %v", err)
+			}
+			if _, err := store.GetAppSession("app"); !errors.Is(err, identity.ErrAppSessionNotFound) {
+				t.Errorf("GetAppSession after DeleteAppSession = %v, want ErrAppSessionNotFound", err)
+			}
+			if _, err := store.Get("app"); err != nil {
+				t.Errorf("Get of the S3 session after DeleteAppSession = %v", err)
+			}
+		})
+	}
+}
+
+// ids returns the IDs of sessions, sorted
+func ids(sessions []*models.Session) []string {
+	var ids []string
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestStoreListOwned(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, session := range []*models.Session{
+				{ID: "browser"},
+				{ID: "linked", Owner: "browser"},
+				{ID: "token", Token: &models.TokenGrant{Owner: "browser", ExpiresAt: time.Now().Add(time.Hour)}},
+				{ID: "user-token", User: "local:alice", Token: &models.TokenGrant{Owner: "browser", ExpiresAt: time.Now().Add(time.Hour)}},
+				{ID: "other", Owner: "other-browser"},
+			} {
+				if err := store.Put(session); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+			}
+
+			owned, err := store.ListOwned("browser")
+			if err != nil {
+				t.Fatalf("ListOwned: %v", err)
+			}
+			if got := ids(owned); !slices.Equal(got, []string{"linked", "token"}) {
+				t.Errorf("ListOwned of the browser session = %v, want [linked token]", got)
+			}
+			if owned, _ := store.ListOwned("local:alice"); !slices.Equal(ids(owned), []string{"user-token"}) {
+				t.Errorf("ListOwned of the user = %v, want [user-token]", ids(owned))
+			}
+
+			// Putting a session again does not list it twice
+			if err := store.Put(&models.Session{ID: "linked", Owner: "browser", Region: "eu-west-1"}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if err := store.Delete("token"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			owned, err = store.ListOwned("browser")
+			if err != nil {
+				t.Fatalf("ListOwned: %v", err)
+			}
+			if len(owned) != 1 || owned[0].ID != "linked" || owned[0].Region != "eu-west-1" {
+				t.Errorf("ListOwned after Put and Delete = %+v, want the updated linked session", owned)
+			}
+			if owned, err := store.ListOwned("nobody"); err != nil || len(owned) != 0 {
+				t.Errorf("ListOwned of an owner without sessions = %v, %v", owned, err)
+			}
+		})
+	}
+}
+
+func TestBoltStoreIndexesExistingSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	if err := store.Put(&models.Session{ID: "linked", Owner: "browser"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// A database written before sessions were indexed by owner
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(ownersBucket)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer store.Close()
+	if owned, err := store.ListOwned("browser"); err != nil || !slices.Equal(ids(owned), []string{"linked"}) {
+		t.Errorf("ListOwned after reopening = %v, %v, want [linked]", ids(owned), err)
+	}
+}
+
+func TestRedisStoreOwnedExpiry(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore("redis://"+server.Addr(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer store.Close()
+
+	long := &models.Session{ID: "long", User: "local:alice", Token: &models.TokenGrant{ExpiresAt: time.Now().Add(3 * time.Hour)}}
+	short := &models.Session{ID: "short", User: "local:alice", Token: &models.TokenGrant{ExpiresAt: time.Now().Add(time.Hour)}}
+	for _, session := range []*models.Session{long, short} {
+		if err := store.Put(session); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	// The set outlives the token that expires first
+	server.FastForward(2 * time.Hour)
+	owned, err := store.ListOwned("local:alice")
+	if err != nil || !slices.Equal(ids(owned), []string{"long"}) {
+		t.Fatalf("ListOwned = %v, %v, want [long]", ids(owned), err)
+	}
+	if members, _ := server.SMembers(redisOwnerKeyPrefix + "local:alice"); !slices.Equal(members, []string{"long"}) {
+		t.Errorf("owner set = %v, want the expired token dropped", members)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if server.Exists(redisOwnerKeyPrefix + "local:alice") {
+		t.Error("the owner set outlived its sessions")
+	}
+}
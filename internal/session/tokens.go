@@ -0,0 +1,224 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/google/uuid"
+)
+
+// tokenPrefix marks personal API tokens so that they are easy to recognise,
+// e.g. by secret scanners. A token is tokenPrefix, the ID of its session, an
+// underscore and the secret.
+const tokenPrefix = "s3b_"
+
+const (
+	// defaultTokenLifetime is how long a token is valid when no expiry is requested
+	defaultTokenLifetime = 30 * 24 * time.Hour
+	// maxTokenLifetime is the furthest away a token may expire
+	maxTokenLifetime = 365 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidToken is returned for bearer tokens that are malformed, unknown, revoked or expired
+	ErrInvalidToken = errors.New("invalid or expired API token")
+	// ErrUnknownToken is returned when revoking a token the caller cannot see
+	ErrUnknownToken = errors.New("unknown API token")
+)
+
+// CreateToken issues a personal API token for the connection of owner named
+// by req.Connection. The token gets a session of its own with a copy of the
+// connection, narrowed to req's scope, that lives until the token expires or
+// is revoked, independently of the browser session. The returned string is
+// the token; only a hash of its secret is stored.
+func (sm *Manager) CreateToken(ctx context.Context, owner *models.Session, req models.APITokenRequest) (*models.Session, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", errors.New("a name is required")
+	}
+	source, err := sm.Resolve(owner, req.Connection)
+	if err != nil {
+		return nil, "", err
+	}
+	if source.CredentialsHaveExpired() {
+		return nil, "", errors.New("the credentials of this connection have expired; reconnect first")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultTokenLifetime)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, "", errors.New("expires_at must be in the future")
+		}
+		if req.ExpiresAt.Sub(now) > maxTokenLifetime {
+			return nil, "", errors.New("tokens can be valid for at most a year")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	// A token cannot outlive the temporary credentials it uses
+	if source.ExpiresAt != nil && source.ExpiresAt.Before(expiresAt) {
+		expiresAt = *source.ExpiresAt
+	}
+
+	buckets := declaredBuckets(req.Buckets)
+	if len(source.Buckets) > 0 {
+		for _, bucket := range buckets {
+			if !slices.Contains(source.Buckets, bucket) {
+				return nil, "", fmt.Errorf("bucket %q is outside the buckets of the connection", bucket)
+			}
+		}
+		if len(buckets) == 0 {
+			buckets = slices.Clone(source.Buckets)
+		}
+	}
+
+	secret := rand.Text()
+	token := *source
+	token.ID = uuid.New().String()
+	token.S3Client = nil
+	token.CreatedAt = now
+	token.LastUsed = now
+	token.Owner = ""
+	token.ActiveConnection = ""
+	token.ReadOnly = source.ReadOnly || req.ReadOnly
+	token.Buckets = buckets
+	token.Token = &models.TokenGrant{Name: name, SecretHash: hashTokenSecret(secret), ExpiresAt: expiresAt, Owner: owner.ID}
+	// Credentials are sealed for the session they are kept in
+	plaintext, err := sm.keyring.Open(source.ID, source.Credentials)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt session credentials: %w", err)
+	}
+	token.Credentials, err = sm.keyring.Seal(token.ID, plaintext)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+
+	client, err := newS3Client(ctx, &token, sm.keyring, sm.IsReadOnly(&token), sm.expiredCallback(token.ID))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(buckets) > 0 {
+		testCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := testScope(testCtx, client, buckets, token.Prefix); err != nil {
+			return nil, "", fmt.Errorf("token scope test failed: %w", err)
+		}
+	}
+
+	if err := sm.store.Put(&token); err != nil {
+		return nil, "", fmt.Errorf("failed to store token: %w", err)
+	}
+	token.S3Client = client
+
+	sm.mu.Lock()
+	sm.clients[token.ID] = client
+	sm.mu.Unlock()
+
+	sm.logger.Info("API token created",
+		slog.String("token_id", token.ID),
+		slog.String("connection_id", source.ID),
+		slog.String("principal", token.Principal()),
+		slog.Time("expires_at", expiresAt))
+	return &token, tokenPrefix + token.ID + "_" + secret, nil
+}
+
+// AuthenticateToken returns the session of a bearer token
+func (sm *Manager) AuthenticateToken(raw string) (*models.Session, error) {
+	rest, ok := strings.CutPrefix(raw, tokenPrefix)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	// Check the secret before GetSession marks the session as used
+	stored, err := sm.store.Get(id)
+	if err != nil || stored.Token == nil ||
+		subtle.ConstantTimeCompare([]byte(hashTokenSecret(secret)), []byte(stored.Token.SecretHash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	session := sm.GetSession(id)
+	if session == nil {
+		return nil, ErrInvalidToken
+	}
+	return session, nil
+}
+
+// Tokens lists the API tokens owner may see and revoke, oldest first: those
+// its signed-in user created, or without sign-in those created in owner itself
+func (sm *Manager) Tokens(owner *models.Session) ([]*models.Session, error) {
+	sessions, err := sm.store.ListOwned(owner.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if owner.User != "" {
+		created, err := sm.store.ListOwned(owner.User)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		sessions = append(sessions, created...)
+	}
+
+	var tokens []*models.Session
+	for _, session := range sessions {
+		if session.Token == nil || expired(session, time.Now()) {
+			continue
+		}
+		if ownsToken(owner, session) {
+			tokens = append(tokens, session)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// RevokeToken deletes one of the tokens listed by Tokens
+func (sm *Manager) RevokeToken(owner *models.Session, id string) error {
+	tokens, err := sm.Tokens(owner)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if token.ID == id {
+			sm.deleteSession(id)
+			sm.logger.Info("API token revoked",
+				slog.String("token_id", id),
+				slog.String("session_id", owner.ID))
+			return nil
+		}
+	}
+	return ErrUnknownToken
+}
+
+// ownsToken reports whether the browser session owner created token, or its
+// signed-in user did in any browser session. Sessions that merely share the
+// endpoint and principal, such as a named connection, own nothing.
+func ownsToken(owner, token *models.Session) bool {
+	if token.User != "" {
+		return token.User == owner.User
+	}
+	return token.Token.Owner == owner.ID
+}
+
+// hashTokenSecret hashes the secret part of a token for storage. The secret
+// is random, so a fast hash is as good as a password hash here.
+func hashTokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// newHTTPClient builds the HTTP client used for S3 and STS calls of a
+// session, applying its custom CA, TLS verification and proxy settings
+func newHTTPClient(session *models.Session, sessionSecrets models.SessionSecrets) (*awshttp.BuildableClient, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if session.CACertPEM != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(session.CACertPEM)) {
+			return nil, errors.New("the CA bundle contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if session.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if sessionSecrets.ProxyURL != "" {
+		proxyURL, err := parseProxyURL(sessionSecrets.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	return awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		tr.TLSClientConfig = tlsConfig
+		tr.Proxy = proxy
+	}), nil
+}
+
+// parseProxyURL checks that raw is an absolute HTTP(S) proxy URL
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+		return nil, errors.New("the proxy URL must look like http://host:port or https://host:port")
+	}
+	return proxyURL, nil
+}
+
+// readCACertFile reads a CA bundle from the server's filesystem
+func readCACertFile(path string) (string, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	return string(pem), nil
+}
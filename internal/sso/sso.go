@@ -0,0 +1,234 @@
+// Package sso signs users in with an OpenID Connect provider, using the
+// authorization code flow with PKCE
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/identity"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/secrets"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// LoginTimeout is how long a user has to finish signing in at the provider
+const LoginTimeout = 10 * time.Minute
+
+// ErrLoginExpired is returned for sign-ins that were not started in the same
+// browser or took longer than LoginTimeout
+var ErrLoginExpired = errors.New("sign-in was not started in this browser, took too long or was already completed; please try again")
+
+// Config describes the OIDC client registered with the provider
+type Config struct {
+	// Issuer is the provider's issuer URL; its discovery document is read at startup
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is this server's /auth/callback as registered with the provider
+	RedirectURL string
+	// Scopes are requested on top of openid, profile and email
+	Scopes []string
+	// GroupsClaim names the ID token claim that lists the user's groups
+	GroupsClaim string
+}
+
+// Provider signs users in with an OIDC provider
+type Provider struct {
+	oauth2      oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	issuer      string
+	groupsClaim string
+	// connections maps a user's groups to the connections they may use
+	connections func(groups []string) []string
+	// keyring seals pending sign-ins into the browsers that started them
+	keyring *secrets.Keyring
+}
+
+// pendingLogin is a sign-in that was sent to the provider and has not come
+// back yet. It is sealed and handed to the browser that started it instead
+// of being kept here, so that signing in leaves no state on the server and
+// can be finished by any instance sharing the master keys.
+type pendingLogin struct {
+	State    string    `json:"state"`
+	Nonce    string    `json:"nonce"`
+	Verifier string    `json:"verifier"`
+	Redirect string    `json:"redirect"`
+	Expires  time.Time `json:"expires"`
+}
+
+// New discovers the provider at cfg.Issuer. connections returns the names
+// of the connections that members of groups may use; keyring seals pending
+// sign-ins.
+func New(ctx context.Context, cfg Config, connections func(groups []string) []string, keyring *secrets.Keyring) (*Provider, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC sign-in needs a client ID and a redirect URL")
+	}
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	return &Provider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       append([]string{oidc.ScopeOpenID, "profile", "email"}, cfg.Scopes...),
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		issuer:      cfg.Issuer,
+		groupsClaim: groupsClaim,
+		connections: connections,
+		keyring:     keyring,
+	}, nil
+}
+
+// Begin starts signing in: it returns the sealed sign-in, for the browser to
+// keep until it comes back, and the provider URL to send the browser to.
+// redirect is where the browser returns to afterwards; only local paths are
+// kept.
+func (p *Provider) Begin(redirect string) (string, string, error) {
+	login := pendingLogin{
+		State:    rand.Text(),
+		Nonce:    rand.Text(),
+		Verifier: oauth2.GenerateVerifier(),
+		Redirect: identity.LocalRedirect(redirect),
+		Expires:  time.Now().Add(LoginTimeout),
+	}
+
+	sealed, err := p.seal(&login)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to seal sign-in: %w", err)
+	}
+	url := p.oauth2.AuthCodeURL(login.State, oidc.Nonce(login.Nonce), oauth2.S256ChallengeOption(login.Verifier))
+	return sealed, url, nil
+}
+
+// Finish completes the sign-in sealed by Begin with the state and
+// authorization code the provider returned, and returns the user and the
+// local path to return to
+func (p *Provider) Finish(ctx context.Context, sealedLogin, state, code string) (*models.User, string, error) {
+	login, err := p.open(sealedLogin)
+	if err != nil || state == "" || login.State != state || time.Now().After(login.Expires) {
+		return nil, "", ErrLoginExpired
+	}
+
+	user, err := p.exchange(ctx, code, login)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, login.Redirect, nil
+}
+
+// loginRecord is what pending sign-ins are sealed for, so that no other
+// secret sealed with the keyring opens as one
+const loginRecord = "oidc-login"
+
+// seal encrypts a pending sign-in into a value that can be put in a cookie
+func (p *Provider) seal(login *pendingLogin) (string, error) {
+	raw, err := json.Marshal(login)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := p.keyring.Seal(loginRecord, raw)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(sealed)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// open reads back a sign-in sealed by seal
+func (p *Provider) open(sealedLogin string) (*pendingLogin, error) {
+	encoded, err := base64.RawURLEncoding.DecodeString(sealedLogin)
+	if err != nil {
+		return nil, err
+	}
+	var sealed models.SealedSecret
+	if err := json.Unmarshal(encoded, &sealed); err != nil {
+		return nil, err
+	}
+	raw, err := p.keyring.Open(loginRecord, &sealed)
+	if err != nil {
+		return nil, err
+	}
+	login := &pendingLogin{}
+	if err := json.Unmarshal(raw, login); err != nil {
+		return nil, err
+	}
+	return login, nil
+}
+
+// exchange redeems the authorization code and reads the user from the verified ID token
+func (p *Provider) exchange(ctx context.Context, code string, login *pendingLogin) (*models.User, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("the token response has no ID token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("ID token: %w", err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("ID token claims: %w", err)
+	}
+	user := &models.User{
+		ID:     p.issuer + "#" + idToken.Subject,
+		Name:   stringClaim(claims, "name"),
+		Email:  stringClaim(claims, "email"),
+		Groups: listClaim(claims, p.groupsClaim),
+	}
+	if user.Name == "" {
+		user.Name = stringClaim(claims, "preferred_username")
+	}
+	user.Connections = p.connections(user.Groups)
+	return user, nil
+}
+
+// stringClaim returns a string claim, or "" when it is missing or not a string
+func stringClaim(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// listClaim returns a claim that lists strings; providers that send a
+// single group send it as a plain string
+func listClaim(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var items []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/secrets"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// testIdP is an OpenID Connect provider that signs in whoever asks as
+// alice, checking PKCE at its token endpoint
+type testIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// nonce, when set, replaces the nonce sent to the authorization endpoint
+	nonce string
+	mu    sync.Mutex
+	codes map[string]url.Values
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	idp := &testIdP{key: key, codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("POST /token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize stands in for the user signing in at the provider: it takes the
+// URL the browser was sent to and returns the code and state it comes back with
+func (idp *testIdP) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL without a PKCE challenge: %s", authURL)
+	}
+
+	code := rand.Text()
+	idp.mu.Lock()
+	idp.codes[code] = query
+	idp.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// token redeems a code once, when the verifier matches its challenge
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	idp.mu.Lock()
+	request, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != request.Get("code_challenge") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := request.Get("nonce")
+	if idp.nonce != "" {
+		nonce = idp.nonce
+	}
+	signer, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: idp.key}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+	idToken, _ := jwt.Signed(signer).Claims(map[string]any{
+		"iss":                idp.server.URL,
+		"sub":                "alice",
+		"aud":                request.Get("client_id"),
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"roles":              []string{"devs", "ops"},
+	}).Serialize()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// newTestProvider creates a provider for idp that maps the group devs to the connection dev
+func newTestProvider(t *testing.T, idp *testIdP) *Provider {
+	t.Helper()
+	keyring, err := secrets.NewEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeyring: %v", err)
+	}
+	provider, err := New(context.Background(), Config{
+		Issuer:      idp.server.URL,
+		ClientID:    "s3-browser",
+		RedirectURL: "http://localhost/auth/callback",
+		GroupsClaim: "roles",
+	}, func(groups []string) []string {
+		if slices.Contains(groups, "devs") {
+			return []string{"dev"}
+		}
+		return nil
+	}, keyring)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return provider
+}
+
+func TestFinish(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	sealed, authURL, err := provider.Begin("/objects/logs?prefix=2024/")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := idp.authorize(t, authURL)
+
+	user, redirect, err := provider.Finish(context.Background(), sealed, state, code)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if user.ID != idp.server.URL+"#alice" || user.Name != "alice" || user.Email != "alice@example.com" {
+		t.Errorf("Finish user = %+v", user)
+	}
+	if !slices.Equal(user.Groups, []string{"devs", "ops"}) || !slices.Equal(user.Connections, []string{"dev"}) {
+		t.Errorf("Finish groups = %v and connections = %v, want [devs ops] and [dev]", user.Groups, user.Connections)
+	}
+	if redirect != "/objects/logs?prefix=2024/" {
+		t.Errorf("Finish redirect = %q", redirect)
+	}
+
+	// The code was redeemed, so the same callback cannot sign in again
+	if _, _, err := provider.Finish(context.Background(), sealed, state, code); err == nil {
+		t.Error("Finish succeeded twice with the same code")
+	}
+}
+
+func TestFinishChecksState(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	sealed, authURL, err := provider.Begin("/")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := idp.authorize(t, authURL)
+	other, _, err := provider.Begin("/")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	foreign, _, err := newTestProvider(t, idp).Begin("/")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		sealed string
+		state  string
+	}{
+		{"no state", sealed, ""},
+		{"another state", sealed, "not-the-state"},
+		{"sign-in started for another state", other, state},
+		{"sign-in sealed by another server", foreign, state},
+		{"no sign-in", "", state},
+		{"garbage", "not-sealed", state},
+		{"tampered", strings.ToUpper(sealed), state},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := provider.Finish(context.Background(), tt.sealed, tt.state, code); !errors.Is(err, ErrLoginExpired) {
+				t.Errorf("Finish = %v, want ErrLoginExpired", err)
+			}
+		})
+	}
+}
+
+func TestFinishExpired(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	login := &pendingLogin{State: "state", Nonce: "nonce", Verifier: "verifier", Redirect: "/", Expires: time.Now().Add(-time.Second)}
+	sealed, err := provider.seal(login)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, _, err := provider.Finish(context.Background(), sealed, "state", "code"); !errors.Is(err, ErrLoginExpired) {
+		t.Errorf("Finish of an expired sign-in = %v, want ErrLoginExpired", err)
+	}
+}
+
+func TestFinishChecksNonce(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	sealed, authURL, err := provider.Begin("/")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := idp.authorize(t, authURL)
+	// An ID token issued for another sign-in
+	idp.nonce = "someone-elses-nonce"
+
+	_, _, err = provider.Finish(context.Background(), sealed, state, code)
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("Finish = %v, want a nonce mismatch", err)
+	}
+}
+
+func TestFinishChecksPKCE(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	sealed, authURL, err := provider.Begin("/")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, state := idp.authorize(t, authURL)
+
+	// A sign-in for the same state but with another verifier, as with a code
+	// intercepted and redeemed by someone who did not start the sign-in
+	login, err := provider.open(sealed)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	login.Verifier = "a-verifier-that-does-not-match-the-code-challenge-of-the-sign-in"
+	substituted, err := provider.seal(login)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	_, _, err = provider.Finish(context.Background(), substituted, state, code)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Finish = %v, want the provider to reject the code exchange", err)
+	}
+}
+
+func TestBeginKeepsRedirectsLocal(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := newTestProvider(t, idp)
+
+	for _, redirect := range []string{"https://evil.example.com/", "//evil.example.com/", "/\\evil.example.com"} {
+		sealed, authURL, err := provider.Begin(redirect)
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		code, state := idp.authorize(t, authURL)
+		_, got, err := provider.Finish(context.Background(), sealed, state, code)
+		if err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		if got != "/" {
+			t.Errorf("redirect %q became %q, want /", redirect, got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// Cache keeps the most recent statistics per session, bucket and prefix
+type Cache struct {
+	entries map[string]*models.BucketStats
+	mu      sync.RWMutex
+	maxAge  time.Duration
+}
+
+// NewCache creates a cache whose entries are discarded after maxAge
+func NewCache(maxAge time.Duration) *Cache {
+	return &Cache{
+		entries: make(map[string]*models.BucketStats),
+		maxAge:  maxAge,
+	}
+}
+
+// Get returns a copy of the cached statistics, if present and not expired
+func (c *Cache) Get(owner, bucket, prefix string) (*models.BucketStats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats, exists := c.entries[cacheKey(owner, bucket, prefix)]
+	if !exists || time.Since(stats.ComputedAt) > c.maxAge {
+		return nil, false
+	}
+
+	cached := *stats
+	cached.Cached = true
+	return &cached, true
+}
+
+// Put stores freshly computed statistics and drops expired entries
+func (c *Cache) Put(owner string, stats *models.BucketStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if time.Since(entry.ComputedAt) > c.maxAge {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[cacheKey(owner, stats.Bucket, stats.Prefix)] = stats
+}
+
+func cacheKey(owner, bucket, prefix string) string {
+	return owner + "\x00" + bucket + "\x00" + prefix
+}
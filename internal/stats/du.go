@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/listing"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// duNode accumulates usage for one prefix while the listing is walked
+type duNode struct {
+	name     string
+	prefix   string
+	size     int64
+	count    int64
+	largest  []types.Object
+	children map[string]*duNode
+}
+
+func newDUNode(name, prefix string) *duNode {
+	return &duNode{
+		name:     name,
+		prefix:   prefix,
+		children: make(map[string]*duNode),
+	}
+}
+
+// add accounts obj in the node and keeps the top largest objects, biggest first
+func (n *duNode) add(obj types.Object, top int) {
+	size := aws.ToInt64(obj.Size)
+	n.size += size
+	n.count++
+
+	if top <= 0 || (len(n.largest) == top && size <= aws.ToInt64(n.largest[top-1].Size)) {
+		return
+	}
+	i := sort.Search(len(n.largest), func(i int) bool {
+		return aws.ToInt64(n.largest[i].Size) < size
+	})
+	n.largest = append(n.largest, types.Object{})
+	copy(n.largest[i+1:], n.largest[i:])
+	n.largest[i] = obj
+	if len(n.largest) > top {
+		n.largest = n.largest[:top]
+	}
+}
+
+// toModel converts the node and its descendants, largest children first
+func (n *duNode) toModel(layout string) *models.DiskUsageNode {
+	node := &models.DiskUsageNode{
+		Name:        n.name,
+		Prefix:      n.prefix,
+		Size:        n.size,
+		ObjectCount: n.count,
+		Largest:     make([]models.S3Object, 0, len(n.largest)),
+	}
+	for _, obj := range n.largest {
+		node.Largest = append(node.Largest, listing.ToS3Object(obj, layout))
+	}
+
+	for _, child := range n.children {
+		node.Children = append(node.Children, child.toModel(layout))
+	}
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Size != node.Children[j].Size {
+			return node.Children[i].Size > node.Children[j].Size
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	return node
+}
+
+// DiskUsage builds a size tree for prefix in a single walk of the listing.
+// Sub-prefixes are expanded down to depth levels; objects below that depth
+// are accounted to their deepest expanded ancestor. Every node lists its top
+// largest objects, their last-modified times formatted with layout.
+func DiskUsage(ctx context.Context, walk listing.WalkFunc, prefix string, depth, top int, layout string) (*models.DiskUsageNode, error) {
+	root := newDUNode(prefix, prefix)
+
+	err := walk(ctx, prefix, func(obj types.Object) error {
+		key := aws.ToString(obj.Key)
+		segments := strings.Split(strings.TrimPrefix(key, prefix), "/")
+
+		node := root
+		node.add(obj, top)
+		// The last segment is the object name, not a prefix
+		for i := 0; i < len(segments)-1 && i < depth; i++ {
+			child, exists := node.children[segments[i]]
+			if !exists {
+				child = newDUNode(segments[i], node.prefix+segments[i]+"/")
+				node.children[segments[i]] = child
+			}
+			child.add(obj, top)
+			node = child
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return root.toModel(layout), nil
+}
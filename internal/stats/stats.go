@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/listing"
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// noneLabel groups objects without a top-level prefix or file extension
+const noneLabel = "(none)"
+
+// ageBuckets are the age ranges objects are grouped into, oldest last
+var ageBuckets = []struct {
+	label  string
+	maxAge time.Duration
+}{
+	{"<1d", 24 * time.Hour},
+	{"1d-7d", 7 * 24 * time.Hour},
+	{"7d-30d", 30 * 24 * time.Hour},
+	{"30d-90d", 90 * 24 * time.Hour},
+	{"90d-1y", 365 * 24 * time.Hour},
+}
+
+// Compute walks every object under prefix and aggregates count, size and
+// breakdowns by storage class, top-level prefix, extension and age
+func Compute(ctx context.Context, walk listing.WalkFunc, bucket, prefix string) (*models.BucketStats, error) {
+	stats := NewBucketStats(bucket, prefix)
+	now := time.Now()
+
+	err := walk(ctx, prefix, func(obj types.Object) error {
+		Add(stats, obj, now)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ComputedAt = time.Now()
+	return stats, nil
+}
+
+// NewBucketStats returns empty statistics for bucket and prefix
+func NewBucketStats(bucket, prefix string) *models.BucketStats {
+	return &models.BucketStats{
+		Bucket:         bucket,
+		Prefix:         prefix,
+		StorageClasses: make(map[string]models.StatsBreakdown),
+		Prefixes:       make(map[string]models.StatsBreakdown),
+		Extensions:     make(map[string]models.StatsBreakdown),
+		Ages:           make(map[string]models.StatsBreakdown),
+	}
+}
+
+// Add accounts a single object in stats, measuring its age relative to now
+func Add(stats *models.BucketStats, obj types.Object, now time.Time) {
+	key := aws.ToString(obj.Key)
+	size := aws.ToInt64(obj.Size)
+
+	stats.ObjectCount++
+	stats.TotalSize += size
+
+	storageClass := string(obj.StorageClass)
+	if storageClass == "" {
+		storageClass = string(types.ObjectStorageClassStandard)
+	}
+	addTo(stats.StorageClasses, storageClass, size)
+	addTo(stats.Prefixes, topLevelPrefix(strings.TrimPrefix(key, stats.Prefix)), size)
+	addTo(stats.Extensions, extension(key), size)
+	addTo(stats.Ages, ageLabel(aws.ToTime(obj.LastModified), now), size)
+}
+
+func addTo(breakdown map[string]models.StatsBreakdown, label string, size int64) {
+	entry := breakdown[label]
+	entry.Count++
+	entry.Size += size
+	breakdown[label] = entry
+}
+
+// topLevelPrefix returns the first path segment of key including its trailing slash
+func topLevelPrefix(key string) string {
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i+1]
+	}
+	return noneLabel
+}
+
+// extension returns the lower-cased file extension of key
+func extension(key string) string {
+	ext := strings.ToLower(path.Ext(path.Base(key)))
+	if ext == "" || strings.HasSuffix(key, "/") {
+		return noneLabel
+	}
+	return ext
+}
+
+// ageLabel returns the age bucket that lastModified falls into
+func ageLabel(lastModified, now time.Time) string {
+	age := now.Sub(lastModified)
+	for _, bucket := range ageBuckets {
+		if age < bucket.maxAge {
+			return bucket.label
+		}
+	}
+	return ">1y"
+}
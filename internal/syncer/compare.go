@@ -0,0 +1,53 @@
+package syncer
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// Compare walks two listings in key order and calls fn with every key
+// classified, returning the totals. Keys whose ETags have different shapes,
+// which happens for copies uploaded in other part sizes, are only compared
+// by size.
+func Compare(ctx context.Context, left, right Lister, fn func(entry models.CompareEntry) error) (models.CompareSummary, error) {
+	var summary models.CompareSummary
+	err := Merge(ctx, left, right, func(l, r *Entry) error {
+		entry := models.CompareEntry{Key: entryKey(l, r)}
+		// Merge reuses its entries, so sizes are copied before being kept
+		if l != nil {
+			entry.LeftSize = aws.Int64(l.Size)
+			entry.LeftETag = l.ETag
+		}
+		if r != nil {
+			entry.RightSize = aws.Int64(r.Size)
+			entry.RightETag = r.ETag
+		}
+
+		switch {
+		case r == nil:
+			entry.Class = models.CompareOnlyLeft
+			summary.OnlyLeft++
+		case l == nil:
+			entry.Class = models.CompareOnlyRight
+			summary.OnlyRight++
+		case l.Size != r.Size:
+			entry.Class = models.CompareSizeDiffers
+			summary.SizeDiffers++
+		case !transfer.ETagComparable(r.ETag, l.ETag):
+			entry.Class = models.CompareETagIncomparable
+			summary.ETagIncomparable++
+		case l.ETag != r.ETag:
+			entry.Class = models.CompareETagDiffers
+			summary.ETagDiffers++
+		default:
+			entry.Class = models.CompareIdentical
+			summary.Identical++
+		}
+		return fn(entry)
+	})
+	summary.Match = summary.OnlyLeft+summary.OnlyRight+summary.SizeDiffers+summary.ETagDiffers == 0
+	return summary, err
+}
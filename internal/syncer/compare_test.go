@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+func TestCompare(t *testing.T) {
+	single := etag("content")
+	left := listEntries(
+		Entry{Key: "copied.bin", Size: 20 << 20, ETag: single},
+		Entry{Key: "left.txt", Size: 1, ETag: etag("l")},
+		Entry{Key: "same.txt", Size: 4, ETag: etag("same")},
+		Entry{Key: "size.txt", Size: 4, ETag: etag("size")},
+		Entry{Key: "touched.txt", Size: 4, ETag: etag("old")},
+	)
+	right := listEntries(
+		// The same content uploaded in parts, so the ETags only differ in shape
+		Entry{Key: "copied.bin", Size: 20 << 20, ETag: etag("parts") + "-2"},
+		Entry{Key: "right.txt", Size: 1, ETag: etag("r")},
+		Entry{Key: "same.txt", Size: 4, ETag: etag("same")},
+		Entry{Key: "size.txt", Size: 5, ETag: etag("size")},
+		Entry{Key: "touched.txt", Size: 4, ETag: etag("new")},
+	)
+
+	classes := make(map[string]string)
+	summary, err := Compare(context.Background(), left, right, func(entry models.CompareEntry) error {
+		classes[entry.Key] = entry.Class
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+
+	want := map[string]string{
+		"copied.bin":  models.CompareETagIncomparable,
+		"left.txt":    models.CompareOnlyLeft,
+		"right.txt":   models.CompareOnlyRight,
+		"same.txt":    models.CompareIdentical,
+		"size.txt":    models.CompareSizeDiffers,
+		"touched.txt": models.CompareETagDiffers,
+	}
+	for key, class := range want {
+		if classes[key] != class {
+			t.Errorf("Compare classified %s as %q, want %q", key, classes[key], class)
+		}
+	}
+	wantSummary := models.CompareSummary{OnlyLeft: 1, OnlyRight: 1, SizeDiffers: 1, ETagDiffers: 1, ETagIncomparable: 1, Identical: 1}
+	if summary != wantSummary {
+		t.Errorf("Compare summary = %+v, want %+v", summary, wantSummary)
+	}
+}
+
+func TestCompareMatchesAcrossPartSizes(t *testing.T) {
+	left := listEntries(
+		Entry{Key: "a.bin", Size: 40 << 20, ETag: etag("a") + "-3"},
+		Entry{Key: "b.txt", Size: 4, ETag: etag("b")},
+	)
+	right := listEntries(
+		Entry{Key: "a.bin", Size: 40 << 20, ETag: etag("a") + "-5"},
+		Entry{Key: "b.txt", Size: 4, ETag: etag("b")},
+	)
+
+	summary, err := Compare(context.Background(), left, right, func(models.CompareEntry) error { return nil })
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if !summary.Match || summary.ETagIncomparable != 1 {
+		t.Errorf("Compare summary = %+v, want a match with one key compared by size only", summary)
+	}
+}
@@ -0,0 +1,126 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/listing"
+)
+
+// Entry is an object of a location, keyed relative to the location prefix
+type Entry struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// Lister calls fn for every entry of a location in ascending key order
+type Lister func(ctx context.Context, fn func(entry Entry) error) error
+
+// S3 lists the objects stored under prefix in bucket
+func S3(client s3.ListObjectsV2APIClient, bucket, prefix string) Lister {
+	return func(ctx context.Context, fn func(entry Entry) error) error {
+		return listing.Walk(ctx, client, bucket, prefix, func(obj types.Object) error {
+			return fn(Entry{
+				Key:          strings.TrimPrefix(aws.ToString(obj.Key), prefix),
+				Size:         aws.ToInt64(obj.Size),
+				ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		})
+	}
+}
+
+// Merge walks two listings side by side in key order, calling fn once per
+// key with the entries found for it; the side that lacks the key is nil
+func Merge(ctx context.Context, left, right Lister, fn func(left, right *Entry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	l := newStream(ctx, left)
+	r := newStream(ctx, right)
+	a, aok, err := l.next()
+	if err != nil {
+		return err
+	}
+	b, bok, err := r.next()
+	if err != nil {
+		return err
+	}
+
+	for aok || bok {
+		switch {
+		case aok && (!bok || a.Key < b.Key):
+			if err := fn(&a, nil); err != nil {
+				return err
+			}
+			if a, aok, err = l.next(); err != nil {
+				return err
+			}
+		case bok && (!aok || b.Key < a.Key):
+			if err := fn(nil, &b); err != nil {
+				return err
+			}
+			if b, bok, err = r.next(); err != nil {
+				return err
+			}
+		default:
+			if err := fn(&a, &b); err != nil {
+				return err
+			}
+			if a, aok, err = l.next(); err != nil {
+				return err
+			}
+			if b, bok, err = r.next(); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// stream turns a Lister into entries that can be pulled one at a time
+type stream struct {
+	entries chan Entry
+	err     error
+	last    string
+	started bool
+}
+
+func newStream(ctx context.Context, list Lister) *stream {
+	s := &stream{entries: make(chan Entry, 1000)}
+	go func() {
+		defer close(s.entries)
+		s.err = list(ctx, func(entry Entry) error {
+			select {
+			case s.entries <- entry:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return s
+}
+
+// next returns the following entry, or false once the listing is exhausted.
+// A listing that failed or is out of order is reported as an error rather
+// than treated as complete.
+func (s *stream) next() (Entry, bool, error) {
+	entry, ok := <-s.entries
+	if !ok {
+		return Entry{}, false, s.err
+	}
+	if s.started && entry.Key <= s.last {
+		return Entry{}, false, fmt.Errorf("listing is not in key order at %q", entry.Key)
+	}
+	s.started = true
+	s.last = entry.Key
+	return entry, true, nil
+}
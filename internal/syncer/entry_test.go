@@ -0,0 +1,153 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// listOf lists the given keys in the order given
+func listOf(keys ...string) Lister {
+	return func(ctx context.Context, fn func(entry Entry) error) error {
+		for i, key := range keys {
+			if err := fn(Entry{Key: key, Size: int64(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// merged describes the calls Merge made as "left|right" pairs, with - for a missing side
+func merged(t *testing.T, left, right Lister) ([]string, error) {
+	t.Helper()
+	var calls []string
+	err := Merge(context.Background(), left, right, func(l, r *Entry) error {
+		if l != nil && r != nil && l.Key != r.Key {
+			t.Errorf("Merge paired %q with %q", l.Key, r.Key)
+		}
+		side := func(e *Entry) string {
+			if e == nil {
+				return "-"
+			}
+			return e.Key
+		}
+		calls = append(calls, side(l)+"|"+side(r))
+		return nil
+	})
+	return calls, err
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right Lister
+		want        string
+	}{
+		{"both empty", listOf(), listOf(), ""},
+		{"left only", listOf("a", "b"), listOf(), "a|- b|-"},
+		{"right only", listOf(), listOf("a", "b"), "-|a -|b"},
+		{"same keys", listOf("a", "b/c"), listOf("a", "b/c"), "a|a b/c|b/c"},
+		{"interleaved", listOf("a", "c", "e"), listOf("b", "c", "d", "f"), "a|- -|b c|c -|d e|- -|f"},
+		{"left runs out first", listOf("a"), listOf("a", "b", "c"), "a|a -|b -|c"},
+		{"prefix keys", listOf("dir", "dir/file"), listOf("dir/", "dir/file"), "dir|- -|dir/ dir/file|dir/file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, err := merged(t, tt.left, tt.right)
+			if err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if got := strings.Join(calls, " "); got != tt.want {
+				t.Errorf("Merge called fn with %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLargeListings(t *testing.T) {
+	// More entries than a stream buffers, so both sides must be pulled in step
+	var left, right []string
+	for i := range 5000 {
+		key := fmt.Sprintf("%05d", i)
+		if i%2 == 0 {
+			left = append(left, key)
+		}
+		if i%3 == 0 {
+			right = append(right, key)
+		}
+	}
+	calls, err := merged(t, listOf(left...), listOf(right...))
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	// Keys divisible by 2 or 3
+	if len(calls) != 3333 {
+		t.Errorf("Merge called fn %d times, want 3333", len(calls))
+	}
+}
+
+func TestMergeListingError(t *testing.T) {
+	failing := func(ctx context.Context, fn func(entry Entry) error) error {
+		if err := fn(Entry{Key: "a"}); err != nil {
+			return err
+		}
+		return errors.New("access denied")
+	}
+
+	for name, pair := range map[string][2]Lister{
+		"left":  {failing, listOf("a", "b")},
+		"right": {listOf("a", "b"), failing},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := merged(t, pair[0], pair[1])
+			if err == nil || err.Error() != "access denied" {
+				t.Errorf("Merge = %v, want the listing error instead of a partial result", err)
+			}
+		})
+	}
+}
+
+func TestMergeOutOfOrder(t *testing.T) {
+	for name, list := range map[string]Lister{
+		"descending": listOf("b", "a"),
+		"duplicate":  listOf("a", "a"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := merged(t, list, listOf())
+			if err == nil || !strings.Contains(err.Error(), "not in key order") {
+				t.Errorf("Merge = %v, want an out-of-order error", err)
+			}
+		})
+	}
+}
+
+func TestMergeStopsListings(t *testing.T) {
+	stop := errors.New("stop")
+	stopped := make(chan error, 2)
+	endless := func(ctx context.Context, fn func(entry Entry) error) error {
+		for i := 0; ; i++ {
+			if err := fn(Entry{Key: fmt.Sprintf("%09d", i)}); err != nil {
+				stopped <- err
+				return err
+			}
+		}
+	}
+
+	err := Merge(context.Background(), endless, endless, func(left, right *Entry) error {
+		if left.Key == "000000010" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("Merge = %v, want the error of fn", err)
+	}
+	for range 2 {
+		if err := <-stopped; !errors.Is(err, context.Canceled) {
+			t.Errorf("a listing stopped with %v, want context.Canceled", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// deleteBatchSize is the maximum number of keys accepted by a single DeleteObjects call
+const deleteBatchSize = 1000
+
+// Execute returns the operation that carries out a plan: creates and updates
+// are copied with a transfer, then extraneous objects are deleted. Like a
+// transfer, it can be resumed after a failure without copying objects again.
+func Execute(items []models.SyncItem, source, destination transfer.Location, logger *slog.Logger) operations.Func {
+	var copies, deletes []string
+	for _, item := range items {
+		switch item.Action {
+		case models.SyncCreate, models.SyncUpdate:
+			copies = append(copies, source.Prefix+item.Key)
+		case models.SyncDelete:
+			deletes = append(deletes, destination.Prefix+item.Key)
+		}
+	}
+	t := transfer.New(source, destination, copies, false, logger)
+
+	return func(ctx context.Context, op *operations.Operation) error {
+		// A transfer without keys copies the whole prefix
+		if len(copies) > 0 {
+			if err := t.Run(ctx, op); err != nil {
+				return err
+			}
+		}
+		if len(deletes) == 0 {
+			return nil
+		}
+
+		op.SetPhase("deleting extraneous objects")
+		op.AddTotal(int64(len(deletes)))
+		return DeleteKeys(ctx, destination, deletes, op)
+	}
+}
+
+// DeleteKeys deletes keys from location in batches of at most deleteBatchSize,
+// recording progress on op
+func DeleteKeys(ctx context.Context, location transfer.Location, keys []string, op *operations.Operation) error {
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(keys))
+		ids := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			ids = append(ids, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		result, err := location.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(location.Bucket),
+			Delete: &types.Delete{
+				Objects: ids,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			op.AddFailed(int64(len(result.Errors)))
+			first := result.Errors[0]
+			return fmt.Errorf("failed to delete %s: %s", aws.ToString(first.Key), aws.ToString(first.Message))
+		}
+		op.AddDone(int64(end-start), 0)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package syncer
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/cksidharthan/s3-browser/internal/search"
+)
+
+// Filter selects keys by include and exclude globs, with the glob syntax of
+// search. Patterns match whole keys relative to the location prefix, so
+// "*.log" only matches at the top level and "**/*.log" at any depth.
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewFilter compiles include and exclude globs. With no include globs every
+// key is included.
+func NewFilter(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.include, err = compileGlobs(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileGlobs(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Match reports whether key is included and not excluded
+func (f *Filter) Match(key string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, key) {
+		return false
+	}
+	return !matchAny(f.exclude, key)
+}
+
+func compileGlobs(globs []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		re, err := regexp.Compile(search.GlobToRegexp(glob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func matchAny(patterns []*regexp.Regexp, key string) bool {
+	for _, re := range patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/cksidharthan/s3-browser/internal/transfer"
+)
+
+// How objects present on both sides are compared, besides their size
+const (
+	CompareETag     = "etag"
+	CompareModified = "modified"
+)
+
+// Options control what a sync plan contains
+type Options struct {
+	Filter *Filter
+	// Compare is CompareETag or CompareModified
+	Compare string
+	// Delete plans the removal of destination objects missing from the source
+	Delete bool
+}
+
+// ValidCompare reports whether compare names a supported comparison
+func ValidCompare(compare string) bool {
+	return compare == CompareETag || compare == CompareModified
+}
+
+// Plan compares the source and destination listings and returns the steps
+// that make the destination match the source. Keys excluded by the filter
+// are neither copied nor deleted.
+func Plan(ctx context.Context, source, destination Lister, opts Options) ([]models.SyncItem, models.SyncSummary, error) {
+	if !ValidCompare(opts.Compare) {
+		return nil, models.SyncSummary{}, fmt.Errorf("unknown comparison %q", opts.Compare)
+	}
+
+	items := make([]models.SyncItem, 0)
+	var summary models.SyncSummary
+	err := Merge(ctx, source, destination, func(src, dst *Entry) error {
+		key := entryKey(src, dst)
+		if opts.Filter != nil && !opts.Filter.Match(key) {
+			return nil
+		}
+
+		switch {
+		case dst == nil:
+			items = append(items, models.SyncItem{Action: models.SyncCreate, Key: key, Size: src.Size})
+			summary.Creates++
+			summary.Bytes += src.Size
+		case src == nil:
+			if !opts.Delete {
+				return nil
+			}
+			items = append(items, models.SyncItem{Action: models.SyncDelete, Key: key, Size: dst.Size})
+			summary.Deletes++
+		default:
+			reason := changed(src, dst, opts.Compare)
+			if reason == "" {
+				summary.Unchanged++
+				return nil
+			}
+			items = append(items, models.SyncItem{Action: models.SyncUpdate, Key: key, Size: src.Size, Reason: reason})
+			summary.Updates++
+			summary.Bytes += src.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, models.SyncSummary{}, err
+	}
+	return items, summary, nil
+}
+
+// changed returns why dst is out of date with src, or an empty string if it
+// is not. ETags of different shapes, such as a copy uploaded in other part
+// sizes, cannot be compared, so those objects are compared by modified time.
+func changed(src, dst *Entry, compare string) string {
+	if src.Size != dst.Size {
+		return "size"
+	}
+	if compare == CompareETag && transfer.ETagComparable(dst.ETag, src.ETag) {
+		if src.ETag != dst.ETag {
+			return "etag"
+		}
+		return ""
+	}
+	if src.LastModified.After(dst.LastModified) {
+		return "modified"
+	}
+	return ""
+}
+
+func entryKey(left, right *Entry) string {
+	if left != nil {
+		return left.Key
+	}
+	return right.Key
+}
@@ -0,0 +1,153 @@
+package syncer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+)
+
+// listEntries lists entries that are already in key order
+func listEntries(entries ...Entry) Lister {
+	return func(ctx context.Context, fn func(entry Entry) error) error {
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// etag returns the ETag S3 gives content uploaded with a single Put
+func etag(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPlan(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	source := listEntries(
+		Entry{Key: "app.log", Size: 10, ETag: etag("a"), LastModified: newer},
+		Entry{Key: "logs/new.log", Size: 5, ETag: etag("n"), LastModified: newer},
+		Entry{Key: "logs/same.log", Size: 7, ETag: etag("s"), LastModified: older},
+		Entry{Key: "logs/size.log", Size: 8, ETag: etag("z"), LastModified: older},
+		Entry{Key: "logs/touched.log", Size: 3, ETag: etag("t2"), LastModified: newer},
+	)
+	destination := listEntries(
+		Entry{Key: "app.log", Size: 10, ETag: etag("a"), LastModified: older},
+		Entry{Key: "logs/gone.log", Size: 4, ETag: etag("g"), LastModified: older},
+		Entry{Key: "logs/same.log", Size: 7, ETag: etag("s"), LastModified: newer},
+		Entry{Key: "logs/size.log", Size: 9, ETag: etag("z"), LastModified: older},
+		Entry{Key: "logs/touched.log", Size: 3, ETag: etag("t1"), LastModified: older},
+	)
+	logsOnly, err := NewFilter([]string{"logs/**"}, nil)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		summary models.SyncSummary
+	}{
+		{
+			name: "etag",
+			opts: Options{Compare: CompareETag},
+			want: []string{"create logs/new.log", "update logs/size.log size", "update logs/touched.log etag"},
+			summary: models.SyncSummary{
+				Creates: 1, Updates: 2, Unchanged: 2, Bytes: 5 + 8 + 3,
+			},
+		},
+		{
+			name: "modified",
+			opts: Options{Compare: CompareModified},
+			want: []string{"update app.log modified", "create logs/new.log", "update logs/size.log size", "update logs/touched.log modified"},
+			summary: models.SyncSummary{
+				Creates: 1, Updates: 3, Unchanged: 1, Bytes: 10 + 5 + 8 + 3,
+			},
+		},
+		{
+			name: "delete",
+			opts: Options{Compare: CompareETag, Delete: true},
+			want: []string{"delete logs/gone.log", "create logs/new.log", "update logs/size.log size", "update logs/touched.log etag"},
+			summary: models.SyncSummary{
+				Creates: 1, Updates: 2, Deletes: 1, Unchanged: 2, Bytes: 5 + 8 + 3,
+			},
+		},
+		{
+			name: "filtered",
+			opts: Options{Compare: CompareModified, Delete: true, Filter: logsOnly},
+			want: []string{"delete logs/gone.log", "create logs/new.log", "update logs/size.log size", "update logs/touched.log modified"},
+			summary: models.SyncSummary{
+				Creates: 1, Updates: 2, Deletes: 1, Unchanged: 1, Bytes: 5 + 8 + 3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, summary, err := Plan(context.Background(), source, destination, tt.opts)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			var got []string
+			for _, item := range items {
+				step := string(item.Action) + " " + item.Key
+				if item.Reason != "" {
+					step += " " + item.Reason
+				}
+				got = append(got, step)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Plan steps = %q, want %q", got, tt.want)
+			}
+			if summary != tt.summary {
+				t.Errorf("Plan summary = %+v, want %+v", summary, tt.summary)
+			}
+		})
+	}
+}
+
+func TestPlanETagShapes(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	single := etag("content")
+	multipart := etag("parts") + "-2"
+	source := listEntries(
+		Entry{Key: "copied.bin", Size: 20 << 20, ETag: single, LastModified: older},
+		Entry{Key: "parts.bin", Size: 20 << 20, ETag: multipart, LastModified: older},
+		Entry{Key: "repartitioned.bin", Size: 40 << 20, ETag: etag("parts") + "-5", LastModified: older},
+		Entry{Key: "rewritten.bin", Size: 20 << 20, ETag: single, LastModified: newer},
+	)
+	// The destination copies were uploaded in other part sizes, so their
+	// ETags differ from the source's only in shape
+	destination := listEntries(
+		Entry{Key: "copied.bin", Size: 20 << 20, ETag: multipart, LastModified: newer},
+		Entry{Key: "parts.bin", Size: 20 << 20, ETag: single, LastModified: newer},
+		Entry{Key: "repartitioned.bin", Size: 40 << 20, ETag: etag("parts") + "-3", LastModified: newer},
+		Entry{Key: "rewritten.bin", Size: 20 << 20, ETag: multipart, LastModified: older},
+	)
+
+	items, summary, err := Plan(context.Background(), source, destination, Options{Compare: CompareETag})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(items) != 1 || items[0].Key != "rewritten.bin" || items[0].Reason != "modified" {
+		t.Errorf("Plan = %+v, want only rewritten.bin updated as modified", items)
+	}
+	if summary.Unchanged != 3 {
+		t.Errorf("Plan left %d objects unchanged, want 3", summary.Unchanged)
+	}
+}
+
+func TestPlanUnknownCompare(t *testing.T) {
+	if _, _, err := Plan(context.Background(), listEntries(), listEntries(), Options{Compare: "checksum"}); err == nil {
+		t.Error("Plan with an unknown comparison succeeded")
+	}
+}
@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cksidharthan/s3-browser/internal/models"
+	"github.com/google/uuid"
+)
+
+// PlanTTL is how long a plan can be executed after it was made
+const PlanTTL = time.Hour
+
+// Plans keeps sync plans until they are executed, discarded or expire
+type Plans struct {
+	mu    sync.Mutex
+	plans map[string]storedPlan
+}
+
+type storedPlan struct {
+	owner string
+	plan  *models.SyncPlan
+}
+
+// NewPlans creates an empty plan store
+func NewPlans() *Plans {
+	return &Plans{plans: make(map[string]storedPlan)}
+}
+
+// Add stores plan on behalf of owner, assigning its ID and expiry
+func (p *Plans) Add(owner string, plan *models.SyncPlan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for id, stored := range p.plans {
+		if now.After(stored.plan.ExpiresAt) {
+			delete(p.plans, id)
+		}
+	}
+
+	plan.ID = uuid.New().String()
+	plan.CreatedAt = now
+	plan.ExpiresAt = now.Add(PlanTTL)
+	p.plans[plan.ID] = storedPlan{owner: owner, plan: plan}
+}
+
+// Get returns the plan id if it belongs to owner and has not expired
+func (p *Plans) Get(owner, id string) *models.SyncPlan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, exists := p.plans[id]
+	if !exists || stored.owner != owner {
+		return nil
+	}
+	if time.Now().After(stored.plan.ExpiresAt) {
+		delete(p.plans, id)
+		return nil
+	}
+	return stored.plan
+}
+
+// Remove forgets the plan id of owner, reporting whether there was one
+func (p *Plans) Remove(owner, id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, exists := p.plans[id]
+	if !exists || stored.owner != owner {
+		return false
+	}
+	delete(p.plans, id)
+	return true
+}
@@ -0,0 +1,93 @@
+package transfer
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// etagSum computes the ETags S3 gives unencrypted and SSE-S3 objects while
+// they stream past: the MD5 of the content for single uploads, and the MD5 of
+// the part MD5s followed by the part count for multipart uploads
+type etagSum struct {
+	partSize int64
+	size     int64
+	whole    hash.Hash
+	part     hash.Hash
+	partLen  int64
+	parts    []byte
+}
+
+func newETagSum(partSize int64) *etagSum {
+	return &etagSum{
+		partSize: partSize,
+		whole:    md5.New(),
+		part:     md5.New(),
+	}
+}
+
+// Write feeds streamed content into the sums
+func (s *etagSum) Write(p []byte) (int, error) {
+	n := len(p)
+	s.size += int64(n)
+	s.whole.Write(p)
+	for len(p) > 0 {
+		chunk := min(int64(len(p)), s.partSize-s.partLen)
+		s.part.Write(p[:chunk])
+		s.partLen += chunk
+		p = p[chunk:]
+		if s.partLen == s.partSize {
+			s.parts = s.part.Sum(s.parts)
+			s.part.Reset()
+			s.partLen = 0
+		}
+	}
+	return n, nil
+}
+
+// single returns the ETag of the content uploaded in one piece
+func (s *etagSum) single() string {
+	return hex.EncodeToString(s.whole.Sum(nil))
+}
+
+// multipart returns the ETag of the content uploaded in parts of partSize
+func (s *etagSum) multipart() string {
+	parts := s.parts
+	if s.partLen > 0 {
+		parts = s.part.Sum(parts)
+	}
+	sum := md5.Sum(parts)
+	return fmt.Sprintf("%x-%d", sum, len(parts)/md5.Size)
+}
+
+// md5ETag reports whether an object's ETag is derived from MD5 sums, which is
+// not the case for objects encrypted with KMS or customer-provided keys
+func md5ETag(sse types.ServerSideEncryption, customerAlgorithm *string) bool {
+	return sse != types.ServerSideEncryptionAwsKms && sse != types.ServerSideEncryptionAwsKmsDsse && customerAlgorithm == nil
+}
+
+// ETagComparable reports whether got has the shape of expected: a plain MD5,
+// or a multipart ETag with the same number of parts. ETags of other shapes
+// say nothing about whether the content is the same, so providers that
+// compute ETags differently, and objects uploaded in other part sizes, are
+// only checked by size.
+func ETagComparable(got, expected string) bool {
+	_, gotParts, gotMultipart := strings.Cut(got, "-")
+	_, expectedParts, expectedMultipart := strings.Cut(expected, "-")
+	if gotMultipart != expectedMultipart || gotParts != expectedParts {
+		return false
+	}
+	return len(strings.SplitN(got, "-", 2)[0]) == 2*md5.Size
+}
+
+// trimETag strips the quotes S3 puts around ETags
+func trimETag(etag *string) string {
+	if etag == nil {
+		return ""
+	}
+	return strings.Trim(*etag, `"`)
+}
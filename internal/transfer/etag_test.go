@@ -0,0 +1,196 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// content returns size bytes that differ from part to part
+func content(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+// multipartETag computes the ETag of data uploaded in parts of partSize the way S3 does
+func multipartETag(data []byte, partSize int) string {
+	var sums []byte
+	for start := 0; start < len(data); start += partSize {
+		sum := md5.Sum(data[start:min(start+partSize, len(data))])
+		sums = append(sums, sum[:]...)
+	}
+	return fmt.Sprintf("%s-%d", md5Hex(sums), len(sums)/md5.Size)
+}
+
+func TestETagSum(t *testing.T) {
+	const partSize = 8
+	tests := []struct {
+		name      string
+		size      int
+		multipart string
+	}{
+		{"under a part", 5, "-1"},
+		{"exactly a part", 8, "-1"},
+		{"a byte over a part", 9, "-2"},
+		{"exactly two parts", 16, "-2"},
+		{"several parts", 30, "-4"},
+	}
+	for _, tt := range tests {
+		// Writes that do not line up with parts must give the same sums
+		for _, write := range []int{1, 3, partSize, 64} {
+			t.Run(fmt.Sprintf("%s in writes of %d", tt.name, write), func(t *testing.T) {
+				data := content(tt.size)
+				sum := newETagSum(partSize)
+				for start := 0; start < len(data); start += write {
+					sum.Write(data[start:min(start+write, len(data))])
+				}
+
+				if sum.size != int64(tt.size) {
+					t.Errorf("size = %d, want %d", sum.size, tt.size)
+				}
+				if got := sum.single(); got != md5Hex(data) {
+					t.Errorf("single = %s, want %s", got, md5Hex(data))
+				}
+				want := multipartETag(data, partSize)
+				if got := sum.multipart(); got != want || want[len(want)-2:] != tt.multipart {
+					t.Errorf("multipart = %s, want %s ending in %s", got, want, tt.multipart)
+				}
+			})
+		}
+	}
+}
+
+func TestETagComparable(t *testing.T) {
+	plain := md5Hex([]byte("a"))
+	tests := []struct {
+		got, expected string
+		want          bool
+	}{
+		{plain, md5Hex([]byte("b")), true},
+		{plain + "-2", md5Hex([]byte("b")) + "-2", true},
+		{plain + "-2", plain + "-3", false},
+		{plain, plain + "-1", false},
+		{plain + "-1", plain, false},
+		{"", plain, false},
+		// ETags that are not MD5 sums, as some providers give
+		{"0123456789abcdef", plain, false},
+		{"W/" + plain, plain, false},
+	}
+	for _, tt := range tests {
+		if got := ETagComparable(tt.got, tt.expected); got != tt.want {
+			t.Errorf("ETagComparable(%q, %q) = %v, want %v", tt.got, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestUploadETags(t *testing.T) {
+	const partSize = 8
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name      string
+		size      int
+		multipart bool
+	}{
+		{"empty", 0, false},
+		{"exactly a part", partSize, false},
+		{"a byte over a part", partSize + 1, true},
+		{"several parts", 3*partSize + 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, dst := newFakeS3(t)
+			data := content(tt.size)
+			sum := newETagSum(partSize)
+
+			err := upload(context.Background(), dst, "key", io.TeeReader(bytes.NewReader(data), sum), int64(tt.size), partSize, objectAttributes{head: &s3.HeadObjectOutput{}}, logger)
+			if err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+			if (fake.multiparts == 1) != tt.multipart || fake.puts+fake.multiparts != 1 {
+				t.Errorf("upload made %d puts and %d multipart uploads, want multipart %v", fake.puts, fake.multiparts, tt.multipart)
+			}
+			if !bytes.Equal(fake.objects["key"], data) {
+				t.Errorf("uploaded %d bytes that differ from the %d sent", len(fake.objects["key"]), len(data))
+			}
+
+			// verify expects exactly the ETag the endpoint computed
+			copier := &Transfer{destination: dst, partSize: partSize}
+			source := &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(tt.size)), ETag: aws.String(`"` + md5Hex(data) + `"`)}
+			if err := copier.verify(context.Background(), "key", source, sum); err != nil {
+				t.Errorf("verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestUploadAbortsOnFailure(t *testing.T) {
+	fake, dst := newFakeS3(t)
+	fake.failPart = 2
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	data := content(20)
+	err := upload(context.Background(), dst, "key", bytes.NewReader(data), int64(len(data)), 8, objectAttributes{head: &s3.HeadObjectOutput{}}, logger)
+	if err == nil {
+		t.Fatal("upload with a failing part succeeded")
+	}
+	if fake.aborts != 1 || len(fake.uploads) != 0 {
+		t.Errorf("upload left %d multipart uploads after %d aborts, want it aborted", len(fake.uploads), fake.aborts)
+	}
+	if _, ok := fake.objects["key"]; ok {
+		t.Error("a failed upload created the object")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const partSize = 8
+	data := content(20)
+	plain := md5Hex(data)
+
+	tests := []struct {
+		name string
+		// source is the ETag the source reported, and destination the one of the copy
+		source, destination string
+		sourceSize          int64
+		sse                 types.ServerSideEncryption
+		wantErr             bool
+	}{
+		{"matching", plain, multipartETag(data, partSize), 20, "", false},
+		{"source changed while read", md5Hex([]byte("other")), multipartETag(data, partSize), 20, "", true},
+		{"source a multipart upload", multipartETag(data, 5), multipartETag(data, partSize), 20, "", false},
+		{"copy corrupted", plain, multipartETag([]byte("other content here!!"), partSize), 20, "", true},
+		{"copy in other part sizes", plain, multipartETag(data, 5), 20, "", false},
+		{"copy ETag not an MD5", plain, "provider-specific", 20, "", false},
+		{"source encrypted with KMS", "kms-etag", multipartETag(data, partSize), 20, types.ServerSideEncryptionAwsKms, false},
+		{"short read", plain, multipartETag(data, partSize), 21, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, dst := newFakeS3(t)
+			fake.objects["key"] = data
+			fake.etags["key"] = tt.destination
+
+			sum := newETagSum(partSize)
+			sum.Write(data)
+			copier := &Transfer{destination: dst, partSize: partSize}
+			source := &s3.HeadObjectOutput{
+				ContentLength:        aws.Int64(tt.sourceSize),
+				ETag:                 aws.String(`"` + tt.source + `"`),
+				ServerSideEncryption: tt.sse,
+			}
+			if err := copier.verify(context.Background(), "key", source, sum); (err != nil) != tt.wantErr {
+				t.Errorf("verify = %v, want an error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package transfer
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 is an S3 endpoint holding the objects of one bucket, with the
+// ETags S3 gives unencrypted uploads
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	etags   map[string]string
+	uploads map[string]map[int][]byte
+	// failPart, when set, is the part number every UploadPart fails on
+	failPart int
+	// puts, multiparts and aborts count the uploads started each way
+	puts, multiparts, aborts int
+}
+
+// newFakeS3 starts a fake endpoint and returns it with a location in its bucket
+func newFakeS3(t *testing.T) (*fakeS3, Location) {
+	t.Helper()
+	fake := &fakeS3{
+		objects: make(map[string][]byte),
+		etags:   make(map[string]string),
+		uploads: make(map[string]map[int][]byte),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := s3.New(s3.Options{
+		Region:                     "us-east-1",
+		BaseEndpoint:               aws.String(server.URL),
+		UsePathStyle:               true,
+		Credentials:                credentials.NewStaticCredentialsProvider("AKIAEXAMPLE", "secret", ""),
+		Retryer:                    aws.NopRetryer{},
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	})
+	return fake, Location{Client: client, Bucket: "bucket"}
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.multiparts++
+		id := strconv.Itoa(len(f.uploads) + f.aborts + 1)
+		f.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, id)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if number == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, "<Error><Code>InternalError</Code><Message>part failed</Message></Error>")
+			return
+		}
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		delete(f.uploads, query.Get("uploadId"))
+		var data, sums []byte
+		for number := 1; number <= len(parts); number++ {
+			data = append(data, parts[number]...)
+			sum := md5.Sum(parts[number])
+			sums = append(sums, sum[:]...)
+		}
+		f.objects[key] = data
+		f.etags[key] = fmt.Sprintf("%s-%d", md5Hex(sums), len(parts))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Key>%s</Key><ETag>"%s"</ETag></CompleteMultipartUploadResult>`, key, f.etags[key])
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.aborts++
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.puts++
+		f.objects[key] = body
+		f.etags[key] = md5Hex(body)
+		w.Header().Set("ETag", `"`+f.etags[key]+`"`)
+	case r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", `"`+f.etags[key]+`"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,384 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cksidharthan/s3-browser/internal/listing"
+	"github.com/cksidharthan/s3-browser/internal/operations"
+)
+
+const (
+	// PartSize is the multipart part size; smaller objects are copied with a single PutObject
+	PartSize = 16 << 20
+	// Concurrency is how many objects are copied at the same time
+	Concurrency = 4
+)
+
+// Location is a bucket, or a prefix within it, reached through the client of a connection
+type Location struct {
+	Client *s3.Client
+	Bucket string
+	Prefix string
+}
+
+// Transfer streams objects from one location to another, possibly on a
+// different endpoint. Destination keys keep the part of the source key after
+// the source prefix, placed under the destination prefix.
+type Transfer struct {
+	source       Location
+	destination  Location
+	keys         []string
+	skipExisting bool
+	partSize     int64
+	logger       *slog.Logger
+
+	mu     sync.Mutex
+	copied map[string]bool
+}
+
+// New prepares a transfer of keys, or of everything under the source prefix
+// when keys is empty. With skipExisting, objects that already exist at the
+// destination with the same size are left alone.
+func New(source, destination Location, keys []string, skipExisting bool, logger *slog.Logger) *Transfer {
+	return &Transfer{
+		source:       source,
+		destination:  destination,
+		keys:         keys,
+		skipExisting: skipExisting,
+		partSize:     PartSize,
+		logger:       logger,
+		copied:       make(map[string]bool),
+	}
+}
+
+// Run copies every selected object that an earlier run has not copied yet,
+// so resuming a failed or cancelled transfer picks up where it stopped
+func (t *Transfer) Run(ctx context.Context, op *operations.Operation) error {
+	op.SetPhase("copying objects")
+
+	var failed atomic.Int64
+	keys := make(chan string)
+	var wg sync.WaitGroup
+	for range Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				if t.isCopied(key) {
+					op.AddDone(1, 0)
+					continue
+				}
+				size, err := t.copyObject(ctx, key)
+				if err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					failed.Add(1)
+					op.AddFailed(1)
+					t.logger.Warn("Failed to transfer object",
+						slog.String("key", key),
+						slog.String("error", err.Error()))
+					continue
+				}
+				t.markCopied(key)
+				op.AddDone(1, size)
+			}
+		}()
+	}
+
+	err := t.enumerate(ctx, op, func(key string) error {
+		select {
+		case keys <- key:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(keys)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if n := failed.Load(); n > 0 {
+		return fmt.Errorf("%d objects could not be transferred; resume the operation to retry them", n)
+	}
+	return nil
+}
+
+// enumerate calls fn for every key to transfer
+func (t *Transfer) enumerate(ctx context.Context, op *operations.Operation, fn func(key string) error) error {
+	if len(t.keys) > 0 {
+		op.SetTotal(int64(len(t.keys)))
+		for _, key := range t.keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return listing.Walk(ctx, t.source.Client, t.source.Bucket, t.source.Prefix, func(obj types.Object) error {
+		op.AddTotal(1)
+		return fn(aws.ToString(obj.Key))
+	})
+}
+
+// copyObject streams a single object to the destination and verifies the
+// copy, returning the number of bytes transferred
+func (t *Transfer) copyObject(ctx context.Context, key string) (int64, error) {
+	destKey := t.destinationKey(key)
+
+	head, err := t.source.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(t.source.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source object: %w", err)
+	}
+	size := aws.ToInt64(head.ContentLength)
+
+	if t.skipExisting {
+		existing, err := t.destination.Client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(t.destination.Bucket),
+			Key:    aws.String(destKey),
+		})
+		if err == nil && aws.ToInt64(existing.ContentLength) == size {
+			return 0, nil
+		}
+	}
+
+	object, err := t.source.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(t.source.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source object: %w", err)
+	}
+	defer object.Body.Close()
+
+	sum := newETagSum(t.partSize)
+	body := io.TeeReader(object.Body, sum)
+	attrs := objectAttributes{head: head, tagging: t.tagging(ctx, key)}
+	if err := upload(ctx, t.destination, destKey, body, size, t.partSize, attrs, t.logger); err != nil {
+		return 0, err
+	}
+
+	if err := t.verify(ctx, destKey, head, sum); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// objectAttributes are the parts of a source object carried over to its copy
+type objectAttributes struct {
+	head    *s3.HeadObjectOutput
+	tagging *string
+}
+
+// Upload writes size bytes read from body to key at destination, in parts
+// when the object is larger than PartSize
+func Upload(ctx context.Context, destination Location, key string, body io.Reader, size int64, contentType string, logger *slog.Logger) error {
+	attrs := objectAttributes{head: &s3.HeadObjectOutput{}}
+	if contentType != "" {
+		attrs.head.ContentType = aws.String(contentType)
+	}
+	return upload(ctx, destination, key, body, size, PartSize, attrs, logger)
+}
+
+// upload writes an object with a single PutObject when it fits in one part
+func upload(ctx context.Context, dst Location, key string, body io.Reader, size, partSize int64, attrs objectAttributes, logger *slog.Logger) error {
+	if size <= partSize {
+		return put(ctx, dst, key, body, attrs)
+	}
+	return putMultipart(ctx, dst, key, body, partSize, attrs, logger)
+}
+
+// put uploads an object that fits in a single part
+func put(ctx context.Context, dst Location, key string, body io.Reader, attrs objectAttributes) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("failed to read source object: %w", err)
+	}
+
+	_, err = dst.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:             aws.String(dst.Bucket),
+		Key:                aws.String(key),
+		Body:               bytes.NewReader(data),
+		ContentLength:      aws.Int64(int64(len(data))),
+		ContentType:        attrs.head.ContentType,
+		CacheControl:       attrs.head.CacheControl,
+		ContentDisposition: attrs.head.ContentDisposition,
+		ContentEncoding:    attrs.head.ContentEncoding,
+		ContentLanguage:    attrs.head.ContentLanguage,
+		Metadata:           attrs.head.Metadata,
+		Tagging:            attrs.tagging,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write destination object: %w", err)
+	}
+	return nil
+}
+
+// putMultipart uploads an object part by part, holding one part in memory at
+// a time. The upload is aborted if any part fails.
+func putMultipart(ctx context.Context, dst Location, key string, body io.Reader, partSize int64, attrs objectAttributes, logger *slog.Logger) error {
+	created, err := dst.Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(dst.Bucket),
+		Key:                aws.String(key),
+		ContentType:        attrs.head.ContentType,
+		CacheControl:       attrs.head.CacheControl,
+		ContentDisposition: attrs.head.ContentDisposition,
+		ContentEncoding:    attrs.head.ContentEncoding,
+		ContentLanguage:    attrs.head.ContentLanguage,
+		Metadata:           attrs.head.Metadata,
+		Tagging:            attrs.tagging,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		// Abort even when the transfer was cancelled, so no parts are left behind
+		_, err := dst.Client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(dst.Bucket),
+			Key:      aws.String(key),
+			UploadId: created.UploadId,
+		})
+		if err != nil {
+			logger.Warn("Failed to abort multipart upload",
+				slog.String("key", key),
+				slog.String("error", err.Error()))
+		}
+	}()
+
+	buf := make([]byte, partSize)
+	var parts []types.CompletedPart
+	for number := int32(1); ; number++ {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			part, err := dst.Client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:        aws.String(dst.Bucket),
+				Key:           aws.String(key),
+				UploadId:      created.UploadId,
+				PartNumber:    aws.Int32(number),
+				Body:          bytes.NewReader(buf[:n]),
+				ContentLength: aws.Int64(int64(n)),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to upload part %d: %w", number, err)
+			}
+			parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(number)})
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read source object: %w", readErr)
+		}
+	}
+
+	_, err = dst.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(dst.Bucket),
+		Key:             aws.String(key),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	completed = true
+	return nil
+}
+
+// tagging returns the tags of a source object in the form PutObject takes.
+// Providers without tagging support simply yield no tags.
+func (t *Transfer) tagging(ctx context.Context, key string) *string {
+	out, err := t.source.Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(t.source.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil || len(out.TagSet) == 0 {
+		return nil
+	}
+
+	values := url.Values{}
+	for _, tag := range out.TagSet {
+		values.Set(aws.ToString(tag.Key), aws.ToString(tag.Value))
+	}
+	return aws.String(values.Encode())
+}
+
+// verify checks the copy against what was read from the source: sizes must
+// match, and ETags must too wherever they are MD5 based
+func (t *Transfer) verify(ctx context.Context, key string, source *s3.HeadObjectOutput, sum *etagSum) error {
+	size := aws.ToInt64(source.ContentLength)
+	if sum.size != size {
+		return fmt.Errorf("read %d bytes from the source, expected %d", sum.size, size)
+	}
+	sourceETag := trimETag(source.ETag)
+	if md5ETag(source.ServerSideEncryption, source.SSECustomerAlgorithm) &&
+		ETagComparable(sourceETag, sum.single()) && sourceETag != sum.single() {
+		return fmt.Errorf("source checksum mismatch: read %s, expected %s", sum.single(), sourceETag)
+	}
+
+	dest, err := t.destination.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(t.destination.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify destination object: %w", err)
+	}
+	if destSize := aws.ToInt64(dest.ContentLength); destSize != size {
+		return fmt.Errorf("destination has %d bytes, expected %d", destSize, size)
+	}
+	if !md5ETag(dest.ServerSideEncryption, dest.SSECustomerAlgorithm) {
+		return nil
+	}
+
+	destETag := trimETag(dest.ETag)
+	expected := sum.single()
+	if size > t.partSize {
+		expected = sum.multipart()
+	}
+	if ETagComparable(destETag, expected) && destETag != expected {
+		return fmt.Errorf("destination checksum mismatch: got %s, expected %s", destETag, expected)
+	}
+	return nil
+}
+
+// destinationKey maps a source key to its key at the destination
+func (t *Transfer) destinationKey(key string) string {
+	return t.destination.Prefix + strings.TrimPrefix(key, t.source.Prefix)
+}
+
+func (t *Transfer) isCopied(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.copied[key]
+}
+
+func (t *Transfer) markCopied(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.copied[key] = true
+}
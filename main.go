@@ -10,9 +10,11 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	_ "github.com/cksidharthan/s3-browser/docs"
 	"github.com/cksidharthan/s3-browser/internal/server"
+	"github.com/cksidharthan/s3-browser/internal/sso"
 )
 
 //go:embed frontend/dist
@@ -22,12 +24,28 @@ var frontendFS embed.FS
 // @version 1.0
 // @description A modern web-based file manager for S3-compatible storage systems
 // @host localhost:8080
-// @BasePath /api
+// @BasePath /
 func main() {
 	// Define command-line flags
 	var (
 		port     = flag.String("port", "8080", "Port to run the server on")
 		logLevel = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+		dataDir  = flag.String("data-dir", "", "Directory for persistent data such as the key index (disabled when empty)")
+		indexInt = flag.Duration("index-interval", 15*time.Minute, "How often indexed buckets are refreshed")
+		store    = flag.String("session-store", "memory", "Where sessions are kept (memory, bolt, redis); bolt requires -data-dir")
+		redisURL = flag.String("redis-url", "", "Redis-compatible server for the redis session store, e.g. redis://localhost:6379/0")
+		keyFile  = flag.String("master-key-file", "", "File with base64 master keys encrypting stored credentials, newest first (or set S3_BROWSER_MASTER_KEY)")
+		purge    = flag.Bool("purge-unreadable-sessions", false, "Remove stored sessions whose credentials none of the master keys can open, e.g. after a key was lost")
+		profiles = flag.String("allow-profiles", "", "Comma-separated server-side AWS profiles users may connect with; \"default\" offers the SDK default credential chain")
+		connFile = flag.String("connections-file", "", "JSON file declaring named connections offered to users")
+		readOnly = flag.Bool("read-only", false, "Reject uploads, deletes and bucket changes for every session")
+		mirrors  = flag.String("mirror-roots", "", "Comma-separated name=directory pairs users may export buckets to and import from (disabled when empty)")
+		issuer   = flag.String("oidc-issuer", "", "OpenID Connect issuer URL; users must sign in there before using S3 Browser (disabled when empty)")
+		clientID = flag.String("oidc-client-id", "", "OIDC client ID (the client secret, if any, is read from S3_BROWSER_OIDC_CLIENT_SECRET)")
+		redirect = flag.String("oidc-redirect-url", "", "This server's /auth/callback URL as registered with the OIDC provider")
+		scopes   = flag.String("oidc-scopes", "", "Comma-separated OIDC scopes to request on top of openid, profile and email, e.g. groups")
+		groups   = flag.String("oidc-groups-claim", "groups", "ID token claim that lists the groups mapped to connections")
+		userFile = flag.String("users-file", "", "htpasswd-style file of local users (name:bcrypt-hash:connections) who must sign in before using S3 Browser; reloaded on SIGHUP (disabled when empty)")
 		help     = flag.Bool("help", false, "Show help message")
 	)
 	flag.Parse()
@@ -43,6 +61,16 @@ func main() {
 		fmt.Println("  s3-browser")
 		fmt.Println("  s3-browser -port 3000")
 		fmt.Println("  s3-browser -port 8080 -log-level debug")
+		fmt.Println("  s3-browser -data-dir /var/lib/s3-browser -index-interval 30m")
+		fmt.Println("  s3-browser -data-dir /var/lib/s3-browser -session-store bolt")
+		fmt.Println("  s3-browser -session-store redis -redis-url redis://localhost:6379/0")
+		fmt.Println("  s3-browser -session-store bolt -data-dir /var/lib/s3-browser -master-key-file /etc/s3-browser/keys")
+		fmt.Println("  s3-browser -allow-profiles default,staging")
+		fmt.Println("  s3-browser -connections-file /etc/s3-browser/connections.json")
+		fmt.Println("  s3-browser -read-only")
+		fmt.Println("  s3-browser -mirror-roots backups=/srv/backups,seed=/srv/seed")
+		fmt.Println("  s3-browser -connections-file connections.json -oidc-issuer https://idp.example.com -oidc-client-id s3-browser -oidc-redirect-url https://s3.example.com/auth/callback")
+		fmt.Println("  s3-browser -connections-file connections.json -users-file /etc/s3-browser/users")
 		fmt.Println("  s3-browser -help")
 		os.Exit(0)
 	}
@@ -76,7 +104,32 @@ func main() {
 	)
 
 	// Create server
-	srv := server.New(logger, frontendFS)
+	srv, err := server.New(logger, frontendFS, server.Options{
+		DataDir:                 *dataDir,
+		IndexInterval:           *indexInt,
+		SessionStore:            *store,
+		RedisURL:                *redisURL,
+		MasterKeyFile:           *keyFile,
+		MasterKeys:              os.Getenv("S3_BROWSER_MASTER_KEY"),
+		PurgeUnreadableSessions: *purge,
+		AllowedProfiles:         splitList(*profiles),
+		ConnectionsFile:         *connFile,
+		ReadOnly:                *readOnly,
+		MirrorRoots:             *mirrors,
+		OIDC: sso.Config{
+			Issuer:       *issuer,
+			ClientID:     *clientID,
+			ClientSecret: os.Getenv("S3_BROWSER_OIDC_CLIENT_SECRET"),
+			RedirectURL:  *redirect,
+			Scopes:       splitList(*scopes),
+			GroupsClaim:  *groups,
+		},
+		UsersFile: *userFile,
+	})
+	if err != nil {
+		logger.Error("Failed to create server", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	// Create context that listens for the interrupt signal from the OS
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -97,3 +150,14 @@ func main() {
 
 	logger.Info("Application terminated")
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}